@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// DiffRequest asks for the differences between two revisions of a package. Diffs are served by
+// the manager and are never stored.
+type DiffRequest struct {
+	// Namespace is the namespace of the PackageRevisions.
+	Namespace string `json:"namespace"`
+
+	// From is the name of the PackageRevision to compare from. If empty, the upstream of To,
+	// which it was cloned from or last upgraded to, is compared from: the commit of its upstream
+	// lock when its contents are cached, or else the upstream revision.
+	From string `json:"from,omitempty"`
+
+	// To is the name of the PackageRevision to compare to.
+	To string `json:"to"`
+}
+
+// DiffResponse lists the differences between two revisions of a package.
+type DiffResponse struct {
+	// From is the name of the PackageRevision compared from, or the repository, directory and
+	// commit of the upstream lock compared from, as <repo>//<directory>@<commit>.
+	From string `json:"from"`
+
+	// To is the name of the PackageRevision compared to.
+	To string `json:"to"`
+
+	// Resources lists the KRM resources that differ, ordered by file.
+	Resources []ResourceDiff `json:"resources,omitempty"`
+
+	// Files lists the files that hold no KRM resources and differ, such as a README.md,
+	// ordered by path.
+	Files []FileDiff `json:"files,omitempty"`
+}
+
+// ChangeType is the way something differs between two versions of package contents.
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "Added"
+	ChangeModified ChangeType = "Modified"
+	ChangeRemoved  ChangeType = "Removed"
+)
+
+// FileDiff describes a file that differs between two versions of package contents.
+type FileDiff struct {
+	// Path is the path of the file relative to the package root.
+	Path string `json:"path"`
+
+	// Change is how the file differs.
+	Change ChangeType `json:"change"`
+
+	// Diff is the difference in the unified diff format.
+	Diff string `json:"diff,omitempty"`
+}
+
+// ResourceDiff describes a KRM resource that differs between two versions of package contents.
+type ResourceDiff struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+
+	// File is the path of the file holding the resource, in the version compared to unless the
+	// resource was removed.
+	File string `json:"file"`
+
+	// Change is how the resource differs.
+	Change ChangeType `json:"change"`
+
+	// Fields lists the fields of a modified resource that differ.
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// FieldDiff describes a field of a KRM resource that differs between two versions.
+type FieldDiff struct {
+	// Path is the path of the field, such as spec.replicas. Elements of lists whose elements
+	// all have a name are identified by it, as in spec.containers[name=app].image, and other
+	// elements by their index, as in spec.args[0].
+	Path string `json:"path"`
+
+	// Change is how the field differs.
+	Change ChangeType `json:"change"`
+
+	// From is the value of the field, encoded as YAML, in the version compared from.
+	From string `json:"from,omitempty"`
+
+	// To is the value of the field, encoded as YAML, in the version compared to.
+	To string `json:"to,omitempty"`
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// FunctionPolicyList contains a list of FunctionPolicy.
+type FunctionPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FunctionPolicy `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// FunctionPolicy is the Schema for the functionpolicies API.
+// It restricts the KRM functions that the pipelines of packages may run. Packages to which no
+// FunctionPolicy applies may run any function. A function may only be run in a package if every
+// FunctionPolicy that applies to the package allows it.
+type FunctionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec FunctionPolicySpec `json:"spec,omitempty"`
+}
+
+// FunctionPolicySpec defines the functions allowed by a FunctionPolicy and the packages it applies to.
+type FunctionPolicySpec struct {
+	// Namespaces are the namespaces of the PackageRevisions the policy applies to.
+	// The policy applies in every namespace if empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Repositories are the names of the Repositories whose packages the policy applies to.
+	// The policy applies to the packages of every repository if empty.
+	Repositories []string `json:"repositories,omitempty"`
+
+	// AllowedFunctions are the functions that may be run. No function may be run if empty.
+	AllowedFunctions []AllowedFunction `json:"allowedFunctions,omitempty"`
+
+	// Limits restrict the resources functions may use when they are run.
+	Limits *FunctionLimits `json:"limits,omitempty"`
+}
+
+// AllowedFunction identifies functions that may be run.
+type AllowedFunction struct {
+	// Image is the name of the function image, without a tag or digest, for example
+	// gcr.io/kpt-fn/set-labels. A name ending in * allows every image whose name starts with
+	// the rest of it, so gcr.io/kpt-fn/* allows every image in gcr.io/kpt-fn, and * allows
+	// every image.
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// Digests, in the form sha256:<hex>, restrict the function to specific builds of the image.
+	// If set, the function must be pinned to one of the digests in the pipeline, as in
+	// gcr.io/kpt-fn/set-labels:v0.2@sha256:<hex>.
+	Digests []string `json:"digests,omitempty"`
+}
+
+// FunctionLimits restrict the resources functions may use when they are run.
+type FunctionLimits struct {
+	// Timeout is the maximum time a function may run for.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Memory is the maximum memory a function compiled to WebAssembly may use.
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// MaxSteps is the maximum number of execution steps of a Starlark function.
+	// +kubebuilder:validation:Minimum=1
+	MaxSteps *int64 `json:"maxSteps,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FunctionPolicy{}, &FunctionPolicyList{})
+}
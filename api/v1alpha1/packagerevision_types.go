@@ -34,6 +34,7 @@ type PackageRevisionList struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:storageversion
 
 // PackageRevision is the Schema for the packagerevisions API.
 type PackageRevision struct {
@@ -45,6 +46,10 @@ type PackageRevision struct {
 }
 
 // PackageRevisionSpec defines the desired state of PackageRevision.
+// The package, repository and workspace of a PackageRevision identify it, and are immutable.
+// +kubebuilder:validation:XValidation:rule="has(self.packageName) == has(oldSelf.packageName) && (!has(self.packageName) || self.packageName == oldSelf.packageName)",message="packageName is immutable"
+// +kubebuilder:validation:XValidation:rule="has(self.repository) == has(oldSelf.repository) && (!has(self.repository) || self.repository == oldSelf.repository)",message="repository is immutable"
+// +kubebuilder:validation:XValidation:rule="has(self.workspaceName) == has(oldSelf.workspaceName) && (!has(self.workspaceName) || self.workspaceName == oldSelf.workspaceName)",message="workspaceName is immutable"
 type PackageRevisionSpec struct {
 	// PackageName identifies the package in the repository.
 	PackageName string `json:"packageName,omitempty"`
@@ -64,9 +69,13 @@ type PackageRevisionSpec struct {
 
 	Lifecycle PackageRevisionLifecycle `json:"lifecycle,omitempty"`
 
+	// +kubebuilder:validation:MaxItems=32
 	Tasks []Task `json:"tasks,omitempty"`
 
 	ReadinessGates []ReadinessGate `json:"readinessGates,omitempty"`
+
+	// Injectors select the in-cluster objects whose values are injected into the package.
+	Injectors []InjectionSelector `json:"injectors,omitempty"`
 }
 
 // PackageRevisionStatus defines the observed state of PackageRevision.
@@ -83,6 +92,22 @@ type PackageRevisionStatus struct {
 	// UpstreamLock identifies the upstream data for this package.
 	UpstreamLock *UpstreamLock `json:"upstreamLock,omitempty"`
 
+	// ProposedAt is when the packagerevision was last proposed for approval. It is cleared when the
+	// packagerevision goes back to Draft.
+	ProposedAt *metav1.Time `json:"proposedTimestamp,omitempty"`
+
+	// ObservedLifecycle is the lifecycle of the packagerevision when it was last reconciled, so
+	// that changes of its lifecycle are noticed.
+	ObservedLifecycle PackageRevisionLifecycle `json:"observedLifecycle,omitempty"`
+
+	// ObservedResourcesGeneration is the generation of the packagerevisionresources of the
+	// packagerevision when it was last reconciled, so that edits of its resources are noticed.
+	ObservedResourcesGeneration int64 `json:"observedResourcesGeneration,omitempty"`
+
+	// EditedResourcesGeneration is the generation of the packagerevisionresources of the
+	// packagerevision written by their last edit, leaving out the writes of the operator itself.
+	EditedResourcesGeneration int64 `json:"editedResourcesGeneration,omitempty"`
+
 	// PublishedBy is the identity of the user who approved the packagerevision.
 	PublishedBy string `json:"publishedBy,omitempty"`
 
@@ -92,6 +117,16 @@ type PackageRevisionStatus struct {
 	// Deployment is true if this is a deployment package (in a deployment repository).
 	Deployment bool `json:"deployment,omitempty"`
 
+	// InjectionPoints records the outcome of config injection for each injection point in the package.
+	InjectionPoints []InjectionPoint `json:"injectionPoints,omitempty"`
+
+	// RenderResults records the outcome of each function run when the package was last rendered.
+	RenderResults []FunctionResult `json:"renderResults,omitempty"`
+
+	// MergeConflicts lists the conflicts left in the package by merging upstream changes into it,
+	// until they are resolved.
+	MergeConflicts []MergeConflict `json:"mergeConflicts,omitempty"`
+
 	// Conditions store the status conditions of the Memcached instances
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
@@ -114,6 +149,10 @@ const (
 	PackageRevisionLifecycleDeletionProposed PackageRevisionLifecycle = "DeletionProposed"
 )
 
+// Task produces or changes the contents of a draft. It is a union: exactly one of its members is
+// set, the one named by its type.
+// +kubebuilder:validation:XValidation:rule="(has(self.init) ? 1 : 0) + (has(self.clone) ? 1 : 0) + (has(self.edit) ? 1 : 0) + (has(self.upgrade) ? 1 : 0) == 1",message="exactly one of init, clone, edit and upgrade must be set"
+// +kubebuilder:validation:XValidation:rule="self.type == 'init' ? has(self.init) : self.type == 'clone' ? has(self.clone) : self.type == 'edit' ? has(self.edit) : has(self.upgrade)",message="the member that is set must be the one of the type"
 type Task struct {
 	Type    TaskType                `json:"type"`
 	Init    *PackageInitTaskSpec    `json:"init,omitempty"`
@@ -122,6 +161,7 @@ type Task struct {
 	Upgrade *PackageUpgradeTaskSpec `json:"upgrade,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=init;clone;edit;upgrade
 type TaskType string
 
 const (
@@ -192,6 +232,10 @@ type PackageEditTaskSpec struct {
 	Source *PackageRevisionRef `json:"sourceRef,omitempty"`
 }
 
+// UpstreamPackage is a package to clone. It is a union: exactly one of git, oci and upstreamRef
+// is set.
+// +kubebuilder:validation:XValidation:rule="(has(self.git) ? 1 : 0) + (has(self.oci) ? 1 : 0) + (has(self.upstreamRef) ? 1 : 0) == 1",message="exactly one of git, oci and upstreamRef must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.type) || (self.type == 'git') == has(self.git) && (self.type == 'oci') == has(self.oci)",message="type must name the member that is set"
 type UpstreamPackage struct {
 	// Type of the repository (i.e. git, OCI). If empty, `upstreamRef` will be used.
 	Type RepositoryType `json:"type,omitempty"`
@@ -206,6 +250,7 @@ type UpstreamPackage struct {
 	UpstreamRef *PackageRevisionRef `json:"upstreamRef,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=git;oci
 type RepositoryType string
 
 const (
@@ -239,6 +284,129 @@ type OciPackage struct {
 	Image string `json:"image"`
 }
 
+// InjectionSelector identifies an in-cluster object used for config injection. Only ConfigMaps
+// are injected: Secrets never are, since their data would be stored in plain text in the package.
+type InjectionSelector struct {
+	// Group of the object. Empty for the core group.
+	Group string `json:"group,omitempty"`
+
+	// Version of the object.
+	Version string `json:"version,omitempty"`
+
+	// Kind of the object. If unspecified, the kind of the injection point is used.
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the object.
+	Name string `json:"name"`
+}
+
+// InjectionPoint is a resource in the package whose data is injected from an in-cluster object.
+type InjectionPoint struct {
+	// File is the path of the file in the package containing the injection point.
+	File string `json:"file"`
+
+	// Group of the injection point resource. Empty for the core group.
+	Group string `json:"group,omitempty"`
+
+	// Kind of the injection point resource.
+	Kind string `json:"kind"`
+
+	// Name of the injection point resource.
+	Name string `json:"name"`
+
+	// Required is true if the package is incomplete unless the injection point is injected.
+	Required bool `json:"required,omitempty"`
+
+	// Source is the in-cluster object that was injected, if any.
+	Source *InjectionSource `json:"source,omitempty"`
+
+	// Message describes why the injection point was not injected.
+	Message string `json:"message,omitempty"`
+}
+
+// InjectionSource identifies the version of an in-cluster object that was injected.
+type InjectionSource struct {
+	InjectionSelector `json:",inline"`
+
+	// ResourceVersion of the object at the time it was injected.
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// FunctionResult is the outcome of running a function of a Kptfile pipeline.
+type FunctionResult struct {
+	// Image of the function.
+	Image string `json:"image"`
+
+	// Name of the function in the pipeline, if it has one.
+	Name string `json:"name,omitempty"`
+
+	// Package is the directory of the package whose pipeline declares the function, "." for the root package.
+	Package string `json:"package"`
+
+	// Stage of the pipeline the function was run in.
+	// +kubebuilder:validation:Enum=mutator;validator
+	Stage string `json:"stage"`
+
+	// Results reported by the function.
+	Results []ResultItem `json:"results,omitempty"`
+
+	// Error is set if the function failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ResultItem is a result reported by a function.
+type ResultItem struct {
+	// Message is a human readable message.
+	Message string `json:"message"`
+
+	// Severity of the result, one of error, warning or info.
+	Severity string `json:"severity,omitempty"`
+
+	// ResourceRef identifies the resource the result refers to.
+	ResourceRef *ResultResourceRef `json:"resourceRef,omitempty"`
+
+	// Field is the path of the field in the resource that the result refers to.
+	Field string `json:"field,omitempty"`
+
+	// File is the path of the file containing the resource that the result refers to.
+	File string `json:"file,omitempty"`
+}
+
+// MergeConflict is a change made both upstream and locally that could not be merged.
+type MergeConflict struct {
+	// File is the path of the file holding the conflict, relative to the package root.
+	File string `json:"file"`
+
+	// APIVersion, Kind, Name and Namespace identify the resource holding the conflict. They are
+	// not set for conflicts in files that hold no resources.
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+
+	// Path is the path of the conflicting field, such as spec.replicas. It is not set for
+	// conflicts over a whole resource or file.
+	Path string `json:"path,omitempty"`
+
+	// Base, Local and Upstream are the values of the conflicting field, encoded as YAML, in the
+	// old upstream revision, the local revision and the new upstream revision. They are not set
+	// when the field is not set, or for conflicts over a whole resource or file.
+	Base     string `json:"base,omitempty"`
+	Local    string `json:"local,omitempty"`
+	Upstream string `json:"upstream,omitempty"`
+
+	// Description describes the conflict.
+	Description string `json:"description"`
+}
+
+// ResultResourceRef identifies a resource in a package.
+type ResultResourceRef struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
 type PackageRevisionRef struct {
 	// `Name` is the name of the referenced PackageRevision resource.
 	Name string `json:"name"`
@@ -281,6 +449,10 @@ type GitLock struct {
 
 type OriginType string
 
+const (
+	OriginTypeGit OriginType = "git"
+)
+
 func init() {
 	SchemeBuilder.Register(&PackageRevision{}, &PackageRevisionList{})
 }
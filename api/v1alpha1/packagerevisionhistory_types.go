@@ -0,0 +1,103 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// PackageRevisionHistoryList contains a list of PackageRevisionHistory.
+type PackageRevisionHistoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PackageRevisionHistory `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.spec.repositoryName`
+// +kubebuilder:printcolumn:name="Package",type=string,JSONPath=`.spec.packageName`
+// +kubebuilder:printcolumn:name="WorkspaceName",type=string,JSONPath=`.spec.workspaceName`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PackageRevisionHistory is the Schema for the packagerevisionhistories API.
+// It is the append-only history of what was done to a packagerevision, and by whom. It has the
+// name of the packagerevision, and is labelled with its repository and package, so that the
+// history of a package is listed by selecting on the labels. It is kept when the packagerevision
+// is deleted.
+type PackageRevisionHistory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PackageRevisionHistorySpec `json:"spec,omitempty"`
+}
+
+// PackageRevisionHistorySpec holds the history of a packagerevision.
+type PackageRevisionHistorySpec struct {
+	PackageName    string `json:"packageName,omitempty"`
+	RepositoryName string `json:"repositoryName,omitempty"`
+	WorkspaceName  string `json:"workspaceName,omitempty"`
+
+	// Entries are the things done to the packagerevision, oldest first. Entries are only ever
+	// appended, up to 512: what is done once the history is full is not recorded.
+	// +kubebuilder:validation:MaxItems=512
+	// +kubebuilder:validation:XValidation:rule="self.size() >= oldSelf.size() && oldSelf.all(e, e in self)",message="entries are append-only"
+	Entries []PackageRevisionHistoryEntry `json:"entries,omitempty"`
+}
+
+// PackageRevisionAction is something done to a packagerevision.
+// +kubebuilder:validation:Enum=Created;Edited;Proposed;Approved;Rejected;DeletionProposed;Deleted
+type PackageRevisionAction string
+
+const (
+	PackageRevisionActionCreated          PackageRevisionAction = "Created"
+	PackageRevisionActionEdited           PackageRevisionAction = "Edited"
+	PackageRevisionActionProposed         PackageRevisionAction = "Proposed"
+	PackageRevisionActionApproved         PackageRevisionAction = "Approved"
+	PackageRevisionActionRejected         PackageRevisionAction = "Rejected"
+	PackageRevisionActionDeletionProposed PackageRevisionAction = "DeletionProposed"
+	PackageRevisionActionDeleted          PackageRevisionAction = "Deleted"
+)
+
+// PackageRevisionHistoryEntry records something done to a packagerevision.
+type PackageRevisionHistoryEntry struct {
+	// Action is what was done.
+	Action PackageRevisionAction `json:"action"`
+
+	// Actor is the user who did it, if known. Actors are known when the porch admission webhook
+	// is deployed.
+	// +kubebuilder:validation:MaxLength=256
+	Actor string `json:"actor,omitempty"`
+
+	// Timestamp is when it was done.
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// FromLifecycle is the lifecycle of the packagerevision before it was done.
+	FromLifecycle PackageRevisionLifecycle `json:"fromLifecycle,omitempty"`
+
+	// ToLifecycle is the lifecycle of the packagerevision after it was done.
+	ToLifecycle PackageRevisionLifecycle `json:"toLifecycle,omitempty"`
+
+	// Commit is the commit of the packagerevision in its repository, if it has one.
+	// +kubebuilder:validation:MaxLength=64
+	Commit string `json:"commit,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PackageRevisionHistory{}, &PackageRevisionHistoryList{})
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// PackageRevisionResourcesList contains a list of PackageRevisionResources.
+type PackageRevisionResourcesList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PackageRevisionResources `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=packagerevisionresources
+
+// PackageRevisionResources is the Schema for the packagerevisionresources API.
+// It holds the contents of the PackageRevision with the same name.
+type PackageRevisionResources struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PackageRevisionResourcesSpec `json:"spec,omitempty"`
+}
+
+// PackageRevisionResourcesSpec defines the contents of a package revision.
+type PackageRevisionResourcesSpec struct {
+	// PackageName identifies the package in the repository.
+	PackageName string `json:"packageName,omitempty"`
+
+	// RepositoryName is the name of the Repository object containing this package.
+	RepositoryName string `json:"repository,omitempty"`
+
+	// WorkspaceName is a short, unique description of the changes contained in this package revision.
+	WorkspaceName string `json:"workspaceName,omitempty"`
+
+	// Resources are the content of the package, keyed by file path relative to the package root.
+	Resources map[string]string `json:"resources,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PackageRevisionResources{}, &PackageRevisionResourcesList{})
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// PackageVariantSetList contains a list of PackageVariantSet.
+type PackageVariantSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PackageVariantSet `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:validation:XValidation:rule="size(self.metadata.name) <= 63",message="name must be no more than 63 characters"
+
+// PackageVariantSet is the Schema for the packagevariantsets API.
+// It fans an upstream package out into one downstream package variant per target.
+// Its name labels the downstream PackageRevisions, so it must be a valid label value.
+type PackageVariantSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PackageVariantSetSpec   `json:"spec,omitempty"`
+	Status PackageVariantSetStatus `json:"status,omitempty"`
+}
+
+// PackageVariantSetSpec defines the desired state of PackageVariantSet.
+type PackageVariantSetSpec struct {
+	// Upstream is the package revision that is cloned for every target.
+	Upstream PackageRevisionRef `json:"upstream"`
+
+	// Targets determines the set of downstream package variants that are generated.
+	Targets []Target `json:"targets,omitempty"`
+}
+
+// Target selects a set of downstream package variants. Exactly one of `repositories`,
+// `repositorySelector` or `objectSelector` must be specified.
+type Target struct {
+	// Repositories is an explicit list of repositories, and optionally package names, to target.
+	Repositories []RepositoryTarget `json:"repositories,omitempty"`
+
+	// RepositorySelector selects Repository objects in the namespace of the PackageVariantSet.
+	// Each selected repository is a target.
+	RepositorySelector *metav1.LabelSelector `json:"repositorySelector,omitempty"`
+
+	// ObjectSelector selects arbitrary objects in the namespace of the PackageVariantSet.
+	// Each selected object is a target, and `template.repository` must be set to map it to a repository.
+	ObjectSelector *ObjectSelector `json:"objectSelector,omitempty"`
+
+	// Template defines how the downstream package variants for this target are generated.
+	Template *PackageVariantTemplate `json:"template,omitempty"`
+}
+
+// RepositoryTarget identifies a downstream repository and the packages to create in it.
+type RepositoryTarget struct {
+	// Name is the name of the Repository object.
+	Name string `json:"name"`
+
+	// PackageNames is the list of downstream package names to create in the repository.
+	// If unspecified, a single package is created, named by `template.packageName`
+	// or, failing that, by the upstream package name.
+	PackageNames []string `json:"packageNames,omitempty"`
+}
+
+// ObjectSelector selects objects of a single kind by label.
+//
+// The operator lists the objects with its own service account, which may list ConfigMaps and the
+// porch.kpt.dev kinds. Other kinds must be granted to it with a ClusterRole that allows to list
+// them and is labeled `porch.kpt.dev/aggregate-to-object-selector: "true"`.
+type ObjectSelector struct {
+	// APIVersion of the selected objects, for example `infra.example.com/v1`.
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the selected objects, for example `Site`.
+	Kind string `json:"kind"`
+
+	// Selector is a label selector over objects of the given kind. An empty selector selects all objects.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// PackageVariantTemplate defines the fields of the generated downstream package variants.
+// All string values are Go templates, evaluated with `.target` set to the selected object,
+// `.repository` set to the downstream Repository (when it exists) and `.upstream` set to the
+// upstream PackageRevision, for example `{{ .target.metadata.name }}`.
+type PackageVariantTemplate struct {
+	// Repository is the name of the downstream repository. Required for `objectSelector` targets.
+	Repository string `json:"repository,omitempty"`
+
+	// PackageName is the name of the downstream package.
+	PackageName string `json:"packageName,omitempty"`
+
+	// WorkspaceName is the workspace name of the downstream package revision.
+	// If unspecified, the name of the PackageVariantSet is used.
+	WorkspaceName string `json:"workspaceName,omitempty"`
+
+	// Labels are added to the downstream PackageRevision.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the downstream PackageRevision.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Injectors select the in-cluster objects whose values are injected into the downstream package.
+	Injectors []InjectionSelector `json:"injectors,omitempty"`
+}
+
+// PackageVariantSetStatus defines the observed state of PackageVariantSet.
+type PackageVariantSetStatus struct {
+	// DownstreamTargets lists the names of the generated downstream PackageRevisions.
+	DownstreamTargets []string `json:"downstreamTargets,omitempty"`
+
+	// Conditions describes the reconciliation state of the object.
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PackageVariantSet{}, &PackageVariantSetList{})
+}
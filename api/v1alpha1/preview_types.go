@@ -0,0 +1,55 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// PreviewRequest asks for a preview of what a PackageRevision would contain once rendered, with
+// changes to its spec or contents. Previews are served by the manager and are never stored.
+type PreviewRequest struct {
+	// Namespace is the namespace of the PackageRevision.
+	Namespace string `json:"namespace"`
+
+	// Name is the name of the PackageRevision.
+	Name string `json:"name"`
+
+	// Spec, if set, replaces the spec of the PackageRevision, and the contents are produced
+	// afresh by applying its tasks.
+	Spec *PackageRevisionSpec `json:"spec,omitempty"`
+
+	// Resources are files to add to, or replace in, the contents, keyed by file path relative
+	// to the package root.
+	Resources map[string]string `json:"resources,omitempty"`
+
+	// DeletedResources are the paths of files to remove from the contents.
+	DeletedResources []string `json:"deletedResources,omitempty"`
+}
+
+// PreviewResponse is a preview of what a PackageRevision would contain once rendered.
+type PreviewResponse struct {
+	// Resources are the rendered contents, keyed by file path relative to the package root.
+	// They are not set if rendering failed.
+	Resources map[string]string `json:"resources,omitempty"`
+
+	// RenderResults are the results of the functions run to render the contents.
+	RenderResults []FunctionResult `json:"renderResults,omitempty"`
+
+	// Error is set if the contents could not be produced or rendered.
+	Error string `json:"error,omitempty"`
+
+	// Diff lists the files of the rendered contents that differ from the current contents of
+	// the PackageRevision, ordered by path.
+	Diff []FileDiff `json:"diff,omitempty"`
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// RepositoryList contains a list of Repository.
+type RepositoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Repository `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// Repository is the Schema for the repositories API.
+type Repository struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RepositorySpec   `json:"spec,omitempty"`
+	Status RepositoryStatus `json:"status,omitempty"`
+}
+
+// RepositorySpec defines the desired state of Repository.
+type RepositorySpec struct {
+	// Description is a user-friendly description of the repository.
+	Description string `json:"description,omitempty"`
+
+	// Deployment is true if the repository contains deployment packages.
+	Deployment bool `json:"deployment,omitempty"`
+
+	// Type of the repository (i.e. git, OCI).
+	Type RepositoryType `json:"type,omitempty"`
+
+	// Git repository details. Required if `type` is `git`. Ignored if `type` is not `git`.
+	Git *GitRepository `json:"git,omitempty"`
+
+	// OCI repository details. Required if `type` is `oci`. Ignored if `type` is not `oci`.
+	Oci *OciRepository `json:"oci,omitempty"`
+
+	// SyncInterval is how often the repository is synced. If unspecified, the interval the
+	// operator is configured with is used.
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
+}
+
+// RepositoryStatus defines the observed state of Repository.
+type RepositoryStatus struct {
+	// LastSyncTime is when the repository was last synced, whether or not the sync succeeded.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Commit is the commit of the branch of the repository that was last synced successfully.
+	Commit string `json:"commit,omitempty"`
+
+	// SyncError is the error the last sync failed with. It is empty if the last sync succeeded.
+	SyncError string `json:"syncError,omitempty"`
+
+	// Shard is the shard of the Repository, and of its PackageRevisions, when the operator is
+	// sharded, with the replica that owned it when the Repository was last synced.
+	Shard *RepositoryShard `json:"shard,omitempty"`
+
+	// Conditions describes the reconciliation state of the object.
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+}
+
+// RepositoryShard is the shard of a Repository.
+type RepositoryShard struct {
+	// Index is the number of the shard.
+	Index int32 `json:"index"`
+
+	// Owner is the identity of the replica of the operator that owns the shard.
+	Owner string `json:"owner"`
+}
+
+// GitRepository describes a Git repository.
+type GitRepository struct {
+	// Address of the Git repository, for example:
+	//   `https://github.com/GoogleCloudPlatform/blueprints.git`
+	Repo string `json:"repo"`
+
+	// Name of the branch containing the packages. Finalized packages will be committed to this branch (if the repository allows write access). If unspecified, defaults to "main".
+	Branch string `json:"branch,omitempty"`
+
+	// Directory within the Git repository where the packages are stored. A subdirectory of this directory containing a Kptfile is considered a package. If unspecified, defaults to root directory.
+	Directory string `json:"directory,omitempty"`
+
+	// Reference to secret containing authentication credentials. Optional.
+	SecretRef SecretRef `json:"secretRef,omitempty"`
+}
+
+// OciRepository describes a repository compatible with the Open Container Registry standard.
+type OciRepository struct {
+	// Registry is the address of the OCI registry
+	Registry string `json:"registry"`
+
+	// Reference to secret containing authentication credentials. Optional.
+	SecretRef SecretRef `json:"secretRef,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Repository{}, &RepositoryList{})
+}
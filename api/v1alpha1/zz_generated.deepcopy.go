@@ -25,110 +25,1062 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AllowedFunction) DeepCopyInto(out *AllowedFunction) {
+	*out = *in
+	if in.Digests != nil {
+		in, out := &in.Digests, &out.Digests
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AllowedFunction.
+func (in *AllowedFunction) DeepCopy() *AllowedFunction {
+	if in == nil {
+		return nil
+	}
+	out := new(AllowedFunction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiffRequest) DeepCopyInto(out *DiffRequest) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiffRequest.
+func (in *DiffRequest) DeepCopy() *DiffRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(DiffRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiffResponse) DeepCopyInto(out *DiffResponse) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceDiff, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]FileDiff, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiffResponse.
+func (in *DiffResponse) DeepCopy() *DiffResponse {
+	if in == nil {
+		return nil
+	}
+	out := new(DiffResponse)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FieldDiff) DeepCopyInto(out *FieldDiff) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FieldDiff.
+func (in *FieldDiff) DeepCopy() *FieldDiff {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldDiff)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileDiff) DeepCopyInto(out *FileDiff) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileDiff.
+func (in *FileDiff) DeepCopy() *FileDiff {
+	if in == nil {
+		return nil
+	}
+	out := new(FileDiff)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FunctionLimits) DeepCopyInto(out *FunctionLimits) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxSteps != nil {
+		in, out := &in.MaxSteps, &out.MaxSteps
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FunctionLimits.
+func (in *FunctionLimits) DeepCopy() *FunctionLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(FunctionLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FunctionPolicy) DeepCopyInto(out *FunctionPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FunctionPolicy.
+func (in *FunctionPolicy) DeepCopy() *FunctionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(FunctionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FunctionPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FunctionPolicyList) DeepCopyInto(out *FunctionPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FunctionPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FunctionPolicyList.
+func (in *FunctionPolicyList) DeepCopy() *FunctionPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(FunctionPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FunctionPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FunctionPolicySpec) DeepCopyInto(out *FunctionPolicySpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedFunctions != nil {
+		in, out := &in.AllowedFunctions, &out.AllowedFunctions
+		*out = make([]AllowedFunction, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = new(FunctionLimits)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FunctionPolicySpec.
+func (in *FunctionPolicySpec) DeepCopy() *FunctionPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FunctionPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FunctionResult) DeepCopyInto(out *FunctionResult) {
+	*out = *in
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]ResultItem, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FunctionResult.
+func (in *FunctionResult) DeepCopy() *FunctionResult {
+	if in == nil {
+		return nil
+	}
+	out := new(FunctionResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitLock) DeepCopyInto(out *GitLock) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLock.
-func (in *GitLock) DeepCopy() *GitLock {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLock.
+func (in *GitLock) DeepCopy() *GitLock {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitPackage) DeepCopyInto(out *GitPackage) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitPackage.
+func (in *GitPackage) DeepCopy() *GitPackage {
+	if in == nil {
+		return nil
+	}
+	out := new(GitPackage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitRepository) DeepCopyInto(out *GitRepository) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRepository.
+func (in *GitRepository) DeepCopy() *GitRepository {
+	if in == nil {
+		return nil
+	}
+	out := new(GitRepository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectionPoint) DeepCopyInto(out *InjectionPoint) {
+	*out = *in
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(InjectionSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectionPoint.
+func (in *InjectionPoint) DeepCopy() *InjectionPoint {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectionPoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectionSelector) DeepCopyInto(out *InjectionSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectionSelector.
+func (in *InjectionSelector) DeepCopy() *InjectionSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectionSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectionSource) DeepCopyInto(out *InjectionSource) {
+	*out = *in
+	out.InjectionSelector = in.InjectionSelector
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectionSource.
+func (in *InjectionSource) DeepCopy() *InjectionSource {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectionSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MergeConflict) DeepCopyInto(out *MergeConflict) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MergeConflict.
+func (in *MergeConflict) DeepCopy() *MergeConflict {
+	if in == nil {
+		return nil
+	}
+	out := new(MergeConflict)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectSelector) DeepCopyInto(out *ObjectSelector) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectSelector.
+func (in *ObjectSelector) DeepCopy() *ObjectSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OciPackage) DeepCopyInto(out *OciPackage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OciPackage.
+func (in *OciPackage) DeepCopy() *OciPackage {
+	if in == nil {
+		return nil
+	}
+	out := new(OciPackage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OciRepository) DeepCopyInto(out *OciRepository) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OciRepository.
+func (in *OciRepository) DeepCopy() *OciRepository {
+	if in == nil {
+		return nil
+	}
+	out := new(OciRepository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageCloneTaskSpec) DeepCopyInto(out *PackageCloneTaskSpec) {
+	*out = *in
+	in.Upstream.DeepCopyInto(&out.Upstream)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageCloneTaskSpec.
+func (in *PackageCloneTaskSpec) DeepCopy() *PackageCloneTaskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageCloneTaskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageEditTaskSpec) DeepCopyInto(out *PackageEditTaskSpec) {
+	*out = *in
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(PackageRevisionRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageEditTaskSpec.
+func (in *PackageEditTaskSpec) DeepCopy() *PackageEditTaskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageEditTaskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageInitTaskSpec) DeepCopyInto(out *PackageInitTaskSpec) {
+	*out = *in
+	if in.Keywords != nil {
+		in, out := &in.Keywords, &out.Keywords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageInitTaskSpec.
+func (in *PackageInitTaskSpec) DeepCopy() *PackageInitTaskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageInitTaskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevision) DeepCopyInto(out *PackageRevision) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevision.
+func (in *PackageRevision) DeepCopy() *PackageRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PackageRevision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionHistory) DeepCopyInto(out *PackageRevisionHistory) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionHistory.
+func (in *PackageRevisionHistory) DeepCopy() *PackageRevisionHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PackageRevisionHistory) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionHistoryEntry) DeepCopyInto(out *PackageRevisionHistoryEntry) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionHistoryEntry.
+func (in *PackageRevisionHistoryEntry) DeepCopy() *PackageRevisionHistoryEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionHistoryEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionHistoryList) DeepCopyInto(out *PackageRevisionHistoryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PackageRevisionHistory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionHistoryList.
+func (in *PackageRevisionHistoryList) DeepCopy() *PackageRevisionHistoryList {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionHistoryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PackageRevisionHistoryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionHistorySpec) DeepCopyInto(out *PackageRevisionHistorySpec) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]PackageRevisionHistoryEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionHistorySpec.
+func (in *PackageRevisionHistorySpec) DeepCopy() *PackageRevisionHistorySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionHistorySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionList) DeepCopyInto(out *PackageRevisionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PackageRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionList.
+func (in *PackageRevisionList) DeepCopy() *PackageRevisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PackageRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionRef) DeepCopyInto(out *PackageRevisionRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionRef.
+func (in *PackageRevisionRef) DeepCopy() *PackageRevisionRef {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionResources) DeepCopyInto(out *PackageRevisionResources) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionResources.
+func (in *PackageRevisionResources) DeepCopy() *PackageRevisionResources {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionResources)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PackageRevisionResources) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionResourcesList) DeepCopyInto(out *PackageRevisionResourcesList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PackageRevisionResources, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionResourcesList.
+func (in *PackageRevisionResourcesList) DeepCopy() *PackageRevisionResourcesList {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionResourcesList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PackageRevisionResourcesList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionResourcesSpec) DeepCopyInto(out *PackageRevisionResourcesSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionResourcesSpec.
+func (in *PackageRevisionResourcesSpec) DeepCopy() *PackageRevisionResourcesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionResourcesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionSpec) DeepCopyInto(out *PackageRevisionSpec) {
+	*out = *in
+	if in.Parent != nil {
+		in, out := &in.Parent, &out.Parent
+		*out = new(ParentReference)
+		**out = **in
+	}
+	if in.Tasks != nil {
+		in, out := &in.Tasks, &out.Tasks
+		*out = make([]Task, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]ReadinessGate, len(*in))
+		copy(*out, *in)
+	}
+	if in.Injectors != nil {
+		in, out := &in.Injectors, &out.Injectors
+		*out = make([]InjectionSelector, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionSpec.
+func (in *PackageRevisionSpec) DeepCopy() *PackageRevisionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionStatus) DeepCopyInto(out *PackageRevisionStatus) {
+	*out = *in
+	if in.UpstreamLock != nil {
+		in, out := &in.UpstreamLock, &out.UpstreamLock
+		*out = new(UpstreamLock)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProposedAt != nil {
+		in, out := &in.ProposedAt, &out.ProposedAt
+		*out = (*in).DeepCopy()
+	}
+	in.PublishedAt.DeepCopyInto(&out.PublishedAt)
+	if in.InjectionPoints != nil {
+		in, out := &in.InjectionPoints, &out.InjectionPoints
+		*out = make([]InjectionPoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RenderResults != nil {
+		in, out := &in.RenderResults, &out.RenderResults
+		*out = make([]FunctionResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MergeConflicts != nil {
+		in, out := &in.MergeConflicts, &out.MergeConflicts
+		*out = make([]MergeConflict, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionStatus.
+func (in *PackageRevisionStatus) DeepCopy() *PackageRevisionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageUpgradeTaskSpec) DeepCopyInto(out *PackageUpgradeTaskSpec) {
+	*out = *in
+	out.OldUpstream = in.OldUpstream
+	out.NewUpstream = in.NewUpstream
+	out.LocalPackageRevisionRef = in.LocalPackageRevisionRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageUpgradeTaskSpec.
+func (in *PackageUpgradeTaskSpec) DeepCopy() *PackageUpgradeTaskSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(GitLock)
+	out := new(PackageUpgradeTaskSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GitPackage) DeepCopyInto(out *GitPackage) {
+func (in *PackageVariantSet) DeepCopyInto(out *PackageVariantSet) {
 	*out = *in
-	out.SecretRef = in.SecretRef
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitPackage.
-func (in *GitPackage) DeepCopy() *GitPackage {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageVariantSet.
+func (in *PackageVariantSet) DeepCopy() *PackageVariantSet {
 	if in == nil {
 		return nil
 	}
-	out := new(GitPackage)
+	out := new(PackageVariantSet)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PackageVariantSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OciPackage) DeepCopyInto(out *OciPackage) {
+func (in *PackageVariantSetList) DeepCopyInto(out *PackageVariantSetList) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PackageVariantSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OciPackage.
-func (in *OciPackage) DeepCopy() *OciPackage {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageVariantSetList.
+func (in *PackageVariantSetList) DeepCopy() *PackageVariantSetList {
 	if in == nil {
 		return nil
 	}
-	out := new(OciPackage)
+	out := new(PackageVariantSetList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PackageVariantSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageCloneTaskSpec) DeepCopyInto(out *PackageCloneTaskSpec) {
+func (in *PackageVariantSetSpec) DeepCopyInto(out *PackageVariantSetSpec) {
 	*out = *in
-	in.Upstream.DeepCopyInto(&out.Upstream)
+	out.Upstream = in.Upstream
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]Target, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageCloneTaskSpec.
-func (in *PackageCloneTaskSpec) DeepCopy() *PackageCloneTaskSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageVariantSetSpec.
+func (in *PackageVariantSetSpec) DeepCopy() *PackageVariantSetSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageCloneTaskSpec)
+	out := new(PackageVariantSetSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageEditTaskSpec) DeepCopyInto(out *PackageEditTaskSpec) {
+func (in *PackageVariantSetStatus) DeepCopyInto(out *PackageVariantSetStatus) {
 	*out = *in
-	if in.Source != nil {
-		in, out := &in.Source, &out.Source
-		*out = new(PackageRevisionRef)
-		**out = **in
+	if in.DownstreamTargets != nil {
+		in, out := &in.DownstreamTargets, &out.DownstreamTargets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageEditTaskSpec.
-func (in *PackageEditTaskSpec) DeepCopy() *PackageEditTaskSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageVariantSetStatus.
+func (in *PackageVariantSetStatus) DeepCopy() *PackageVariantSetStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageEditTaskSpec)
+	out := new(PackageVariantSetStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageInitTaskSpec) DeepCopyInto(out *PackageInitTaskSpec) {
+func (in *PackageVariantTemplate) DeepCopyInto(out *PackageVariantTemplate) {
 	*out = *in
-	if in.Keywords != nil {
-		in, out := &in.Keywords, &out.Keywords
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Injectors != nil {
+		in, out := &in.Injectors, &out.Injectors
+		*out = make([]InjectionSelector, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageVariantTemplate.
+func (in *PackageVariantTemplate) DeepCopy() *PackageVariantTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageVariantTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParentReference) DeepCopyInto(out *ParentReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParentReference.
+func (in *ParentReference) DeepCopy() *ParentReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ParentReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreviewRequest) DeepCopyInto(out *PreviewRequest) {
+	*out = *in
+	if in.Spec != nil {
+		in, out := &in.Spec, &out.Spec
+		*out = new(PackageRevisionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DeletedResources != nil {
+		in, out := &in.DeletedResources, &out.DeletedResources
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageInitTaskSpec.
-func (in *PackageInitTaskSpec) DeepCopy() *PackageInitTaskSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreviewRequest.
+func (in *PreviewRequest) DeepCopy() *PreviewRequest {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageInitTaskSpec)
+	out := new(PreviewRequest)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageRevision) DeepCopyInto(out *PackageRevision) {
+func (in *PreviewResponse) DeepCopyInto(out *PreviewResponse) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RenderResults != nil {
+		in, out := &in.RenderResults, &out.RenderResults
+		*out = make([]FunctionResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Diff != nil {
+		in, out := &in.Diff, &out.Diff
+		*out = make([]FileDiff, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreviewResponse.
+func (in *PreviewResponse) DeepCopy() *PreviewResponse {
+	if in == nil {
+		return nil
+	}
+	out := new(PreviewResponse)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessGate) DeepCopyInto(out *ReadinessGate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessGate.
+func (in *ReadinessGate) DeepCopy() *ReadinessGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Repository) DeepCopyInto(out *Repository) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -136,18 +1088,18 @@ func (in *PackageRevision) DeepCopyInto(out *PackageRevision) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevision.
-func (in *PackageRevision) DeepCopy() *PackageRevision {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Repository.
+func (in *Repository) DeepCopy() *Repository {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageRevision)
+	out := new(Repository)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PackageRevision) DeepCopyObject() runtime.Object {
+func (in *Repository) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -155,31 +1107,31 @@ func (in *PackageRevision) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageRevisionList) DeepCopyInto(out *PackageRevisionList) {
+func (in *RepositoryList) DeepCopyInto(out *RepositoryList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]PackageRevision, len(*in))
+		*out = make([]Repository, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionList.
-func (in *PackageRevisionList) DeepCopy() *PackageRevisionList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryList.
+func (in *RepositoryList) DeepCopy() *RepositoryList {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageRevisionList)
+	out := new(RepositoryList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PackageRevisionList) DeepCopyObject() runtime.Object {
+func (in *RepositoryList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -187,61 +1139,62 @@ func (in *PackageRevisionList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageRevisionRef) DeepCopyInto(out *PackageRevisionRef) {
+func (in *RepositoryShard) DeepCopyInto(out *RepositoryShard) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionRef.
-func (in *PackageRevisionRef) DeepCopy() *PackageRevisionRef {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryShard.
+func (in *RepositoryShard) DeepCopy() *RepositoryShard {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageRevisionRef)
+	out := new(RepositoryShard)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageRevisionSpec) DeepCopyInto(out *PackageRevisionSpec) {
+func (in *RepositorySpec) DeepCopyInto(out *RepositorySpec) {
 	*out = *in
-	if in.Parent != nil {
-		in, out := &in.Parent, &out.Parent
-		*out = new(ParentReference)
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitRepository)
 		**out = **in
 	}
-	if in.Tasks != nil {
-		in, out := &in.Tasks, &out.Tasks
-		*out = make([]Task, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Oci != nil {
+		in, out := &in.Oci, &out.Oci
+		*out = new(OciRepository)
+		**out = **in
 	}
-	if in.ReadinessGates != nil {
-		in, out := &in.ReadinessGates, &out.ReadinessGates
-		*out = make([]ReadinessGate, len(*in))
-		copy(*out, *in)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionSpec.
-func (in *PackageRevisionSpec) DeepCopy() *PackageRevisionSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositorySpec.
+func (in *RepositorySpec) DeepCopy() *RepositorySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageRevisionSpec)
+	out := new(RepositorySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageRevisionStatus) DeepCopyInto(out *PackageRevisionStatus) {
+func (in *RepositoryStatus) DeepCopyInto(out *RepositoryStatus) {
 	*out = *in
-	if in.UpstreamLock != nil {
-		in, out := &in.UpstreamLock, &out.UpstreamLock
-		*out = new(UpstreamLock)
-		(*in).DeepCopyInto(*out)
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Shard != nil {
+		in, out := &in.Shard, &out.Shard
+		*out = new(RepositoryShard)
+		**out = **in
 	}
-	in.PublishedAt.DeepCopyInto(&out.PublishedAt)
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -251,60 +1204,87 @@ func (in *PackageRevisionStatus) DeepCopyInto(out *PackageRevisionStatus) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionStatus.
-func (in *PackageRevisionStatus) DeepCopy() *PackageRevisionStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryStatus.
+func (in *RepositoryStatus) DeepCopy() *RepositoryStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageRevisionStatus)
+	out := new(RepositoryStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageUpgradeTaskSpec) DeepCopyInto(out *PackageUpgradeTaskSpec) {
+func (in *RepositoryTarget) DeepCopyInto(out *RepositoryTarget) {
 	*out = *in
-	out.OldUpstream = in.OldUpstream
-	out.NewUpstream = in.NewUpstream
-	out.LocalPackageRevisionRef = in.LocalPackageRevisionRef
+	if in.PackageNames != nil {
+		in, out := &in.PackageNames, &out.PackageNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageUpgradeTaskSpec.
-func (in *PackageUpgradeTaskSpec) DeepCopy() *PackageUpgradeTaskSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryTarget.
+func (in *RepositoryTarget) DeepCopy() *RepositoryTarget {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageUpgradeTaskSpec)
+	out := new(RepositoryTarget)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ParentReference) DeepCopyInto(out *ParentReference) {
+func (in *ResourceDiff) DeepCopyInto(out *ResourceDiff) {
 	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]FieldDiff, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParentReference.
-func (in *ParentReference) DeepCopy() *ParentReference {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceDiff.
+func (in *ResourceDiff) DeepCopy() *ResourceDiff {
 	if in == nil {
 		return nil
 	}
-	out := new(ParentReference)
+	out := new(ResourceDiff)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReadinessGate) DeepCopyInto(out *ReadinessGate) {
+func (in *ResultItem) DeepCopyInto(out *ResultItem) {
 	*out = *in
+	if in.ResourceRef != nil {
+		in, out := &in.ResourceRef, &out.ResourceRef
+		*out = new(ResultResourceRef)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessGate.
-func (in *ReadinessGate) DeepCopy() *ReadinessGate {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResultItem.
+func (in *ResultItem) DeepCopy() *ResultItem {
 	if in == nil {
 		return nil
 	}
-	out := new(ReadinessGate)
+	out := new(ResultItem)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResultResourceRef) DeepCopyInto(out *ResultResourceRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResultResourceRef.
+func (in *ResultResourceRef) DeepCopy() *ResultResourceRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ResultResourceRef)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -324,6 +1304,43 @@ func (in *SecretRef) DeepCopy() *SecretRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Target) DeepCopyInto(out *Target) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]RepositoryTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RepositorySelector != nil {
+		in, out := &in.RepositorySelector, &out.RepositorySelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ObjectSelector != nil {
+		in, out := &in.ObjectSelector, &out.ObjectSelector
+		*out = new(ObjectSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(PackageVariantTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Target.
+func (in *Target) DeepCopy() *Target {
+	if in == nil {
+		return nil
+	}
+	out := new(Target)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Task) DeepCopyInto(out *Task) {
 	*out = *in
@@ -0,0 +1,248 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+// The conversions between v1beta1 and v1alpha1 lose nothing: every field of either version has a
+// counterpart in the other. The fields of v1beta1 that differ from those of v1alpha1 are converted
+// explicitly, and the others by converting between their identical struct types.
+
+var _ conversion.Convertible = &PackageRevision{}
+
+// ConvertTo converts this PackageRevision to the hub version, v1alpha1.
+func (src *PackageRevision) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1alpha1.PackageRevision)
+	if !ok {
+		return fmt.Errorf("expected a v1alpha1 PackageRevision but got %T", dstRaw)
+	}
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1alpha1.PackageRevisionSpec{
+		PackageName:    src.Spec.PackageName,
+		RepositoryName: src.Spec.RepositoryName,
+		WorkspaceName:  src.Spec.WorkspaceName,
+		Revision:       src.Spec.Revision,
+		Parent:         (*v1alpha1.ParentReference)(src.Spec.Parent),
+		Lifecycle:      v1alpha1.PackageRevisionLifecycle(src.Spec.Lifecycle),
+		Tasks:          convertSlice(src.Spec.Tasks, taskTo),
+		ReadinessGates: convertSlice(src.Spec.ReadinessGates,
+			func(r ReadinessGate) v1alpha1.ReadinessGate { return v1alpha1.ReadinessGate(r) }),
+		Injectors: convertSlice(src.Spec.Injectors,
+			func(i InjectionSelector) v1alpha1.InjectionSelector { return v1alpha1.InjectionSelector(i) }),
+	}
+	dst.Status = v1alpha1.PackageRevisionStatus{
+		ProposedAt:                  src.Status.ProposedAt,
+		ObservedLifecycle:           v1alpha1.PackageRevisionLifecycle(src.Status.ObservedLifecycle),
+		ObservedResourcesGeneration: src.Status.ObservedResourcesGeneration,
+		EditedResourcesGeneration:   src.Status.EditedResourcesGeneration,
+		PublishedBy:                 src.Status.PublishedBy,
+		Deployment:                  src.Status.Deployment,
+		InjectionPoints:             convertSlice(src.Status.InjectionPoints, injectionPointTo),
+		RenderResults:               convertSlice(src.Status.RenderResults, functionResultTo),
+		MergeConflicts: convertSlice(src.Status.MergeConflicts,
+			func(m MergeConflict) v1alpha1.MergeConflict { return v1alpha1.MergeConflict(m) }),
+		Conditions: src.Status.Conditions,
+	}
+	if lock := src.Status.UpstreamLock; lock != nil {
+		dst.Status.UpstreamLock = &v1alpha1.UpstreamLock{Type: v1alpha1.OriginType(lock.Type), Git: (*v1alpha1.GitLock)(lock.Git)}
+	}
+	if src.Status.PublishedAt != nil {
+		dst.Status.PublishedAt = *src.Status.PublishedAt
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version, v1alpha1, to this PackageRevision.
+func (dst *PackageRevision) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1alpha1.PackageRevision)
+	if !ok {
+		return fmt.Errorf("expected a v1alpha1 PackageRevision but got %T", srcRaw)
+	}
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = PackageRevisionSpec{
+		PackageName:    src.Spec.PackageName,
+		RepositoryName: src.Spec.RepositoryName,
+		WorkspaceName:  src.Spec.WorkspaceName,
+		Revision:       src.Spec.Revision,
+		Parent:         (*ParentReference)(src.Spec.Parent),
+		Lifecycle:      PackageRevisionLifecycle(src.Spec.Lifecycle),
+		Tasks:          convertSlice(src.Spec.Tasks, taskFrom),
+		ReadinessGates: convertSlice(src.Spec.ReadinessGates,
+			func(r v1alpha1.ReadinessGate) ReadinessGate { return ReadinessGate(r) }),
+		Injectors: convertSlice(src.Spec.Injectors,
+			func(i v1alpha1.InjectionSelector) InjectionSelector { return InjectionSelector(i) }),
+	}
+	dst.Status = PackageRevisionStatus{
+		ProposedAt:                  src.Status.ProposedAt,
+		ObservedLifecycle:           PackageRevisionLifecycle(src.Status.ObservedLifecycle),
+		ObservedResourcesGeneration: src.Status.ObservedResourcesGeneration,
+		EditedResourcesGeneration:   src.Status.EditedResourcesGeneration,
+		PublishedBy:                 src.Status.PublishedBy,
+		Deployment:                  src.Status.Deployment,
+		InjectionPoints:             convertSlice(src.Status.InjectionPoints, injectionPointFrom),
+		RenderResults:               convertSlice(src.Status.RenderResults, functionResultFrom),
+		MergeConflicts: convertSlice(src.Status.MergeConflicts,
+			func(m v1alpha1.MergeConflict) MergeConflict { return MergeConflict(m) }),
+		Conditions: src.Status.Conditions,
+	}
+	if lock := src.Status.UpstreamLock; lock != nil {
+		dst.Status.UpstreamLock = &UpstreamLock{Type: OriginType(lock.Type), Git: (*GitLock)(lock.Git)}
+	}
+	// v1alpha1 does not tell an unset publication time from a zero one.
+	if !src.Status.PublishedAt.IsZero() {
+		dst.Status.PublishedAt = src.Status.PublishedAt.DeepCopy()
+	}
+	return nil
+}
+
+func taskTo(src Task) v1alpha1.Task {
+	dst := v1alpha1.Task{
+		Type: v1alpha1.TaskType(src.Type),
+		Init: (*v1alpha1.PackageInitTaskSpec)(src.Init),
+	}
+	if src.Clone != nil {
+		dst.Clone = &v1alpha1.PackageCloneTaskSpec{
+			Upstream: upstreamPackageTo(src.Clone.Upstream),
+			Strategy: v1alpha1.PackageMergeStrategy(src.Clone.Strategy),
+		}
+	}
+	if src.Edit != nil {
+		dst.Edit = &v1alpha1.PackageEditTaskSpec{Source: (*v1alpha1.PackageRevisionRef)(src.Edit.Source)}
+	}
+	if src.Upgrade != nil {
+		dst.Upgrade = &v1alpha1.PackageUpgradeTaskSpec{
+			OldUpstream:             v1alpha1.PackageRevisionRef(src.Upgrade.OldUpstream),
+			NewUpstream:             v1alpha1.PackageRevisionRef(src.Upgrade.NewUpstream),
+			LocalPackageRevisionRef: v1alpha1.PackageRevisionRef(src.Upgrade.LocalPackageRevisionRef),
+			Strategy:                v1alpha1.PackageMergeStrategy(src.Upgrade.Strategy),
+		}
+	}
+	return dst
+}
+
+func taskFrom(src v1alpha1.Task) Task {
+	dst := Task{
+		Type: TaskType(src.Type),
+		Init: (*PackageInitTaskSpec)(src.Init),
+	}
+	if src.Clone != nil {
+		dst.Clone = &PackageCloneTaskSpec{
+			Upstream: upstreamPackageFrom(src.Clone.Upstream),
+			Strategy: PackageMergeStrategy(src.Clone.Strategy),
+		}
+	}
+	if src.Edit != nil {
+		dst.Edit = &PackageEditTaskSpec{Source: (*PackageRevisionRef)(src.Edit.Source)}
+	}
+	if src.Upgrade != nil {
+		dst.Upgrade = &PackageUpgradeTaskSpec{
+			OldUpstream:             PackageRevisionRef(src.Upgrade.OldUpstream),
+			NewUpstream:             PackageRevisionRef(src.Upgrade.NewUpstream),
+			LocalPackageRevisionRef: PackageRevisionRef(src.Upgrade.LocalPackageRevisionRef),
+			Strategy:                PackageMergeStrategy(src.Upgrade.Strategy),
+		}
+	}
+	return dst
+}
+
+func upstreamPackageTo(src UpstreamPackage) v1alpha1.UpstreamPackage {
+	dst := v1alpha1.UpstreamPackage{
+		Type:        v1alpha1.RepositoryType(src.Type),
+		Oci:         (*v1alpha1.OciPackage)(src.Oci),
+		UpstreamRef: (*v1alpha1.PackageRevisionRef)(src.UpstreamRef),
+	}
+	if src.Git != nil {
+		dst.Git = &v1alpha1.GitPackage{Repo: src.Git.Repo, Ref: src.Git.Ref, Directory: src.Git.Directory,
+			SecretRef: v1alpha1.SecretRef(src.Git.SecretRef)}
+	}
+	return dst
+}
+
+func upstreamPackageFrom(src v1alpha1.UpstreamPackage) UpstreamPackage {
+	dst := UpstreamPackage{
+		Type:        RepositoryType(src.Type),
+		Oci:         (*OciPackage)(src.Oci),
+		UpstreamRef: (*PackageRevisionRef)(src.UpstreamRef),
+	}
+	if src.Git != nil {
+		dst.Git = &GitPackage{Repo: src.Git.Repo, Ref: src.Git.Ref, Directory: src.Git.Directory,
+			SecretRef: SecretRef(src.Git.SecretRef)}
+	}
+	return dst
+}
+
+func injectionPointTo(src InjectionPoint) v1alpha1.InjectionPoint {
+	dst := v1alpha1.InjectionPoint{File: src.File, Group: src.Group, Kind: src.Kind, Name: src.Name,
+		Required: src.Required, Message: src.Message}
+	if src.Source != nil {
+		dst.Source = &v1alpha1.InjectionSource{
+			InjectionSelector: v1alpha1.InjectionSelector(src.Source.InjectionSelector),
+			ResourceVersion:   src.Source.ResourceVersion,
+		}
+	}
+	return dst
+}
+
+func injectionPointFrom(src v1alpha1.InjectionPoint) InjectionPoint {
+	dst := InjectionPoint{File: src.File, Group: src.Group, Kind: src.Kind, Name: src.Name,
+		Required: src.Required, Message: src.Message}
+	if src.Source != nil {
+		dst.Source = &InjectionSource{
+			InjectionSelector: InjectionSelector(src.Source.InjectionSelector),
+			ResourceVersion:   src.Source.ResourceVersion,
+		}
+	}
+	return dst
+}
+
+func functionResultTo(src FunctionResult) v1alpha1.FunctionResult {
+	return v1alpha1.FunctionResult{Image: src.Image, Name: src.Name, Package: src.Package, Stage: src.Stage,
+		Results: convertSlice(src.Results, resultItemTo), Error: src.Error}
+}
+
+func functionResultFrom(src v1alpha1.FunctionResult) FunctionResult {
+	return FunctionResult{Image: src.Image, Name: src.Name, Package: src.Package, Stage: src.Stage,
+		Results: convertSlice(src.Results, resultItemFrom), Error: src.Error}
+}
+
+func resultItemTo(src ResultItem) v1alpha1.ResultItem {
+	return v1alpha1.ResultItem{Message: src.Message, Severity: src.Severity,
+		ResourceRef: (*v1alpha1.ResultResourceRef)(src.ResourceRef), Field: src.Field, File: src.File}
+}
+
+func resultItemFrom(src v1alpha1.ResultItem) ResultItem {
+	return ResultItem{Message: src.Message, Severity: src.Severity,
+		ResourceRef: (*ResultResourceRef)(src.ResourceRef), Field: src.Field, File: src.File}
+}
+
+// convertSlice converts the elements of a slice, keeping nil slices nil.
+func convertSlice[S, D any](src []S, convert func(S) D) []D {
+	if src == nil {
+		return nil
+	}
+	dst := make([]D, len(src))
+	for i := range src {
+		dst[i] = convert(src[i])
+	}
+	return dst
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/randfill"
+
+	"github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+var _ = Describe("PackageRevision conversion", func() {
+	// rounds is the number of random PackageRevisions that are round-tripped.
+	const rounds = 1000
+
+	// filler fills every field of PackageRevisions with random values. The conversions do not
+	// set the type meta, so it is left empty. Times are never zero: PublishedAt is the one field
+	// whose zero value is not kept, and is tested on its own.
+	filler := func(seed int64) *randfill.Filler {
+		return randfill.NewWithSeed(seed).NilChance(0.2).NumElements(0, 3).Funcs(
+			func(t *metav1.Time, c randfill.Continue) { *t = metav1.Unix(1+c.Int63n(1<<32), 0) },
+			func(t *metav1.TypeMeta, c randfill.Continue) {},
+		)
+	}
+
+	It("should convert v1alpha1 to v1beta1 and back without loss", func() {
+		f := filler(1)
+		for range rounds {
+			hub := &v1alpha1.PackageRevision{}
+			f.Fill(hub)
+			spoke := &PackageRevision{}
+			Expect(spoke.ConvertFrom(hub.DeepCopy())).To(Succeed())
+			roundTripped := &v1alpha1.PackageRevision{}
+			Expect(spoke.ConvertTo(roundTripped)).To(Succeed())
+			Expect(roundTripped).To(Equal(hub))
+		}
+	})
+
+	It("should convert v1beta1 to v1alpha1 and back without loss", func() {
+		f := filler(2)
+		for range rounds {
+			spoke := &PackageRevision{}
+			f.Fill(spoke)
+			hub := &v1alpha1.PackageRevision{}
+			Expect(spoke.DeepCopy().ConvertTo(hub)).To(Succeed())
+			roundTripped := &PackageRevision{}
+			Expect(roundTripped.ConvertFrom(hub)).To(Succeed())
+			Expect(roundTripped).To(Equal(spoke))
+		}
+	})
+
+	It("should convert an unset publication time to an unset one", func() {
+		hub := &v1alpha1.PackageRevision{}
+		spoke := &PackageRevision{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Status.PublishedAt).To(BeNil())
+		Expect(spoke.ConvertTo(hub)).To(Succeed())
+		Expect(hub.Status.PublishedAt.IsZero()).To(BeTrue())
+	})
+
+	It("should rename the upstream of clone tasks", func() {
+		hub := &v1alpha1.PackageRevision{Spec: v1alpha1.PackageRevisionSpec{Tasks: []v1alpha1.Task{{
+			Type: v1alpha1.TaskTypeClone,
+			Clone: &v1alpha1.PackageCloneTaskSpec{Upstream: v1alpha1.UpstreamPackage{
+				UpstreamRef: &v1alpha1.PackageRevisionRef{Name: "blueprints.app.v1"}}},
+		}}}}
+		spoke := &PackageRevision{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		data, err := json.Marshal(spoke.Spec.Tasks[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"type":"clone","clone":{"upstream":{"upstreamRef":{"name":"blueprints.app.v1"}}}}`))
+	})
+})
@@ -0,0 +1,471 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+
+// PackageRevisionList contains a list of PackageRevision.
+type PackageRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PackageRevision `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Package",type=string,JSONPath=`.spec.packageName`
+// +kubebuilder:printcolumn:name="WorkspaceName",type=string,JSONPath=`.spec.workspaceName`
+// +kubebuilder:printcolumn:name="Revision",type=integer,JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Lifecycle",type=string,JSONPath=`.spec.lifecycle`
+// +kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.spec.repository`
+
+// PackageRevision is the Schema for the packagerevisions API.
+// It is a revision of a package in a repository. Its contents are held in the
+// PackageRevisionResources of the same name.
+type PackageRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PackageRevisionSpec   `json:"spec,omitempty"`
+	Status PackageRevisionStatus `json:"status,omitempty"`
+}
+
+// PackageRevisionSpec defines the desired state of PackageRevision.
+// The package, repository and workspace of a PackageRevision identify it, and are immutable.
+// +kubebuilder:validation:XValidation:rule="has(self.packageName) == has(oldSelf.packageName) && (!has(self.packageName) || self.packageName == oldSelf.packageName)",message="packageName is immutable"
+// +kubebuilder:validation:XValidation:rule="has(self.repository) == has(oldSelf.repository) && (!has(self.repository) || self.repository == oldSelf.repository)",message="repository is immutable"
+// +kubebuilder:validation:XValidation:rule="has(self.workspaceName) == has(oldSelf.workspaceName) && (!has(self.workspaceName) || self.workspaceName == oldSelf.workspaceName)",message="workspaceName is immutable"
+type PackageRevisionSpec struct {
+	// PackageName identifies the package in the repository.
+	PackageName string `json:"packageName,omitempty"`
+
+	// RepositoryName is the name of the Repository object containing this package.
+	RepositoryName string `json:"repository,omitempty"`
+
+	// WorkspaceName is a short, unique description of the changes contained in this package revision.
+	WorkspaceName string `json:"workspaceName,omitempty"`
+
+	// Revision identifies the version of the package. It is set when the package revision is
+	// published.
+	// +kubebuilder:validation:Minimum=-1
+	Revision int `json:"revision,omitempty"`
+
+	// Parent references a package that provides resources to this package.
+	Parent *ParentReference `json:"parent,omitempty"`
+
+	// Lifecycle is the stage of the package revision in its review and approval.
+	Lifecycle PackageRevisionLifecycle `json:"lifecycle,omitempty"`
+
+	// Tasks produce the contents of a draft, in order, when it is created.
+	// +kubebuilder:validation:MaxItems=32
+	Tasks []Task `json:"tasks,omitempty"`
+
+	// ReadinessGates are the conditions that must be true for the package revision to be ready.
+	ReadinessGates []ReadinessGate `json:"readinessGates,omitempty"`
+
+	// Injectors select the in-cluster objects whose values are injected into the package.
+	Injectors []InjectionSelector `json:"injectors,omitempty"`
+}
+
+// PackageRevisionStatus defines the observed state of PackageRevision.
+type PackageRevisionStatus struct {
+	// UpstreamLock identifies the upstream data for this package.
+	UpstreamLock *UpstreamLock `json:"upstreamLock,omitempty"`
+
+	// ProposedAt is when the packagerevision was last proposed for approval. It is cleared when the
+	// packagerevision goes back to Draft.
+	ProposedAt *metav1.Time `json:"proposedAt,omitempty"`
+
+	// ObservedLifecycle is the lifecycle of the packagerevision when it was last reconciled, so
+	// that changes of its lifecycle are noticed.
+	ObservedLifecycle PackageRevisionLifecycle `json:"observedLifecycle,omitempty"`
+
+	// ObservedResourcesGeneration is the generation of the packagerevisionresources of the
+	// packagerevision when it was last reconciled, so that edits of its resources are noticed.
+	ObservedResourcesGeneration int64 `json:"observedResourcesGeneration,omitempty"`
+
+	// EditedResourcesGeneration is the generation of the packagerevisionresources of the
+	// packagerevision written by their last edit, leaving out the writes of the operator itself.
+	EditedResourcesGeneration int64 `json:"editedResourcesGeneration,omitempty"`
+
+	// PublishedBy is the identity of the user who approved the packagerevision.
+	PublishedBy string `json:"publishedBy,omitempty"`
+
+	// PublishedAt is when the packagerevision was approved.
+	PublishedAt *metav1.Time `json:"publishedAt,omitempty"`
+
+	// Deployment is true if this is a deployment package (in a deployment repository).
+	Deployment bool `json:"deployment,omitempty"`
+
+	// InjectionPoints records the outcome of config injection for each injection point in the package.
+	InjectionPoints []InjectionPoint `json:"injectionPoints,omitempty"`
+
+	// RenderResults records the outcome of each function run when the package was last rendered.
+	RenderResults []FunctionResult `json:"renderResults,omitempty"`
+
+	// MergeConflicts lists the conflicts left in the package by merging upstream changes into it,
+	// until they are resolved.
+	MergeConflicts []MergeConflict `json:"mergeConflicts,omitempty"`
+
+	// Conditions are the observations of the state of the packagerevision: whether its tasks
+	// were applied, its config injected and its contents rendered, and whether it has merge
+	// conflicts.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ParentReference is a reference to a parent package.
+type ParentReference struct {
+	// Name is the name of the parent PackageRevision.
+	Name string `json:"name"`
+}
+
+// PackageRevisionLifecycle is the stage of a package revision in its review and approval.
+// +kubebuilder:validation:Enum=Draft;Proposed;Published;DeletionProposed
+type PackageRevisionLifecycle string
+
+const (
+	PackageRevisionLifecycleDraft            PackageRevisionLifecycle = "Draft"
+	PackageRevisionLifecycleProposed         PackageRevisionLifecycle = "Proposed"
+	PackageRevisionLifecyclePublished        PackageRevisionLifecycle = "Published"
+	PackageRevisionLifecycleDeletionProposed PackageRevisionLifecycle = "DeletionProposed"
+)
+
+// Task produces or changes the contents of a draft. It is a union: exactly one of its members is
+// set, the one named by its type.
+// +kubebuilder:validation:XValidation:rule="(has(self.init) ? 1 : 0) + (has(self.clone) ? 1 : 0) + (has(self.edit) ? 1 : 0) + (has(self.upgrade) ? 1 : 0) == 1",message="exactly one of init, clone, edit and upgrade must be set"
+// +kubebuilder:validation:XValidation:rule="self.type == 'init' ? has(self.init) : self.type == 'clone' ? has(self.clone) : self.type == 'edit' ? has(self.edit) : has(self.upgrade)",message="the member that is set must be the one of the type"
+type Task struct {
+	// Type is the type of the task.
+	Type TaskType `json:"type"`
+
+	Init    *PackageInitTaskSpec    `json:"init,omitempty"`
+	Clone   *PackageCloneTaskSpec   `json:"clone,omitempty"`
+	Edit    *PackageEditTaskSpec    `json:"edit,omitempty"`
+	Upgrade *PackageUpgradeTaskSpec `json:"upgrade,omitempty"`
+}
+
+// TaskType is the type of a task.
+// +kubebuilder:validation:Enum=init;clone;edit;upgrade
+type TaskType string
+
+const (
+	TaskTypeInit    TaskType = "init"
+	TaskTypeClone   TaskType = "clone"
+	TaskTypeEdit    TaskType = "edit"
+	TaskTypeUpgrade TaskType = "upgrade"
+)
+
+// ReadinessGate is a condition that must be true for a package revision to be ready.
+type ReadinessGate struct {
+	// ConditionType is the type of the condition.
+	ConditionType string `json:"conditionType,omitempty"`
+}
+
+// PackageInitTaskSpec initializes a new package.
+type PackageInitTaskSpec struct {
+	// Subpackage is a directory path to a subpackage to initialize. If unspecified, the main package is initialized.
+	Subpackage string `json:"subpackage,omitempty"`
+	// Description is a short description of the package.
+	Description string `json:"description,omitempty"`
+	// Keywords is a list of keywords describing the package.
+	Keywords []string `json:"keywords,omitempty"`
+	// Site is a link to page with information about the package.
+	Site string `json:"site,omitempty"`
+}
+
+// PackageCloneTaskSpec clones an upstream package.
+type PackageCloneTaskSpec struct {
+	// Upstream is the upstream package to clone.
+	Upstream UpstreamPackage `json:"upstream"`
+
+	// Strategy is the strategy of the later upgrades of the package. It defaults to resource-merge.
+	Strategy PackageMergeStrategy `json:"strategy,omitempty"`
+}
+
+// PackageUpgradeTaskSpec upgrades a package to a newer revision of its upstream package.
+type PackageUpgradeTaskSpec struct {
+	// OldUpstream is the reference to the original upstream package revision that is the common
+	// ancestor of the local package and the new upstream package revision.
+	OldUpstream PackageRevisionRef `json:"oldUpstreamRef"`
+
+	// NewUpstream is the reference to the new upstream package revision that the local package
+	// is upgraded to.
+	NewUpstream PackageRevisionRef `json:"newUpstreamRef"`
+
+	// LocalPackageRevisionRef is the reference to the local package revision that contains all
+	// the local changes on top of the OldUpstream package revision.
+	LocalPackageRevisionRef PackageRevisionRef `json:"localPackageRevisionRef"`
+
+	// Strategy is the strategy of the upgrade. It defaults to resource-merge.
+	Strategy PackageMergeStrategy `json:"strategy,omitempty"`
+}
+
+// PackageEditTaskSpec copies the contents of another revision of the package.
+type PackageEditTaskSpec struct {
+	// Source is the package revision whose contents are copied.
+	// +kubebuilder:validation:Required
+	Source *PackageRevisionRef `json:"sourceRef,omitempty"`
+}
+
+// UpstreamPackage is a package to clone. It is a union: exactly one of git, oci and upstreamRef
+// is set, and the type, if set, names it.
+// +kubebuilder:validation:XValidation:rule="(has(self.git) ? 1 : 0) + (has(self.oci) ? 1 : 0) + (has(self.upstreamRef) ? 1 : 0) == 1",message="exactly one of git, oci and upstreamRef must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.type) || (self.type == 'git') == has(self.git) && (self.type == 'oci') == has(self.oci)",message="type must name the member that is set"
+type UpstreamPackage struct {
+	// Type of the repository of the package, git or oci. It is not set for an upstreamRef.
+	Type RepositoryType `json:"type,omitempty"`
+
+	// Git is a package in a git repository.
+	Git *GitPackage `json:"git,omitempty"`
+
+	// Oci is a package in an OCI registry.
+	Oci *OciPackage `json:"oci,omitempty"`
+
+	// UpstreamRef is a package revision of a registered repository.
+	UpstreamRef *PackageRevisionRef `json:"upstreamRef,omitempty"`
+}
+
+// RepositoryType is the type of a repository.
+// +kubebuilder:validation:Enum=git;oci
+type RepositoryType string
+
+const (
+	RepositoryTypeGit RepositoryType = "git"
+	RepositoryTypeOCI RepositoryType = "oci"
+)
+
+// GitPackage is a package in a git repository.
+type GitPackage struct {
+	// Repo is the address of the Git repository, for example:
+	//   `https://github.com/GoogleCloudPlatform/blueprints.git`
+	Repo string `json:"repo"`
+
+	// Ref is the git ref containing the package. Ref can be a branch, tag, or commit SHA.
+	Ref string `json:"ref"`
+
+	// Directory is the directory of the package within the Git repository.
+	Directory string `json:"directory"`
+
+	// SecretRef is the reference to the secret containing authentication credentials. Optional.
+	SecretRef SecretRef `json:"secretRef,omitempty"`
+}
+
+// SecretRef is a reference to a secret.
+type SecretRef struct {
+	// Name of the secret. The secret is expected to be located in the same namespace as the resource containing the reference.
+	Name string `json:"name"`
+}
+
+// OciPackage describes a repository compatible with the Open Container Registry standard.
+type OciPackage struct {
+	// Image is the address of an OCI image.
+	Image string `json:"image"`
+}
+
+// InjectionSelector identifies an in-cluster object used for config injection. Only ConfigMaps
+// are injected: Secrets never are, since their data would be stored in plain text in the package.
+type InjectionSelector struct {
+	// Group of the object. Empty for the core group.
+	Group string `json:"group,omitempty"`
+
+	// Version of the object.
+	Version string `json:"version,omitempty"`
+
+	// Kind of the object. If unspecified, the kind of the injection point is used.
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the object.
+	Name string `json:"name"`
+}
+
+// InjectionPoint is a resource in the package whose data is injected from an in-cluster object.
+type InjectionPoint struct {
+	// File is the path of the file in the package containing the injection point.
+	File string `json:"file"`
+
+	// Group of the injection point resource. Empty for the core group.
+	Group string `json:"group,omitempty"`
+
+	// Kind of the injection point resource.
+	Kind string `json:"kind"`
+
+	// Name of the injection point resource.
+	Name string `json:"name"`
+
+	// Required is true if the package is incomplete unless the injection point is injected.
+	Required bool `json:"required,omitempty"`
+
+	// Source is the in-cluster object that was injected, if any.
+	Source *InjectionSource `json:"source,omitempty"`
+
+	// Message describes why the injection point was not injected.
+	Message string `json:"message,omitempty"`
+}
+
+// InjectionSource identifies the version of an in-cluster object that was injected.
+type InjectionSource struct {
+	InjectionSelector `json:",inline"`
+
+	// ResourceVersion of the object at the time it was injected.
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// FunctionResult is the outcome of running a function of a Kptfile pipeline.
+type FunctionResult struct {
+	// Image of the function.
+	Image string `json:"image"`
+
+	// Name of the function in the pipeline, if it has one.
+	Name string `json:"name,omitempty"`
+
+	// Package is the directory of the package whose pipeline declares the function, "." for the root package.
+	Package string `json:"package"`
+
+	// Stage of the pipeline the function was run in.
+	// +kubebuilder:validation:Enum=mutator;validator
+	Stage string `json:"stage"`
+
+	// Results reported by the function.
+	Results []ResultItem `json:"results,omitempty"`
+
+	// Error is set if the function failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ResultItem is a result reported by a function.
+type ResultItem struct {
+	// Message is a human readable message.
+	Message string `json:"message"`
+
+	// Severity of the result, one of error, warning or info.
+	Severity string `json:"severity,omitempty"`
+
+	// ResourceRef identifies the resource the result refers to.
+	ResourceRef *ResultResourceRef `json:"resourceRef,omitempty"`
+
+	// Field is the path of the field in the resource that the result refers to.
+	Field string `json:"field,omitempty"`
+
+	// File is the path of the file containing the resource that the result refers to.
+	File string `json:"file,omitempty"`
+}
+
+// MergeConflict is a change made both upstream and locally that could not be merged.
+type MergeConflict struct {
+	// File is the path of the file holding the conflict, relative to the package root.
+	File string `json:"file"`
+
+	// APIVersion, Kind, Name and Namespace identify the resource holding the conflict. They are
+	// not set for conflicts in files that hold no resources.
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+
+	// Path is the path of the conflicting field, such as spec.replicas. It is not set for
+	// conflicts over a whole resource or file.
+	Path string `json:"path,omitempty"`
+
+	// Base, Local and Upstream are the values of the conflicting field, encoded as YAML, in the
+	// old upstream revision, the local revision and the new upstream revision. They are not set
+	// when the field is not set, or for conflicts over a whole resource or file.
+	Base     string `json:"base,omitempty"`
+	Local    string `json:"local,omitempty"`
+	Upstream string `json:"upstream,omitempty"`
+
+	// Description describes the conflict.
+	Description string `json:"description"`
+}
+
+// ResultResourceRef identifies a resource in a package.
+type ResultResourceRef struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+// PackageRevisionRef is a reference to a package revision.
+type PackageRevisionRef struct {
+	// Name is the name of the referenced PackageRevision resource.
+	Name string `json:"name"`
+}
+
+// PackageMergeStrategy is the strategy of the merge of the changes of an upstream package into a
+// local package:
+//   - resource-merge: Perform a structural comparison of the original / updated resources, and
+//     merge the changes into the local package.
+//   - fast-forward: Fail without updating if the local package was modified since it was fetched.
+//   - force-delete-replace: Wipe all the local changes to the package and replace it with the
+//     remote version.
+//   - copy-merge: Copy all the remote changes to the local package.
+//
+// +kubebuilder:validation:Enum=resource-merge;fast-forward;force-delete-replace;copy-merge
+type PackageMergeStrategy string
+
+const (
+	ResourceMerge      PackageMergeStrategy = "resource-merge"
+	FastForward        PackageMergeStrategy = "fast-forward"
+	ForceDeleteReplace PackageMergeStrategy = "force-delete-replace"
+	CopyMerge          PackageMergeStrategy = "copy-merge"
+)
+
+// UpstreamLock identifies the upstream data of a package.
+type UpstreamLock struct {
+	// Type is the type of origin.
+	Type OriginType `json:"type,omitempty"`
+
+	// Git is the resolved locator for a package on Git.
+	Git *GitLock `json:"git,omitempty"`
+}
+
+// GitLock is the resolved locator of a package on Git.
+type GitLock struct {
+	// Repo is the git repository that was fetched.
+	// e.g. 'https://github.com/kubernetes/examples.git'
+	Repo string `json:"repo,omitempty"`
+
+	// Directory is the sub directory of the git repository that was fetched.
+	// e.g. 'staging/cockroachdb'
+	Directory string `json:"directory,omitempty"`
+
+	// Ref can be a Git branch, tag, or a commit SHA-1 that was fetched.
+	// e.g. 'master'
+	Ref string `json:"ref,omitempty"`
+
+	// Commit is the SHA-1 for the last fetch of the package.
+	// This is set by kpt for bookkeeping purposes.
+	Commit string `json:"commit,omitempty"`
+}
+
+// OriginType is the type of the origin of a package.
+// +kubebuilder:validation:Enum=git
+type OriginType string
+
+const (
+	OriginTypeGit OriginType = "git"
+)
+
+func init() {
+	SchemeBuilder.Register(&PackageRevision{}, &PackageRevisionList{})
+}
@@ -0,0 +1,564 @@
+//go:build !ignore_autogenerated
+
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FunctionResult) DeepCopyInto(out *FunctionResult) {
+	*out = *in
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]ResultItem, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FunctionResult.
+func (in *FunctionResult) DeepCopy() *FunctionResult {
+	if in == nil {
+		return nil
+	}
+	out := new(FunctionResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLock) DeepCopyInto(out *GitLock) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLock.
+func (in *GitLock) DeepCopy() *GitLock {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitPackage) DeepCopyInto(out *GitPackage) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitPackage.
+func (in *GitPackage) DeepCopy() *GitPackage {
+	if in == nil {
+		return nil
+	}
+	out := new(GitPackage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectionPoint) DeepCopyInto(out *InjectionPoint) {
+	*out = *in
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(InjectionSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectionPoint.
+func (in *InjectionPoint) DeepCopy() *InjectionPoint {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectionPoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectionSelector) DeepCopyInto(out *InjectionSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectionSelector.
+func (in *InjectionSelector) DeepCopy() *InjectionSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectionSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectionSource) DeepCopyInto(out *InjectionSource) {
+	*out = *in
+	out.InjectionSelector = in.InjectionSelector
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectionSource.
+func (in *InjectionSource) DeepCopy() *InjectionSource {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectionSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MergeConflict) DeepCopyInto(out *MergeConflict) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MergeConflict.
+func (in *MergeConflict) DeepCopy() *MergeConflict {
+	if in == nil {
+		return nil
+	}
+	out := new(MergeConflict)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OciPackage) DeepCopyInto(out *OciPackage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OciPackage.
+func (in *OciPackage) DeepCopy() *OciPackage {
+	if in == nil {
+		return nil
+	}
+	out := new(OciPackage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageCloneTaskSpec) DeepCopyInto(out *PackageCloneTaskSpec) {
+	*out = *in
+	in.Upstream.DeepCopyInto(&out.Upstream)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageCloneTaskSpec.
+func (in *PackageCloneTaskSpec) DeepCopy() *PackageCloneTaskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageCloneTaskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageEditTaskSpec) DeepCopyInto(out *PackageEditTaskSpec) {
+	*out = *in
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(PackageRevisionRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageEditTaskSpec.
+func (in *PackageEditTaskSpec) DeepCopy() *PackageEditTaskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageEditTaskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageInitTaskSpec) DeepCopyInto(out *PackageInitTaskSpec) {
+	*out = *in
+	if in.Keywords != nil {
+		in, out := &in.Keywords, &out.Keywords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageInitTaskSpec.
+func (in *PackageInitTaskSpec) DeepCopy() *PackageInitTaskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageInitTaskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevision) DeepCopyInto(out *PackageRevision) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevision.
+func (in *PackageRevision) DeepCopy() *PackageRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PackageRevision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionList) DeepCopyInto(out *PackageRevisionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PackageRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionList.
+func (in *PackageRevisionList) DeepCopy() *PackageRevisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PackageRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionRef) DeepCopyInto(out *PackageRevisionRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionRef.
+func (in *PackageRevisionRef) DeepCopy() *PackageRevisionRef {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionSpec) DeepCopyInto(out *PackageRevisionSpec) {
+	*out = *in
+	if in.Parent != nil {
+		in, out := &in.Parent, &out.Parent
+		*out = new(ParentReference)
+		**out = **in
+	}
+	if in.Tasks != nil {
+		in, out := &in.Tasks, &out.Tasks
+		*out = make([]Task, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]ReadinessGate, len(*in))
+		copy(*out, *in)
+	}
+	if in.Injectors != nil {
+		in, out := &in.Injectors, &out.Injectors
+		*out = make([]InjectionSelector, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionSpec.
+func (in *PackageRevisionSpec) DeepCopy() *PackageRevisionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRevisionStatus) DeepCopyInto(out *PackageRevisionStatus) {
+	*out = *in
+	if in.UpstreamLock != nil {
+		in, out := &in.UpstreamLock, &out.UpstreamLock
+		*out = new(UpstreamLock)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProposedAt != nil {
+		in, out := &in.ProposedAt, &out.ProposedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.PublishedAt != nil {
+		in, out := &in.PublishedAt, &out.PublishedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.InjectionPoints != nil {
+		in, out := &in.InjectionPoints, &out.InjectionPoints
+		*out = make([]InjectionPoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RenderResults != nil {
+		in, out := &in.RenderResults, &out.RenderResults
+		*out = make([]FunctionResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MergeConflicts != nil {
+		in, out := &in.MergeConflicts, &out.MergeConflicts
+		*out = make([]MergeConflict, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRevisionStatus.
+func (in *PackageRevisionStatus) DeepCopy() *PackageRevisionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRevisionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageUpgradeTaskSpec) DeepCopyInto(out *PackageUpgradeTaskSpec) {
+	*out = *in
+	out.OldUpstream = in.OldUpstream
+	out.NewUpstream = in.NewUpstream
+	out.LocalPackageRevisionRef = in.LocalPackageRevisionRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageUpgradeTaskSpec.
+func (in *PackageUpgradeTaskSpec) DeepCopy() *PackageUpgradeTaskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageUpgradeTaskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParentReference) DeepCopyInto(out *ParentReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParentReference.
+func (in *ParentReference) DeepCopy() *ParentReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ParentReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessGate) DeepCopyInto(out *ReadinessGate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessGate.
+func (in *ReadinessGate) DeepCopy() *ReadinessGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResultItem) DeepCopyInto(out *ResultItem) {
+	*out = *in
+	if in.ResourceRef != nil {
+		in, out := &in.ResourceRef, &out.ResourceRef
+		*out = new(ResultResourceRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResultItem.
+func (in *ResultItem) DeepCopy() *ResultItem {
+	if in == nil {
+		return nil
+	}
+	out := new(ResultItem)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResultResourceRef) DeepCopyInto(out *ResultResourceRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResultResourceRef.
+func (in *ResultResourceRef) DeepCopy() *ResultResourceRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ResultResourceRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretRef) DeepCopyInto(out *SecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRef.
+func (in *SecretRef) DeepCopy() *SecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Task) DeepCopyInto(out *Task) {
+	*out = *in
+	if in.Init != nil {
+		in, out := &in.Init, &out.Init
+		*out = new(PackageInitTaskSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Clone != nil {
+		in, out := &in.Clone, &out.Clone
+		*out = new(PackageCloneTaskSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Edit != nil {
+		in, out := &in.Edit, &out.Edit
+		*out = new(PackageEditTaskSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(PackageUpgradeTaskSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Task.
+func (in *Task) DeepCopy() *Task {
+	if in == nil {
+		return nil
+	}
+	out := new(Task)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamLock) DeepCopyInto(out *UpstreamLock) {
+	*out = *in
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitLock)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamLock.
+func (in *UpstreamLock) DeepCopy() *UpstreamLock {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamLock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamPackage) DeepCopyInto(out *UpstreamPackage) {
+	*out = *in
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitPackage)
+		**out = **in
+	}
+	if in.Oci != nil {
+		in, out := &in.Oci, &out.Oci
+		*out = new(OciPackage)
+		**out = **in
+	}
+	if in.UpstreamRef != nil {
+		in, out := &in.UpstreamRef, &out.UpstreamRef
+		*out = new(PackageRevisionRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamPackage.
+func (in *UpstreamPackage) DeepCopy() *UpstreamPackage {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamPackage)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -17,10 +17,18 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
+	"errors"
 	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -28,17 +36,33 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	cachev1beta1 "github.com/liamfallon/porch-operator/api/v1beta1"
 	"github.com/liamfallon/porch-operator/internal/controller"
+	"github.com/liamfallon/porch-operator/internal/diff"
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/fn/cache"
+	"github.com/liamfallon/porch-operator/internal/fn/starlark"
+	"github.com/liamfallon/porch-operator/internal/fn/wasm"
+	"github.com/liamfallon/porch-operator/internal/forge"
+	"github.com/liamfallon/porch-operator/internal/git"
+	packagecache "github.com/liamfallon/porch-operator/internal/git/cache"
+	"github.com/liamfallon/porch-operator/internal/shard"
+	"github.com/liamfallon/porch-operator/internal/tracing"
+	webhookv1alpha1 "github.com/liamfallon/porch-operator/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -51,6 +75,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(cachev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(cachev1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -63,6 +88,26 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var wasmModuleDir, wasmAllowedDigests string
+	var wasmMemoryLimit uint64
+	var wasmTimeout time.Duration
+	var wasmModuleCacheSize int
+	var starlarkMaxSteps uint64
+	var functionCacheDir string
+	var repositorySyncInterval time.Duration
+	var repositorySyncJitter float64
+	var pushWebhookAddr, pushWebhookSecretDir string
+	var packageCacheDir string
+	var packageCacheSize, packageCacheDiskSize int64
+	var gitStoreDir string
+	var gitFetchDepth int
+	var gitGCInterval time.Duration
+	var functionCacheSize int64
+	var shardCount int
+	var shardLeaseNamespace string
+	var tracingEndpoint string
+	var tracingInsecure bool
+	var tracingSampleRatio float64
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -81,6 +126,58 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&wasmModuleDir, "wasm-module-dir", "",
+		"The directory that contains the WebAssembly modules of KRM functions. "+
+			"The module of image example.com/fn:v1 is example.com/fn/v1.wasm. Leave empty to disable WebAssembly functions.")
+	flag.Uint64Var(&wasmMemoryLimit, "wasm-memory-limit", wasm.DefaultMemoryLimit,
+		"The maximum memory, in bytes, of a WebAssembly function.")
+	flag.DurationVar(&wasmTimeout, "wasm-timeout", wasm.DefaultTimeout, "The maximum time a WebAssembly function may run for.")
+	flag.StringVar(&wasmAllowedDigests, "wasm-allowed-digests", "",
+		"A comma-separated list of the digests (sha256:<hex>) of the WebAssembly modules that may be run. "+
+			"Leave empty to allow any module.")
+	flag.IntVar(&wasmModuleCacheSize, "wasm-module-cache-size", wasm.DefaultCacheSize,
+		"The number of compiled WebAssembly modules to keep.")
+	flag.Uint64Var(&starlarkMaxSteps, "starlark-max-steps", starlark.DefaultMaxSteps,
+		"The number of execution steps after which a Starlark function is stopped.")
+	flag.StringVar(&functionCacheDir, "function-cache-dir", "",
+		"The directory to cache function outputs in, so that they are kept across restarts. "+
+			"Leave empty to cache function outputs in memory.")
+	flag.Int64Var(&functionCacheSize, "function-cache-size", 64<<20,
+		"The maximum size of the cached function outputs, in bytes. Set to 0 to disable caching of function outputs.")
+	flag.DurationVar(&repositorySyncInterval, "repository-sync-interval", 10*time.Minute,
+		"How often repositories that do not specify a sync interval are synced. Set to 0 to only sync them when they change.")
+	flag.Float64Var(&repositorySyncJitter, "repository-sync-jitter", 0.1,
+		"The fraction of the sync interval by which repository syncs are randomly delayed.")
+	flag.StringVar(&pushWebhookAddr, "push-webhook-bind-address", "0",
+		"The address the git forge push webhook receiver binds to. Use the port :9444. Use 0 to disable it.")
+	flag.StringVar(&pushWebhookSecretDir, "push-webhook-secret-dir", "",
+		"The directory holding the secrets shared with the git forges that send push webhooks, "+
+			"in files named github, gitlab and gitea.")
+	flag.Int64Var(&packageCacheSize, "package-cache-size", 256<<20,
+		"The maximum size of the package contents cached in memory, in bytes.")
+	flag.StringVar(&packageCacheDir, "package-cache-dir", "",
+		"The directory to also cache package contents in, so that they are kept across restarts. "+
+			"Leave empty to only cache package contents in memory.")
+	flag.Int64Var(&packageCacheDiskSize, "package-cache-disk-size", 1<<30,
+		"The maximum size of the package contents cached in the package cache directory, in bytes.")
+	flag.StringVar(&gitStoreDir, "git-store-dir", "",
+		"The directory to keep the object stores of git repositories in, one bare repository per remote. "+
+			"Leave empty to keep them in memory.")
+	flag.IntVar(&gitFetchDepth, "git-fetch-depth", 1,
+		"The number of commits fetched from the tips of the refs of git repositories. Set to 0 to fetch their whole history.")
+	flag.DurationVar(&gitGCInterval, "git-gc-interval", time.Hour,
+		"How often the object stores of git repositories are garbage collected. Set to 0 to never collect them.")
+	flag.IntVar(&shardCount, "shards", 0,
+		"The number of shards the repositories are split into, so that the replicas of the operator share them. "+
+			"Every replica must use the same number. Leave as 0 to reconcile everything in one replica.")
+	flag.StringVar(&shardLeaseNamespace, "shard-lease-namespace", "",
+		"The namespace of the Leases of the shards. Defaults to the namespace the operator runs in.")
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", "",
+		"The address of the OTLP gRPC collector traces are exported to. Leave empty to disable tracing.")
+	flag.BoolVar(&tracingInsecure, "tracing-insecure", false,
+		"If set, traces are exported to the collector without TLS.")
+	flag.Float64Var(&tracingSampleRatio, "tracing-sample-ratio", 1.0,
+		"The fraction of the traces that are sampled and exported, between 0 and 1.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -178,6 +275,25 @@ func main() {
 		})
 	}
 
+	if shardCount > 0 && enableLeaderElection {
+		setupLog.Error(errors.New("--leader-elect and --shards are mutually exclusive"), "invalid flags")
+		os.Exit(1)
+	}
+
+	shutdownTracing := func(context.Context) error { return nil }
+	if tracingEndpoint != "" {
+		var err error
+		shutdownTracing, err = tracing.Setup(context.Background(), tracing.Options{
+			Endpoint:    tracingEndpoint,
+			Insecure:    tracingInsecure,
+			SampleRatio: tracingSampleRatio,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing")
+			os.Exit(1)
+		}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
@@ -185,6 +301,11 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "d2d97e31.liamfallon",
+		// The histories of PackageRevisions are only ever appended to, so they are read from the
+		// API server rather than all held in the cache.
+		Client: client.Options{Cache: &client.CacheOptions{
+			DisableFor: []client.Object{&cachev1alpha1.PackageRevisionHistory{}},
+		}},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -202,14 +323,157 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := (&controller.PackageRevisionReconciler{
+	var shards *shard.Shards
+	if shardCount > 0 {
+		shards, err = newShards(mgr, shardCount, shardLeaseNamespace)
+		if err != nil {
+			setupLog.Error(err, "unable to set up shards")
+			os.Exit(1)
+		}
+		if err := mgr.Add(shards); err != nil {
+			setupLog.Error(err, "unable to add shards to manager")
+			os.Exit(1)
+		}
+	}
+
+	functionRunner := fn.Chain{&starlark.Runner{MaxSteps: starlarkMaxSteps}}
+	if wasmModuleDir != "" {
+		options := wasm.Options{
+			MemoryLimit: wasmMemoryLimit,
+			Timeout:     wasmTimeout,
+			CacheSize:   wasmModuleCacheSize,
+		}
+		if wasmAllowedDigests != "" {
+			options.AllowedDigests = strings.Split(wasmAllowedDigests, ",")
+		}
+		wasmRunner, err := wasm.NewRunner(context.Background(), wasm.DirLoader(wasmModuleDir), options)
+		if err != nil {
+			setupLog.Error(err, "unable to create WebAssembly function runner")
+			os.Exit(1)
+		}
+		functionRunner = append(functionRunner, wasmRunner)
+	}
+	// FunctionPolicies are enforced above the cache, by the PackageRevision controller, and the
+	// allowed digests of modules below it: cached outputs are only served once checked by the
+	// runners below the cache.
+	var cachingRunner fn.Runner = functionRunner
+	if functionCacheSize > 0 {
+		var functionCache cache.Cache = cache.NewMemoryCache(functionCacheSize)
+		if functionCacheDir != "" {
+			if functionCache, err = cache.NewDiskCache(functionCacheDir, functionCacheSize); err != nil {
+				setupLog.Error(err, "unable to create function cache")
+				os.Exit(1)
+			}
+		}
+		cachingRunner = &cache.Runner{Runner: functionRunner, Cache: functionCache}
+	}
+
+	packageCache, err := packagecache.New(packageCacheSize, packageCacheDir, packageCacheDiskSize)
+	if err != nil {
+		setupLog.Error(err, "unable to create package cache")
+		os.Exit(1)
+	}
+
+	packageRevisionReconciler := &controller.PackageRevisionReconciler{
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("porch-controller"),
+		FunctionRunner: cachingRunner,
+		Packages:       packageCache,
+		Shards:         shards,
+	}
+	if err := packageRevisionReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PackageRevision")
+		os.Exit(1)
+	}
+	if err := metrics.Registry.Register(&controller.PackageRevisionCollector{Reader: mgr.GetClient(), Shards: shards}); err != nil {
+		setupLog.Error(err, "unable to register PackageRevision metrics")
+		os.Exit(1)
+	}
+	// The preview and diff APIs are served by the metrics server, so that they are protected by
+	// the same authentication and authorization as the metrics endpoint.
+	if err := mgr.AddMetricsServerExtraHandler(controller.PreviewPath,
+		&controller.PreviewHandler{Reconciler: packageRevisionReconciler}); err != nil {
+		setupLog.Error(err, "unable to add preview handler")
+		os.Exit(1)
+	}
+	if err := mgr.AddMetricsServerExtraHandler(diff.Path, &diff.Handler{Reader: mgr.GetClient(), Packages: packageCache}); err != nil {
+		setupLog.Error(err, "unable to add diff handler")
+		os.Exit(1)
+	}
+	if err := (&controller.PackageVariantSetReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
 		Recorder: mgr.GetEventRecorderFor("porch-controller"),
+		Shards:   shards,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PackageRevision")
+		setupLog.Error(err, "unable to create controller", "controller", "PackageVariantSet")
+		os.Exit(1)
+	}
+	repositories := &git.Repositories{Dir: gitStoreDir, Depth: gitFetchDepth, GCInterval: gitGCInterval}
+	if err := mgr.Add(repositories); err != nil {
+		setupLog.Error(err, "unable to add git object store garbage collection")
+		os.Exit(1)
+	}
+	if err := (&controller.RepositoryReconciler{
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		Recorder:     mgr.GetEventRecorderFor("porch-controller"),
+		Repositories: repositories,
+		Packages:     packageCache,
+		SyncInterval: repositorySyncInterval,
+		SyncJitter:   repositorySyncJitter,
+		Shards:       shards,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Repository")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		operator, err := webhookv1alpha1.OperatorUser(context.Background(), mgr.GetClient())
+		if err != nil {
+			setupLog.Error(err, "unable to find the user of the operator")
+			os.Exit(1)
+		}
+		if err := webhookv1alpha1.SetupPackageRevisionWebhookWithManager(mgr, operator); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PackageRevision")
+			os.Exit(1)
+		}
+		if err := webhookv1alpha1.SetupPackageRevisionResourcesWebhookWithManager(mgr, operator); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PackageRevisionResources")
+			os.Exit(1)
+		}
+	}
+	// Forges cannot authenticate to the metrics server, so push webhooks are received on a server
+	// of their own, and authenticated by their signatures.
+	if pushWebhookAddr != "0" {
+		secrets := map[forge.Forge][]byte{}
+		for _, f := range forge.Forges {
+			secret, err := os.ReadFile(filepath.Join(pushWebhookSecretDir, string(f)))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				setupLog.Error(err, "unable to read push webhook secret", "forge", f)
+				os.Exit(1)
+			}
+			secrets[f] = bytes.TrimSpace(secret)
+		}
+		if len(secrets) == 0 {
+			setupLog.Error(errors.New("no push webhook secrets found"), "unable to receive push webhooks",
+				"dir", pushWebhookSecretDir)
+			os.Exit(1)
+		}
+		mux := http.NewServeMux()
+		mux.Handle(forge.Path, &forge.Handler{Client: mgr.GetClient(), Secrets: secrets})
+		if err := mgr.Add(&manager.Server{
+			Name:   "push-webhook",
+			Server: &http.Server{Addr: pushWebhookAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		}); err != nil {
+			setupLog.Error(err, "unable to add push webhook server")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -238,8 +502,41 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	// The spans of the last reconciles are flushed whether the manager failed or not.
+	if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+		setupLog.Error(shutdownErr, "problem flushing traces")
+	}
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// newShards returns the shards of this replica of the operator, whose Leases are in the namespace,
+// or the namespace the operator runs in if it is empty.
+func newShards(mgr manager.Manager, count int, namespace string) (*shard.Shards, error) {
+	if namespace == "" {
+		data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("unable to find the namespace the operator runs in, set --shard-lease-namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	// The Leases are read directly, rather than through the cache of the manager, which would
+	// watch the Leases of the whole cluster.
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return nil, err
+	}
+	return &shard.Shards{
+		Client:    c,
+		Namespace: namespace,
+		Identity:  hostname + "_" + string(uuid.NewUUID()),
+		Count:     count,
+	}, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/liamfallon/porch-operator/internal/revisions"
+)
+
+func newPullCommand(o *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull REVISION DIR",
+		Short: "Write the contents of a package revision into a local directory",
+		Long: "Write the contents of a package revision into a local directory, which must be empty or not exist,\n" +
+			"so that they can be edited and pushed back with porchctl push.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, namespace, err := o.client()
+			if err != nil {
+				return err
+			}
+			if err := revisions.Pull(cmd.Context(), c, namespace, args[0], args[1]); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s pulled into %s\n", args[0], args[1])
+			return err
+		},
+	}
+}
+
+func newPushCommand(o *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "push DIR",
+		Short: "Replace the contents of a draft with a local directory",
+		Long: "Replace the contents of the draft that a local directory was pulled from with the files of the\n" +
+			"directory. The push is refused if the contents of the draft have changed since they were pulled.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, _, err := o.client()
+			if err != nil {
+				return err
+			}
+			prr, err := revisions.Push(cmd.Context(), c, args[0])
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s pushed\n", prr.Name)
+			return err
+		},
+	}
+}
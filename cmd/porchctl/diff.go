@@ -0,0 +1,138 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/diff"
+)
+
+// changeMarks are the marks that precede the changes in text output.
+var changeMarks = map[cachev1alpha1.ChangeType]string{
+	cachev1alpha1.ChangeAdded:    "+",
+	cachev1alpha1.ChangeModified: "~",
+	cachev1alpha1.ChangeRemoved:  "-",
+}
+
+func newDiffCommand(o *options) *cobra.Command {
+	var upstream bool
+	var output string
+	cmd := &cobra.Command{
+		Use:   "diff FROM TO",
+		Short: "Show the differences between two revisions of a package",
+		Long: "Show the resources and fields that differ between two package revisions, or, with --upstream,\n" +
+			"between a package revision and the upstream revision it was cloned from or last upgraded to:\n\n" +
+			"  porchctl diff --upstream REVISION",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if upstream {
+				return cobra.ExactArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, namespace, err := o.client()
+			if err != nil {
+				return err
+			}
+			request := &cachev1alpha1.DiffRequest{Namespace: namespace, To: args[len(args)-1]}
+			if !upstream {
+				request.From = args[0]
+			}
+			response, err := diff.Revisions(cmd.Context(), c, nil, request)
+			if err != nil {
+				return err
+			}
+			return printDiff(cmd.OutOrStdout(), response, output)
+		},
+	}
+	cmd.Flags().BoolVar(&upstream, "upstream", false, "Compare the package revision to its upstream revision.")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "The output format, one of yaml or json. Text by default.")
+	return cmd
+}
+
+// printDiff writes a diff in the output format.
+func printDiff(w io.Writer, response *cachev1alpha1.DiffResponse, output string) error {
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(response)
+	case "yaml":
+		data, err := yaml.Marshal(response)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case "":
+	default:
+		return fmt.Errorf("unknown output format %q", output)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Comparing %s to %s\n", response.From, response.To)
+	if len(response.Resources) == 0 && len(response.Files) == 0 {
+		b.WriteString("No differences\n")
+	}
+	for _, resource := range response.Resources {
+		name := resource.Name
+		if resource.Namespace != "" {
+			name = resource.Namespace + "/" + name
+		}
+		fmt.Fprintf(&b, "\n%s %s %s (%s)\n", resource.Change, resource.Kind, name, resource.File)
+		for _, field := range resource.Fields {
+			fmt.Fprintf(&b, "  %s %s:", changeMarks[field.Change], field.Path)
+			switch {
+			case field.Change == cachev1alpha1.ChangeAdded:
+				writeValue(&b, field.To)
+			case field.Change == cachev1alpha1.ChangeRemoved:
+				writeValue(&b, field.From)
+			case !strings.Contains(field.From+field.To, "\n"):
+				fmt.Fprintf(&b, " %s -> %s\n", field.From, field.To)
+			default:
+				writeValue(&b, field.From)
+				b.WriteString("    ->")
+				writeValue(&b, field.To)
+			}
+		}
+	}
+	for _, file := range response.Files {
+		fmt.Fprintf(&b, "\n%s %s\n%s", file.Change, file.Path, file.Diff)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeValue writes a field value, on the same line if it fits on one.
+func writeValue(b *strings.Builder, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(b, " %s\n", value)
+		return
+	}
+	b.WriteString("\n")
+	for _, line := range strings.Split(value, "\n") {
+		fmt.Fprintf(b, "      %s\n", line)
+	}
+}
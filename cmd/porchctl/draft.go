@@ -0,0 +1,164 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/revisions"
+)
+
+// draftOptions are the options of the commands that create drafts.
+type draftOptions struct {
+	*options
+	workspace string
+	output    string
+}
+
+// addFlags adds the flags common to the commands that create drafts.
+func (d *draftOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&d.workspace, "workspace", "", "The workspace of the draft.")
+	_ = cmd.MarkFlagRequired("workspace")
+	addOutputFlag(cmd, &d.output)
+}
+
+// create creates the draft that the build function returns, given a client and the namespace,
+// and reports it.
+func (d *draftOptions) create(cmd *cobra.Command,
+	build func(ctx context.Context, c client.Client, namespace string) (*cachev1alpha1.PackageRevision, error)) error {
+	c, namespace, err := d.client()
+	if err != nil {
+		return err
+	}
+	draft, err := build(cmd.Context(), c, namespace)
+	if err != nil {
+		return err
+	}
+	if err := c.Create(cmd.Context(), draft); err != nil {
+		return err
+	}
+	if d.output != "" {
+		return printRevisions(cmd.OutOrStdout(), []cachev1alpha1.PackageRevision{*draft}, d.output)
+	}
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s created\n", draft.Name)
+	return err
+}
+
+func newInitCommand(o *options) *cobra.Command {
+	d := &draftOptions{options: o}
+	var repository string
+	var spec cachev1alpha1.PackageInitTaskSpec
+	cmd := &cobra.Command{
+		Use:   "init PACKAGE",
+		Short: "Create a draft of a new package",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.create(cmd, func(_ context.Context, _ client.Client, namespace string) (*cachev1alpha1.PackageRevision, error) {
+				return revisions.NewDraft(namespace, repository, args[0], d.workspace, revisions.InitTask(spec)), nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&repository, "repository", "", "The repository of the package.")
+	_ = cmd.MarkFlagRequired("repository")
+	cmd.Flags().StringVar(&spec.Description, "description", "", "A short description of the package.")
+	cmd.Flags().StringSliceVar(&spec.Keywords, "keywords", nil, "Keywords describing the package.")
+	cmd.Flags().StringVar(&spec.Site, "site", "", "A link to a page with information about the package.")
+	d.addFlags(cmd)
+	return cmd
+}
+
+func newCloneCommand(o *options) *cobra.Command {
+	d := &draftOptions{options: o}
+	var repository, strategy string
+	cmd := &cobra.Command{
+		Use:   "clone UPSTREAM PACKAGE",
+		Short: "Create a draft of a new package cloned from a published package revision",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.create(cmd, func(ctx context.Context, c client.Client, namespace string) (*cachev1alpha1.PackageRevision, error) {
+				if _, err := revisions.Get(ctx, c, namespace, args[0]); err != nil {
+					return nil, err
+				}
+				task := revisions.CloneTask(args[0], cachev1alpha1.PackageMergeStrategy(strategy))
+				return revisions.NewDraft(namespace, repository, args[1], d.workspace, task), nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&repository, "repository", "", "The repository of the new package.")
+	_ = cmd.MarkFlagRequired("repository")
+	cmd.Flags().StringVar(&strategy, "strategy", "", "The strategy of later upgrades of the package.")
+	d.addFlags(cmd)
+	return cmd
+}
+
+func newCopyCommand(o *options) *cobra.Command {
+	d := &draftOptions{options: o}
+	cmd := &cobra.Command{
+		Use:     "copy SOURCE",
+		Aliases: []string{"edit"},
+		Short:   "Create a draft of a package from one of its revisions",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.create(cmd, func(ctx context.Context, c client.Client, namespace string) (*cachev1alpha1.PackageRevision, error) {
+				source, err := revisions.Get(ctx, c, namespace, args[0])
+				if err != nil {
+					return nil, err
+				}
+				return revisions.NewDraft(namespace, source.Spec.RepositoryName, source.Spec.PackageName, d.workspace,
+					revisions.EditTask(source.Name)), nil
+			})
+		},
+	}
+	d.addFlags(cmd)
+	return cmd
+}
+
+func newUpgradeCommand(o *options) *cobra.Command {
+	d := &draftOptions{options: o}
+	var revision int
+	var strategy string
+	cmd := &cobra.Command{
+		Use:   "upgrade REVISION",
+		Short: "Create a draft of a package upgraded to a newer revision of its upstream",
+		Long: "Create a draft of the package of a package revision that merges the changes between the upstream\n" +
+			"of the package revision and a newer revision of the upstream package into it: the latest\n" +
+			"revision, or the one given with --revision.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.create(cmd, func(ctx context.Context, c client.Client, namespace string) (*cachev1alpha1.PackageRevision, error) {
+				local, err := revisions.Get(ctx, c, namespace, args[0])
+				if err != nil {
+					return nil, err
+				}
+				task, err := revisions.UpgradeTask(ctx, c, local, revision, cachev1alpha1.PackageMergeStrategy(strategy))
+				if err != nil {
+					return nil, err
+				}
+				return revisions.NewDraft(namespace, local.Spec.RepositoryName, local.Spec.PackageName, d.workspace, task), nil
+			})
+		},
+	}
+	cmd.Flags().IntVar(&revision, "revision", 0, "The revision of the upstream package to upgrade to. The latest by default.")
+	cmd.Flags().StringVar(&strategy, "strategy", "", "The strategy of the upgrade, resource-merge by default.")
+	d.addFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/revisions"
+)
+
+func newGetCommand(o *options) *cobra.Command {
+	var repository, packageName, output string
+	cmd := &cobra.Command{
+		Use:   "get [REVISION...]",
+		Short: "List package revisions",
+		Long: "List the package revisions, or the named package revisions, optionally only those of a\n" +
+			"repository or a package.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, namespace, err := o.client()
+			if err != nil {
+				return err
+			}
+			var prs []cachev1alpha1.PackageRevision
+			if len(args) > 0 {
+				for _, name := range args {
+					pr, err := revisions.Get(cmd.Context(), c, namespace, name)
+					if err != nil {
+						return err
+					}
+					prs = append(prs, *pr)
+				}
+			} else {
+				list := &cachev1alpha1.PackageRevisionList{}
+				if err := c.List(cmd.Context(), list, client.InNamespace(namespace)); err != nil {
+					return err
+				}
+				prs = list.Items
+			}
+			prs = slices.DeleteFunc(prs, func(pr cachev1alpha1.PackageRevision) bool {
+				return (repository != "" && pr.Spec.RepositoryName != repository) ||
+					(packageName != "" && pr.Spec.PackageName != packageName)
+			})
+			return printRevisions(cmd.OutOrStdout(), prs, output)
+		},
+	}
+	cmd.Flags().StringVar(&repository, "repository", "", "Only list the package revisions of the repository.")
+	cmd.Flags().StringVar(&packageName, "package", "", "Only list the revisions of the package.")
+	addOutputFlag(cmd, &output)
+	return cmd
+}
+
+// addOutputFlag adds the flag that selects the output format of the package revisions.
+func addOutputFlag(cmd *cobra.Command, output *string) {
+	cmd.Flags().StringVarP(output, "output", "o", "", "The output format, one of yaml or json. A table by default.")
+}
+
+// printRevisions writes package revisions in the output format. A single package revision is
+// written as an object rather than as a list.
+func printRevisions(w io.Writer, prs []cachev1alpha1.PackageRevision, output string) error {
+	for i := range prs {
+		prs[i].TypeMeta = metav1.TypeMeta{APIVersion: cachev1alpha1.GroupVersion.String(), Kind: "PackageRevision"}
+		prs[i].ManagedFields = nil
+	}
+	var obj any = &cachev1alpha1.PackageRevisionList{
+		TypeMeta: metav1.TypeMeta{APIVersion: cachev1alpha1.GroupVersion.String(), Kind: "PackageRevisionList"},
+		Items:    prs,
+	}
+	if len(prs) == 1 {
+		obj = &prs[0]
+	}
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(obj)
+	case "yaml":
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case "":
+	default:
+		return fmt.Errorf("unknown output format %q", output)
+	}
+
+	slices.SortFunc(prs, func(a, b cachev1alpha1.PackageRevision) int { return strings.Compare(a.Name, b.Name) })
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tPACKAGE\tWORKSPACENAME\tREVISION\tLIFECYCLE\tREPOSITORY")
+	for _, pr := range prs {
+		revision := "-"
+		if pr.Spec.Lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished ||
+			pr.Spec.Lifecycle == cachev1alpha1.PackageRevisionLifecycleDeletionProposed {
+			revision = fmt.Sprint(pr.Spec.Revision)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", pr.Name, pr.Spec.PackageName, pr.Spec.WorkspaceName,
+			revision, pr.Spec.Lifecycle, pr.Spec.RepositoryName)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/revisions"
+)
+
+// transition changes the lifecycle of a package revision.
+type transition func(ctx context.Context, c client.Client, namespace, name string) (*cachev1alpha1.PackageRevision, error)
+
+// newLifecycleCommand returns a command that changes the lifecycle of the package revisions it is
+// given, and reports each of them with the verb. The package revisions are all attempted, even
+// when some fail.
+func newLifecycleCommand(o *options, use, short, verb string, change transition) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " REVISION...",
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, namespace, err := o.client()
+			if err != nil {
+				return err
+			}
+			var errs []error
+			for _, name := range args {
+				if _, err := change(cmd.Context(), c, namespace, name); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", name, verb)
+			}
+			return errors.Join(errs...)
+		},
+	}
+}
+
+func newLifecycleCommands(o *options) []*cobra.Command {
+	del := func(ctx context.Context, c client.Client, namespace, name string) (*cachev1alpha1.PackageRevision, error) {
+		return nil, revisions.Delete(ctx, c, namespace, name)
+	}
+	return []*cobra.Command{
+		newLifecycleCommand(o, "propose", "Propose drafts for approval", "proposed", revisions.Propose),
+		newLifecycleCommand(o, "approve", "Approve proposed package revisions, publishing them", "approved", revisions.Approve),
+		newLifecycleCommand(o, "reject", "Reject proposed package revisions, or proposed deletions", "rejected", revisions.Reject),
+		newLifecycleCommand(o, "propose-delete", "Propose the deletion of published package revisions", "proposed for deletion",
+			revisions.ProposeDelete),
+		newLifecycleCommand(o, "del", "Delete package revisions that are not published", "deleted", del),
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command porchctl works with the packages managed by the porch operator.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+// options are the options common to all commands.
+type options struct {
+	loadingRules *clientcmd.ClientConfigLoadingRules
+	overrides    clientcmd.ConfigOverrides
+}
+
+// client returns a client for the cluster, and the namespace to work in.
+func (o *options) client() (client.Client, string, error) {
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(o.loadingRules, &o.overrides)
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	namespace, _, err := config.Namespace()
+	if err != nil {
+		return nil, "", err
+	}
+
+	scheme := runtime.NewScheme()
+	if err := cachev1alpha1.AddToScheme(scheme); err != nil {
+		return nil, "", err
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, "", err
+	}
+	return c, namespace, nil
+}
+
+func newRootCommand() *cobra.Command {
+	o := &options{loadingRules: clientcmd.NewDefaultClientConfigLoadingRules()}
+	root := &cobra.Command{
+		Use:           "porchctl",
+		Short:         "Work with the packages managed by the porch operator",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&o.loadingRules.ExplicitPath, "kubeconfig", "", "Path to the kubeconfig file to use.")
+	flags.StringVar(&o.overrides.CurrentContext, "context", "", "The kubeconfig context to use.")
+	flags.StringVarP(&o.overrides.Context.Namespace, "namespace", "n", "", "The namespace of the package revisions.")
+
+	root.AddCommand(newGetCommand(o), newInitCommand(o), newCloneCommand(o), newCopyCommand(o), newUpgradeCommand(o))
+	root.AddCommand(newLifecycleCommands(o)...)
+	root.AddCommand(newPullCommand(o), newPushCommand(o))
+	root.AddCommand(newDiffCommand(o))
+	return root
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/history"
+)
+
+// The reasons of the events recorded about PackageRevisions and Repositories. Tools select
+// events by their reasons, so the reasons are stable: they are never changed, only added to.
+const (
+	// ReasonTaskStarted is recorded, as a Normal event, when a task of a draft starts being
+	// applied for the first time.
+	ReasonTaskStarted = "TaskStarted"
+	// ReasonTaskFailed is recorded, as a Warning event, when the tasks of a draft fail to apply.
+	ReasonTaskFailed = "TaskFailed"
+	// ReasonProposed is recorded, as a Normal event, when a PackageRevision is proposed.
+	ReasonProposed = "Proposed"
+	// ReasonPublished is recorded, as a Normal event, when a PackageRevision is published.
+	ReasonPublished = "Published"
+	// ReasonRejected is recorded, as a Normal event, when a proposed PackageRevision goes back to
+	// Draft.
+	ReasonRejected = "Rejected"
+	// ReasonDeletionProposed is recorded, as a Normal event, when the deletion of a published
+	// PackageRevision is proposed.
+	ReasonDeletionProposed = "DeletionProposed"
+	// ReasonUpstreamUpdated is recorded, as a Normal event, on the PackageRevisions cloned from or
+	// upgraded to a revision of a package, when a newer revision of the package is published.
+	ReasonUpstreamUpdated = "UpstreamUpdated"
+	// ReasonMergeConflict is recorded, as a Warning event, when a draft is left with merge
+	// conflicts.
+	ReasonMergeConflict = "MergeConflict"
+	// ReasonRenderFailed is recorded, as a Warning event, when a draft fails to render.
+	ReasonRenderFailed = "RenderFailed"
+	// ReasonFunctionNotAllowed is recorded, as a Warning event, when a draft fails to render
+	// because it runs a function that no FunctionPolicy allows.
+	ReasonFunctionNotAllowed = "FunctionNotAllowed"
+	// ReasonSyncFailed is recorded, as a Warning event, when a Repository fails to sync.
+	ReasonSyncFailed = "SyncFailed"
+	// ReasonDeleting is recorded, as a Warning event, when a PackageRevision is being deleted.
+	ReasonDeleting = "Deleting"
+	// ReasonTampered is recorded, as a Warning event, when the tag of a published PackageRevision
+	// is found to have been moved to another commit out of band.
+	ReasonTampered = "Tampered"
+	// ReasonRenamed is recorded, as a Warning event, when the ref of a discovered PackageRevision
+	// is found to name another package or workspace.
+	ReasonRenamed = "Renamed"
+	// ReasonRefDeleted is recorded, as a Warning event, when the ref of a discovered
+	// PackageRevision is found to have disappeared.
+	ReasonRefDeleted = "RefDeleted"
+	// ReasonHistoryFull is recorded, as a Warning event, when the history of a PackageRevision is
+	// found too full to record what is done to it.
+	ReasonHistoryFull = "HistoryFull"
+)
+
+// eventf records an event about the object, unless there is no recorder.
+func eventf(recorder record.EventRecorder, obj runtime.Object, eventType, reason, messageFmt string, args ...any) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
+
+// recordTransition records an event about the object, with the message of the condition, if the
+// condition is new among its conditions or changes their reason or message. An object that stays
+// in the same state, such as a draft whose tasks keep failing, is thus reported when it gets into
+// that state rather than on every reconcile. The event recorder further limits the events of
+// objects that keep changing state.
+func recordTransition(recorder record.EventRecorder, obj runtime.Object, conditions []metav1.Condition,
+	condition metav1.Condition, eventType, reason string) {
+	previous := meta.FindStatusCondition(conditions, condition.Type)
+	if previous != nil && previous.Status == condition.Status && previous.Reason == condition.Reason &&
+		previous.Message == condition.Message {
+		return
+	}
+	eventf(recorder, obj, eventType, reason, "%s", condition.Message)
+}
+
+// recordLifecycle records the change of the lifecycle of a PackageRevision since it was last
+// reconciled, in its history and with an event, and records the lifecycle it was reconciled with
+// in its status. The first lifecycle a PackageRevision is reconciled with is not a change, so that
+// revisions discovered in repositories are not reported as just published.
+func (r *PackageRevisionReconciler) recordLifecycle(ctx context.Context, pr *cachev1alpha1.PackageRevision) error {
+	observed, lifecycle := pr.Status.ObservedLifecycle, pr.Spec.Lifecycle
+	if observed == "" || observed == lifecycle {
+		pr.Status.ObservedLifecycle = lifecycle
+		return nil
+	}
+	var action cachev1alpha1.PackageRevisionAction
+	var reason, message string
+	switch {
+	case lifecycle == cachev1alpha1.PackageRevisionLifecycleProposed:
+		action, reason, message = cachev1alpha1.PackageRevisionActionProposed, ReasonProposed, "Proposed for approval"
+	case lifecycle == cachev1alpha1.PackageRevisionLifecycleDraft && observed == cachev1alpha1.PackageRevisionLifecycleProposed:
+		action, reason, message = cachev1alpha1.PackageRevisionActionRejected, ReasonRejected, "Proposal rejected, back to Draft"
+	case lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished && observed == cachev1alpha1.PackageRevisionLifecycleDeletionProposed:
+		action, reason, message = cachev1alpha1.PackageRevisionActionRejected, ReasonRejected, "Deletion rejected, back to Published"
+	case lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished:
+		if err := r.recordUpstreamUpdated(ctx, pr); err != nil {
+			return err
+		}
+		action, reason = cachev1alpha1.PackageRevisionActionApproved, ReasonPublished
+		message = fmt.Sprintf("Published as revision %d", pr.Spec.Revision)
+		pr.Status.PublishedBy = history.Actor(pr)
+	case lifecycle == cachev1alpha1.PackageRevisionLifecycleDeletionProposed:
+		action, reason, message = cachev1alpha1.PackageRevisionActionDeletionProposed, ReasonDeletionProposed, "Deletion proposed for approval"
+	}
+	if action != "" {
+		entry := history.NewEntry(pr, action, history.Actor(pr), observed, metav1.Now())
+		if err := r.appendHistory(pr, history.Append(ctx, r.Client, pr, entry)); err != nil {
+			return err
+		}
+		eventf(r.Recorder, pr, corev1.EventTypeNormal, reason, "%s", message)
+	}
+	pr.Status.ObservedLifecycle = lifecycle
+	return nil
+}
+
+// recordUpstreamUpdated records an event on the PackageRevisions whose upstream is an older
+// revision of the package of a newly published PackageRevision.
+func (r *PackageRevisionReconciler) recordUpstreamUpdated(ctx context.Context, published *cachev1alpha1.PackageRevision) error {
+	prs := &cachev1alpha1.PackageRevisionList{}
+	if err := r.List(ctx, prs, client.InNamespace(published.Namespace)); err != nil {
+		return err
+	}
+	olderRevisions := map[string]bool{}
+	for _, pr := range prs.Items {
+		if pr.Spec.RepositoryName == published.Spec.RepositoryName && pr.Spec.PackageName == published.Spec.PackageName &&
+			pr.Spec.Lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished && pr.Spec.Revision < published.Spec.Revision {
+			olderRevisions[pr.Name] = true
+		}
+	}
+	for i := range prs.Items {
+		downstream := &prs.Items[i]
+		if upstream := upstreamName(downstream); olderRevisions[upstream] {
+			eventf(r.Recorder, downstream, corev1.EventTypeNormal, ReasonUpstreamUpdated,
+				"Upstream %s has a newer revision %s", upstream, published.Name)
+		}
+	}
+	return nil
+}
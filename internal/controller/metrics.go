@@ -0,0 +1,168 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/shard"
+)
+
+var (
+	taskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "porch_task_duration_seconds",
+		Help:    "Duration of the tasks applied to drafts, by task type.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 9),
+	}, []string{"type"})
+	taskFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "porch_task_failures_total",
+		Help: "Number of tasks that failed to apply to drafts, by task type.",
+	}, []string{"type"})
+	renderDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "porch_render_duration_seconds",
+		Help:    "Duration of the rendering of drafts by their Kptfile pipelines.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 9),
+	})
+	renderFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "porch_render_failures_total",
+		Help: "Number of renderings of drafts that failed.",
+	})
+	approvalDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "porch_package_revision_approval_duration_seconds",
+		Help:    "Time from PackageRevisions being Proposed to being Published.",
+		Buckets: prometheus.ExponentialBuckets(60, 4, 8),
+	})
+
+	packageRevisionsDesc = prometheus.NewDesc("porch_package_revisions",
+		"Number of PackageRevisions, by repository and lifecycle.", []string{"repository", "lifecycle"}, nil)
+	behindUpstreamDesc = prometheus.NewDesc("porch_package_revisions_behind_upstream",
+		"Number of PackageRevisions whose upstream has a newer Published revision, by repository.", []string{"repository"}, nil)
+)
+
+func init() {
+	metrics.Registry.MustRegister(taskDuration, taskFailures, renderDuration, renderFailures, approvalDuration)
+}
+
+// collectTimeout bounds the time the PackageRevisions are listed for on a scrape.
+const collectTimeout = 5 * time.Second
+
+// PackageRevisionCollector collects the metrics that count PackageRevisions, from the
+// PackageRevisions at the time of the scrape. When the operator is sharded, every replica only
+// counts the PackageRevisions of its own shards, so that the counts of the replicas add up.
+type PackageRevisionCollector struct {
+	Reader client.Reader
+	Shards *shard.Shards
+}
+
+// Describe sends the descriptions of the metrics.
+func (c *PackageRevisionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- packageRevisionsDesc
+	ch <- behindUpstreamDesc
+}
+
+// Collect counts the PackageRevisions by repository and lifecycle, and those whose upstream has
+// a newer Published revision than the revision they were cloned from or last upgraded to.
+func (c *PackageRevisionCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	prs := &cachev1alpha1.PackageRevisionList{}
+	if err := c.Reader.List(ctx, prs); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list PackageRevisions for metrics")
+		return
+	}
+
+	type lifecycleKey struct{ repository, lifecycle string }
+	type packageKey struct{ namespace, repository, pkg string }
+	byName := map[types.NamespacedName]*cachev1alpha1.PackageRevision{}
+	latest := map[packageKey]int{}
+	for i := range prs.Items {
+		pr := &prs.Items[i]
+		byName[types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}] = pr
+		if pr.Spec.Lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished {
+			key := packageKey{pr.Namespace, pr.Spec.RepositoryName, pr.Spec.PackageName}
+			latest[key] = max(latest[key], pr.Spec.Revision)
+		}
+	}
+
+	counts := map[lifecycleKey]int{}
+	behind := map[string]int{}
+	for i := range prs.Items {
+		pr := &prs.Items[i]
+		if !c.Shards.Owns(c.Shards.For(pr.Namespace, pr.Spec.RepositoryName)) {
+			continue
+		}
+		counts[lifecycleKey{pr.Spec.RepositoryName, string(pr.Spec.Lifecycle)}]++
+		name := upstreamName(pr)
+		if name == "" {
+			continue
+		}
+		upstream := byName[types.NamespacedName{Namespace: pr.Namespace, Name: name}]
+		if upstream != nil &&
+			latest[packageKey{upstream.Namespace, upstream.Spec.RepositoryName, upstream.Spec.PackageName}] > upstream.Spec.Revision {
+			behind[pr.Spec.RepositoryName]++
+		}
+	}
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(packageRevisionsDesc, prometheus.GaugeValue, float64(count), key.repository, key.lifecycle)
+	}
+	for repository, count := range behind {
+		ch <- prometheus.MustNewConstMetric(behindUpstreamDesc, prometheus.GaugeValue, float64(count), repository)
+	}
+}
+
+// upstreamName returns the name of the upstream PackageRevision a PackageRevision was cloned from
+// or last upgraded to, or an empty string if it has none.
+func upstreamName(pr *cachev1alpha1.PackageRevision) string {
+	name := ""
+	for _, task := range pr.Spec.Tasks {
+		switch {
+		case task.Type == cachev1alpha1.TaskTypeClone && task.Clone != nil && task.Clone.Upstream.UpstreamRef != nil:
+			name = task.Clone.Upstream.UpstreamRef.Name
+		case task.Type == cachev1alpha1.TaskTypeUpgrade && task.Upgrade != nil:
+			name = task.Upgrade.NewUpstream.Name
+		}
+	}
+	return name
+}
+
+// recordApproval records when a PackageRevision is Proposed and Published in its status, and
+// observes the time it took to be approved once it is Published. The time is measured from the
+// last time it was proposed.
+func recordApproval(pr *cachev1alpha1.PackageRevision, now time.Time) {
+	switch pr.Spec.Lifecycle {
+	case cachev1alpha1.PackageRevisionLifecycleDraft:
+		pr.Status.ProposedAt = nil
+	case cachev1alpha1.PackageRevisionLifecycleProposed:
+		if pr.Status.ProposedAt == nil {
+			pr.Status.ProposedAt = &metav1.Time{Time: now}
+		}
+	case cachev1alpha1.PackageRevisionLifecyclePublished:
+		if pr.Status.ProposedAt != nil && pr.Status.PublishedAt.IsZero() {
+			pr.Status.PublishedAt = metav1.Time{Time: now}
+			approvalDuration.Observe(now.Sub(pr.Status.ProposedAt.Time).Seconds())
+		}
+	}
+}
@@ -19,23 +19,33 @@ package controller
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"strings"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/fn"
+	packagecache "github.com/liamfallon/porch-operator/internal/git/cache"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+	"github.com/liamfallon/porch-operator/internal/shard"
+	"github.com/liamfallon/porch-operator/internal/tracing"
 )
 
 const PackageRevisionFinalizer = "cache.example.com/finalizer"
@@ -46,6 +56,27 @@ const (
 	typeAvailablePackageRevision = "Available"
 	// typeDegradedPackageRevision represents the status used when the custom resource is deleted and the finalizer operations are yet to occur.
 	typeDegradedPackageRevision = "Degraded"
+	// typeTasksAppliedPackageRevision represents whether the tasks of a draft have produced its contents
+	typeTasksAppliedPackageRevision = "TasksApplied"
+	// typeConfigInjectedPackageRevision represents whether the injection points of a draft have been injected
+	typeConfigInjectedPackageRevision = "ConfigInjected"
+	// typeRenderedPackageRevision represents whether the contents of a draft are rendered by its Kptfile pipelines
+	typeRenderedPackageRevision = "Rendered"
+	// typeMergeConflictPackageRevision represents whether the contents of a draft hold unresolved merge conflicts
+	typeMergeConflictPackageRevision = "MergeConflict"
+	// typeTamperedPackageRevision represents whether the tag of a published revision has been moved out of band
+	typeTamperedPackageRevision = "Tampered"
+	// typeHistoryFullPackageRevision represents whether the history of a PackageRevision is too full to record more
+	typeHistoryFullPackageRevision = "HistoryFull"
+)
+
+const (
+	// taskRetryPeriod is how long to wait before applying the tasks of a draft again after they failed.
+	taskRetryPeriod = 30 * time.Second
+
+	// injectionResyncPeriod is how often a draft with injection points is injected again, so that
+	// changes of the ConfigMaps it is injected from are picked up even if their events are missed.
+	injectionResyncPeriod = 5 * time.Minute
 )
 
 // PackageRevisionReconciler reconciles a PackageRevision object
@@ -53,15 +84,30 @@ type PackageRevisionReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+
+	// FunctionRunner runs the functions of the Kptfile pipelines when drafts are rendered.
+	FunctionRunner fn.Runner
+
+	// Packages caches the contents of the packages read from git repositories. The contents of
+	// discovered Published revisions are restored from it, rather than read from git again.
+	Packages *packagecache.Cache
+
+	// Shards are the shards of the Repositories whose PackageRevisions this replica reconciles.
+	// If nil, every PackageRevision is reconciled.
+	Shards *shard.Shards
 }
 
 // The following markers are used to generate the rules permissions (RBAC) on config/rbac using controller-gen
 // when the command <make manifests> is executed.
 // To know more about markers see: https://book.kubebuilder.io/reference/markers.html
 
-// +kubebuilder:rbac:groups=cache.example.com,resources=PackageRevisions,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=cache.example.com,resources=PackageRevisions/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=cache.example.com,resources=PackageRevisions/finalizers,verbs=update
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagerevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagerevisions/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagerevisions/finalizers,verbs=update
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagerevisionresources,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=functionpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagerevisionhistories,verbs=get;create;update
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
@@ -77,14 +123,16 @@ type PackageRevisionReconciler struct {
 // - About Operator Pattern: https://kubernetes.io/docs/concepts/extend-kubernetes/operator/
 // - About Controllers: https://kubernetes.io/docs/concepts/architecture/controller/
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.21.0/pkg/reconcile
-func (r *PackageRevisionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *PackageRevisionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, span := tracing.Start(ctx, "Reconcile PackageRevision")
+	defer func() { tracing.End(span, err) }()
 	log := logf.FromContext(ctx)
 
 	// Fetch the PackageRevision instance
 	// The purpose is check if the Custom Resource for the Kind PackageRevision
 	// is applied on the cluster if not we return nil to stop the reconciliation
 	PackageRevision := &cachev1alpha1.PackageRevision{}
-	err := r.Get(ctx, req.NamespacedName, PackageRevision)
+	err = r.Get(ctx, req.NamespacedName, PackageRevision)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			// If the custom resource is not found then it usually means that it was deleted or not created
@@ -97,6 +145,16 @@ func (r *PackageRevisionReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, err
 	}
 
+	tracing.SetPackageRevision(span, PackageRevision)
+
+	// PackageRevisions are reconciled by the replica that owns the shard of their Repository,
+	// so that it never races with the syncs of the Repository.
+	unlock, owned := r.Shards.Lock(r.shardOf(PackageRevision))
+	if !owned {
+		return ctrl.Result{}, nil
+	}
+	defer unlock()
+
 	// Let's just set the status as Unknown when no status is available
 	if len(PackageRevision.Status.Conditions) == 0 {
 		meta.SetStatusCondition(&PackageRevision.Status.Conditions, metav1.Condition{Type: typeAvailablePackageRevision, Status: metav1.ConditionUnknown, Reason: "Reconciling", Message: "Starting reconciliation"})
@@ -120,6 +178,13 @@ func (r *PackageRevisionReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	// occur before the custom resource is deleted.
 	// More info: https://kubernetes.io/docs/concepts/overview/working-with-objects/finalizers
 	if !controllerutil.ContainsFinalizer(PackageRevision, PackageRevisionFinalizer) {
+		// The finalizer is added when the PackageRevision is first reconciled, which is when its
+		// creation is recorded in its history, unless a previous attempt recorded it already.
+		if err = r.recordCreated(ctx, PackageRevision); err != nil {
+			log.Error(err, "Failed to record the creation of PackageRevision")
+			return ctrl.Result{}, err
+		}
+
 		log.Info("Adding Finalizer for PackageRevision")
 		if ok := controllerutil.AddFinalizer(PackageRevision, PackageRevisionFinalizer); !ok {
 			err = fmt.Errorf("finalizer for PackageRevision was not added")
@@ -152,11 +217,10 @@ func (r *PackageRevisionReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 
 			// Perform all operations required before removing the finalizer and allow
 			// the Kubernetes API to remove the custom resource.
-			r.doFinalizerOperationsForPackageRevision(PackageRevision)
-
-			// TODO(user): If you add operations to the doFinalizerOperationsForPackageRevision method
-			// then you need to ensure that all worked fine before deleting and updating the Downgrade status
-			// otherwise, you should requeue here.
+			if err := r.doFinalizerOperationsForPackageRevision(ctx, PackageRevision); err != nil {
+				log.Error(err, "Failed to perform finalizer operations for PackageRevision")
+				return ctrl.Result{}, err
+			}
 
 			// Re-fetch the PackageRevision Custom Resource before updating the status
 			// so that we have the latest state of the resource on the cluster and we will avoid
@@ -198,6 +262,26 @@ func (r *PackageRevisionReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	lifecycle := PackageRevision.Spec.Lifecycle
 	klog.Infof("Lifecycle is %s", lifecycle)
 
+	var result ctrl.Result
+	if lifecycle == cachev1alpha1.PackageRevisionLifecycleDraft {
+		if result, err = r.reconcileDraft(ctx, PackageRevision); err != nil {
+			log.Error(err, "Failed to reconcile draft PackageRevision contents")
+			return ctrl.Result{}, err
+		}
+	}
+	if lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished {
+		if err := r.restoreContents(ctx, PackageRevision); err != nil {
+			log.Error(err, "Failed to restore published PackageRevision contents")
+			return ctrl.Result{}, err
+		}
+	}
+
+	recordApproval(PackageRevision, time.Now())
+	if err := r.recordLifecycle(ctx, PackageRevision); err != nil {
+		log.Error(err, "Failed to record PackageRevision lifecycle")
+		return ctrl.Result{}, err
+	}
+
 	// The following implementation will update the status
 	meta.SetStatusCondition(&PackageRevision.Status.Conditions, metav1.Condition{Type: typeAvailablePackageRevision,
 		Status: metav1.ConditionTrue, Reason: "Reconciling",
@@ -208,15 +292,143 @@ func (r *PackageRevisionReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
+}
+
+// reconcileDraft keeps the contents of a draft PackageRevision up to date. The contents are
+// produced by applying the tasks of the draft when it is first reconciled. On every
+// reconciliation, the injection points in the contents are injected from in-cluster objects
+// and the contents are rendered by running their Kptfile pipelines.
+// The outcome is recorded in the status of the PackageRevision, which the caller persists.
+func (r *PackageRevisionReconciler) reconcileDraft(ctx context.Context, pr *cachev1alpha1.PackageRevision) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	prr := &cachev1alpha1.PackageRevisionResources{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, prr)
+	creating := apierrors.IsNotFound(err)
+	if err != nil && !creating {
+		return ctrl.Result{}, err
+	}
+
+	if !creating {
+		if err := r.recordEdited(ctx, pr, prr); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if creating {
+		// The start of the tasks is only reported the first time they are applied, not when
+		// they are retried.
+		recorder := r.Recorder
+		if meta.FindStatusCondition(pr.Status.Conditions, typeTasksAppliedPackageRevision) != nil {
+			recorder = nil
+		}
+		contents, err := r.applyTasks(ctx, pr, recorder)
+		if err != nil {
+			log.Info("Failed to apply tasks", "error", err.Error())
+			condition := metav1.Condition{Type: typeTasksAppliedPackageRevision,
+				Status: metav1.ConditionFalse, Reason: "TaskFailed", Message: err.Error()}
+			recordTransition(r.Recorder, pr, pr.Status.Conditions, condition, corev1.EventTypeWarning, ReasonTaskFailed)
+			meta.SetStatusCondition(&pr.Status.Conditions, condition)
+			return ctrl.Result{RequeueAfter: taskRetryPeriod}, nil
+		}
+		prr = &cachev1alpha1.PackageRevisionResources{
+			ObjectMeta: metav1.ObjectMeta{Name: pr.Name, Namespace: pr.Namespace},
+			Spec: cachev1alpha1.PackageRevisionResourcesSpec{
+				PackageName:    pr.Spec.PackageName,
+				RepositoryName: pr.Spec.RepositoryName,
+				WorkspaceName:  pr.Spec.WorkspaceName,
+				Resources:      contents,
+			},
+		}
+		if err := controllerutil.SetControllerReference(pr, prr, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	meta.SetStatusCondition(&pr.Status.Conditions, metav1.Condition{Type: typeTasksAppliedPackageRevision,
+		Status: metav1.ConditionTrue, Reason: "Applied", Message: fmt.Sprintf("%d tasks applied", len(pr.Spec.Tasks))})
+
+	var result ctrl.Result
+	changed := false
+	nodes, readErr := kpt.ReadResources(prr.Spec.Resources)
+	err = readErr
+	if err == nil {
+		pr.Status.InjectionPoints, changed, err = r.injectConfig(ctx, pr, nodes)
+	}
+	switch {
+	case err != nil:
+		meta.SetStatusCondition(&pr.Status.Conditions, metav1.Condition{Type: typeConfigInjectedPackageRevision,
+			Status: metav1.ConditionFalse, Reason: "InjectionFailed", Message: err.Error()})
+	case len(pr.Status.InjectionPoints) == 0:
+		meta.RemoveStatusCondition(&pr.Status.Conditions, typeConfigInjectedPackageRevision)
+	default:
+		result.RequeueAfter = injectionResyncPeriod
+		condition := metav1.Condition{Type: typeConfigInjectedPackageRevision,
+			Status: metav1.ConditionTrue, Reason: "Injected", Message: "all required injection points are injected"}
+		for _, point := range pr.Status.InjectionPoints {
+			if point.Required && point.Source == nil {
+				condition.Status, condition.Reason = metav1.ConditionFalse, "MissingInjection"
+				condition.Message = fmt.Sprintf("required injection point %s %q in %s is not injected", point.Kind, point.Name, point.File)
+				break
+			}
+		}
+		meta.SetStatusCondition(&pr.Status.Conditions, condition)
+	}
+
+	conflicts := readErr == nil && findMergeConflicts(pr, prr.Spec.Resources)
+	switch {
+	case readErr != nil:
+		condition := metav1.Condition{Type: typeRenderedPackageRevision,
+			Status: metav1.ConditionFalse, Reason: "RenderFailed", Message: readErr.Error()}
+		recordTransition(r.Recorder, pr, pr.Status.Conditions, condition, corev1.EventTypeWarning, ReasonRenderFailed)
+		meta.SetStatusCondition(&pr.Status.Conditions, condition)
+	case conflicts:
+		// Functions are not run over contents that hold conflicts, which may not be valid.
+		condition := metav1.Condition{Type: typeRenderedPackageRevision,
+			Status: metav1.ConditionFalse, Reason: "MergeConflict", Message: "merge conflicts must be resolved first"}
+		recordTransition(r.Recorder, pr, pr.Status.Conditions, condition, corev1.EventTypeWarning, ReasonMergeConflict)
+		meta.SetStatusCondition(&pr.Status.Conditions, condition)
+	default:
+		rendered, ok, err := r.renderContents(ctx, pr, nodes)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if ok {
+			nodes, changed = rendered, true
+		}
+	}
+
+	if changed {
+		contents, err := kpt.WriteResources(prr.Spec.Resources, nodes)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		changed = !maps.Equal(contents, prr.Spec.Resources)
+		prr.Spec.Resources = contents
+	}
+	switch {
+	case creating:
+		log.Info("Creating PackageRevisionResources")
+		if err := r.Create(ctx, prr); err != nil {
+			return ctrl.Result{}, err
+		}
+	case changed:
+		log.Info("Updating PackageRevisionResources")
+		if err := r.Update(ctx, prr); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	// The resources the operator writes itself are not edits.
+	pr.Status.ObservedResourcesGeneration = prr.Generation
+	return result, nil
 }
 
 // finalizePackageRevision will perform the required operations before delete the CR.
-func (r *PackageRevisionReconciler) doFinalizerOperationsForPackageRevision(cr *cachev1alpha1.PackageRevision) {
-	// TODO(user): Add the cleanup steps that the operator
-	// needs to do before the CR can be deleted. Examples
-	// of finalizers include performing backups and deleting
-	// resources that are not owned by this CR, like a PVC.
+func (r *PackageRevisionReconciler) doFinalizerOperationsForPackageRevision(ctx context.Context,
+	cr *cachev1alpha1.PackageRevision) error {
+	if err := r.recordDeleted(ctx, cr); err != nil {
+		return err
+	}
 
 	// Note: It is not recommended to use finalizers with the purpose of deleting resources which are
 	// created and managed in the reconciliation. These ones, such as the Deployment created on this reconcile,
@@ -225,10 +437,11 @@ func (r *PackageRevisionReconciler) doFinalizerOperationsForPackageRevision(cr *
 	// More info: https://kubernetes.io/docs/tasks/administer-cluster/use-cascading-deletion/
 
 	// The following implementation will raise an event
-	r.Recorder.Event(cr, "Warning", "Deleting",
-		fmt.Sprintf("Custom Resource %s is being deleted from the namespace %s",
-			cr.Name,
-			cr.Namespace))
+	eventf(r.Recorder, cr, corev1.EventTypeWarning, ReasonDeleting,
+		"Custom Resource %s is being deleted from the namespace %s",
+		cr.Name,
+		cr.Namespace)
+	return nil
 }
 
 // labelsForPackageRevision returns the labels for selecting the resources
@@ -267,7 +480,7 @@ func imageForPackageRevision() (string, error) {
 // or deletion of a Custom Resource (CR) of the PackageRevision kind, as well as any changes
 // to the Deployment that the controller manages and owns.
 func (r *PackageRevisionReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		// Watch the PackageRevision CR(s) and trigger reconciliation whenever it
 		// is created, updated, or deleted
 		For(&cachev1alpha1.PackageRevision{}).
@@ -276,6 +489,20 @@ func (r *PackageRevisionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		// owned and managed by this controller, it will trigger reconciliation, ensuring that the cluster
 		// state aligns with the desired state. See that the ownerRef was set when the Deployment was created.
 		Owns(&appsv1.Deployment{}).
-		WithOptions(controller.Options{MaxConcurrentReconciles: 2}).
-		Complete(r)
+		// Watch the contents of the PackageRevision, and the ConfigMaps injected into it, so that
+		// config is injected again when either of them changes.
+		Owns(&cachev1alpha1.PackageRevisionResources{}).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(
+			r.mapInjectionSourceToPackageRevisions(injectableGroupKind))).
+		// Watch the FunctionPolicies, so that drafts are rendered again when the functions they
+		// may run change.
+		Watches(&cachev1alpha1.FunctionPolicy{}, handler.EnqueueRequestsFromMapFunc(r.mapFunctionPolicyToPackageRevisions)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 2})
+	return watchShards(b, mgr.GetClient(), r.Shards, &cachev1alpha1.PackageRevisionList{}, r.shardOf).Complete(r)
+}
+
+// shardOf returns the shard of a PackageRevision, which is the shard of its Repository.
+func (r *PackageRevisionReconciler) shardOf(obj client.Object) int {
+	pr := obj.(*cachev1alpha1.PackageRevision)
+	return r.Shards.For(pr.Namespace, pr.Spec.RepositoryName)
 }
@@ -17,17 +17,40 @@ limitations under the License.
 package controller
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/kustomize/kyaml/fn/framework"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/history"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+	"github.com/liamfallon/porch-operator/internal/merge"
+	"github.com/liamfallon/porch-operator/internal/tracing"
 )
 
 var _ = Describe("PackageRevision Controller", func() {
@@ -81,4 +104,1192 @@ var _ = Describe("PackageRevision Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When reconciling a draft that injects config", func() {
+		const namespace = "default"
+
+		ctx := context.Background()
+
+		var reconciler *PackageRevisionReconciler
+
+		reconcileRevision := func(name string) {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: namespace, Name: name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		contentsOf := func(name string) map[string]string {
+			prr := &cachev1alpha1.PackageRevisionResources{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, prr)).To(Succeed())
+			return prr.Spec.Resources
+		}
+
+		BeforeEach(func() {
+			reconciler = &PackageRevisionReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+		})
+
+		It("should clone the upstream and inject the in-cluster ConfigMap", func() {
+			By("creating the published upstream and its contents")
+			upstream := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "blueprints.app.v1", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "app",
+					RepositoryName: "blueprints",
+					WorkspaceName:  "v1",
+					Lifecycle:      cachev1alpha1.PackageRevisionLifecyclePublished,
+				},
+			}
+			Expect(k8sClient.Create(ctx, upstream)).To(Succeed())
+			Expect(k8sClient.Create(ctx, &cachev1alpha1.PackageRevisionResources{
+				ObjectMeta: metav1.ObjectMeta{Name: upstream.Name, Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionResourcesSpec{
+					PackageName:    "app",
+					RepositoryName: "blueprints",
+					WorkspaceName:  "v1",
+					Resources: map[string]string{
+						"Kptfile": "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: app\n",
+						"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\n" +
+							"  annotations:\n    kpt.dev/config-injection: required\ndata:\n  site: unknown\n",
+					},
+				},
+			})).To(Succeed())
+
+			By("creating the in-cluster ConfigMap to inject")
+			source := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-1-config", Namespace: namespace},
+				Data:       map[string]string{"site": "edge-1"},
+			}
+			Expect(k8sClient.Create(ctx, source)).To(Succeed())
+
+			By("creating a draft that clones the upstream")
+			draft := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-1.app.injected", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "edge-app",
+					RepositoryName: "edge-1",
+					WorkspaceName:  "injected",
+					Lifecycle:      cachev1alpha1.PackageRevisionLifecycleDraft,
+					Tasks: []cachev1alpha1.Task{{
+						Type: cachev1alpha1.TaskTypeClone,
+						Clone: &cachev1alpha1.PackageCloneTaskSpec{
+							Upstream: cachev1alpha1.UpstreamPackage{
+								UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: upstream.Name},
+							},
+						},
+					}},
+					Injectors: []cachev1alpha1.InjectionSelector{{Kind: "ConfigMap", Name: source.Name}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, draft)).To(Succeed())
+
+			By("reconciling the draft")
+			reconcileRevision(draft.Name)
+
+			contents := contentsOf(draft.Name)
+			Expect(contents["Kptfile"]).To(ContainSubstring("name: edge-app"))
+			Expect(contents["config.yaml"]).To(ContainSubstring("site: edge-1"))
+			Expect(contents["config.yaml"]).To(ContainSubstring("kpt.dev/injected-resource-name: edge-1-config"))
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: draft.Name}, draft)).To(Succeed())
+			Expect(draft.Status.InjectionPoints).To(HaveLen(1))
+			point := draft.Status.InjectionPoints[0]
+			Expect(point.File).To(Equal("config.yaml"))
+			Expect(point.Name).To(Equal("app-config"))
+			Expect(point.Required).To(BeTrue())
+			Expect(point.Source).NotTo(BeNil())
+			Expect(point.Source.Name).To(Equal(source.Name))
+			Expect(meta.IsStatusConditionTrue(draft.Status.Conditions, typeTasksAppliedPackageRevision)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(draft.Status.Conditions, typeConfigInjectedPackageRevision)).To(BeTrue())
+
+			By("updating the in-cluster ConfigMap")
+			source.Data["site"] = "edge-1b"
+			Expect(k8sClient.Update(ctx, source)).To(Succeed())
+			Expect(reconciler.mapInjectionSourceToPackageRevisions(schema.GroupKind{Kind: "ConfigMap"})(ctx, source)).
+				To(ContainElement(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: draft.Name}}))
+			reconcileRevision(draft.Name)
+			Expect(contentsOf(draft.Name)["config.yaml"]).To(ContainSubstring("site: edge-1b"))
+
+			By("deleting the in-cluster ConfigMap")
+			Expect(k8sClient.Delete(ctx, source)).To(Succeed())
+			reconcileRevision(draft.Name)
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: draft.Name}, draft)).To(Succeed())
+			Expect(draft.Status.InjectionPoints[0].Source).To(BeNil())
+			condition := meta.FindStatusCondition(draft.Status.Conditions, typeConfigInjectedPackageRevision)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("MissingInjection"))
+
+			By("creating the in-cluster ConfigMap again")
+			source = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: namespace}}
+			Expect(reconciler.mapInjectionSourceToPackageRevisions(schema.GroupKind{Kind: "ConfigMap"})(ctx, source)).
+				To(ContainElement(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: draft.Name}}))
+			other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "edge-2-config", Namespace: namespace}}
+			Expect(reconciler.mapInjectionSourceToPackageRevisions(schema.GroupKind{Kind: "ConfigMap"})(ctx, other)).
+				NotTo(ContainElement(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: draft.Name}}))
+		})
+
+		It("should never inject Secrets or other kinds than ConfigMaps", func() {
+			upstream := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "blueprints.credentials.v1", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "credentials",
+					RepositoryName: "blueprints",
+					WorkspaceName:  "v1",
+					Lifecycle:      cachev1alpha1.PackageRevisionLifecyclePublished,
+				},
+			}
+			Expect(k8sClient.Create(ctx, upstream)).To(Succeed())
+			secret := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: credentials\n" +
+				"  annotations:\n    kpt.dev/config-injection: required\nstringData:\n  password: unknown\n"
+			Expect(k8sClient.Create(ctx, &cachev1alpha1.PackageRevisionResources{
+				ObjectMeta: metav1.ObjectMeta{Name: upstream.Name, Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionResourcesSpec{
+					PackageName:    "credentials",
+					RepositoryName: "blueprints",
+					WorkspaceName:  "v1",
+					Resources: map[string]string{
+						"Kptfile":     "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: credentials\n",
+						"secret.yaml": secret,
+						"repo.yaml": "apiVersion: porch.kpt.dev/v1alpha1\nkind: Repository\nmetadata:\n  name: edge-3\n" +
+							"  annotations:\n    kpt.dev/config-injection: optional\n",
+					},
+				},
+			})).To(Succeed())
+			Expect(k8sClient.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: namespace},
+				StringData: map[string]string{"password": "hunter2"},
+			})).To(Succeed())
+
+			draft := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-3.credentials.injected", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "credentials",
+					RepositoryName: "edge-3",
+					WorkspaceName:  "injected",
+					Lifecycle:      cachev1alpha1.PackageRevisionLifecycleDraft,
+					Tasks: []cachev1alpha1.Task{{
+						Type: cachev1alpha1.TaskTypeClone,
+						Clone: &cachev1alpha1.PackageCloneTaskSpec{
+							Upstream: cachev1alpha1.UpstreamPackage{
+								UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: upstream.Name},
+							},
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, draft)).To(Succeed())
+
+			reconcileRevision(draft.Name)
+
+			Expect(contentsOf(draft.Name)["secret.yaml"]).To(Equal(secret))
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: draft.Name}, draft)).To(Succeed())
+			Expect(draft.Status.InjectionPoints).To(HaveLen(2))
+			for _, point := range draft.Status.InjectionPoints {
+				Expect(point.Source).To(BeNil())
+				switch point.Kind {
+				case "Secret":
+					Expect(point.Message).To(ContainSubstring("Secrets cannot be injected"))
+				default:
+					Expect(point.Message).To(Equal("Repository objects cannot be injected, only ConfigMaps can"))
+				}
+			}
+			condition := meta.FindStatusCondition(draft.Status.Conditions, typeConfigInjectedPackageRevision)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("MissingInjection"))
+		})
+
+		It("should report a clone from an unpublished upstream as a failed task", func() {
+			upstream := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "blueprints.app.draft", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "app",
+					RepositoryName: "blueprints",
+					WorkspaceName:  "draft",
+					Lifecycle:      cachev1alpha1.PackageRevisionLifecycleProposed,
+				},
+			}
+			Expect(k8sClient.Create(ctx, upstream)).To(Succeed())
+
+			draft := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-2.app.unpublished", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "app",
+					RepositoryName: "edge-2",
+					WorkspaceName:  "unpublished",
+					Lifecycle:      cachev1alpha1.PackageRevisionLifecycleDraft,
+					Tasks: []cachev1alpha1.Task{{
+						Type: cachev1alpha1.TaskTypeClone,
+						Clone: &cachev1alpha1.PackageCloneTaskSpec{
+							Upstream: cachev1alpha1.UpstreamPackage{
+								UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: upstream.Name},
+							},
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, draft)).To(Succeed())
+
+			reconcileRevision(draft.Name)
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: draft.Name}, draft)).To(Succeed())
+			condition := meta.FindStatusCondition(draft.Status.Conditions, typeTasksAppliedPackageRevision)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("is not published"))
+
+			prr := &cachev1alpha1.PackageRevisionResources{}
+			err := k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: draft.Name}, prr)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("When upgrading a draft", func() {
+		const namespace = "default"
+
+		ctx := context.Background()
+
+		var reconciler *PackageRevisionReconciler
+
+		// createRevision creates a PackageRevision with its contents.
+		createRevision := func(repository, workspace string, lifecycle cachev1alpha1.PackageRevisionLifecycle,
+			resources map[string]string) *cachev1alpha1.PackageRevision {
+			pr := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: repository + ".web." + workspace, Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "web",
+					RepositoryName: repository,
+					WorkspaceName:  workspace,
+					Lifecycle:      lifecycle,
+				},
+			}
+			Expect(k8sClient.Create(ctx, pr)).To(Succeed())
+			Expect(k8sClient.Create(ctx, &cachev1alpha1.PackageRevisionResources{
+				ObjectMeta: metav1.ObjectMeta{Name: pr.Name, Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionResourcesSpec{
+					PackageName:    "web",
+					RepositoryName: repository,
+					WorkspaceName:  workspace,
+					Resources:      resources,
+				},
+			})).To(Succeed())
+			return pr
+		}
+
+		deployment := func(replicas int, image string) string {
+			return fmt.Sprintf("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"+
+				"spec:\n  replicas: %d\n  template:\n    spec:\n      containers:\n"+
+				"      - name: web\n        image: %s\n", replicas, image)
+		}
+
+		BeforeEach(func() {
+			reconciler = &PackageRevisionReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+		})
+
+		It("should report the conflicts left by a merge until they are resolved", func() {
+			kptfile := "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: web\n"
+
+			By("creating the old and new upstream revisions and the local revision")
+			oldUpstream := createRevision("catalog", "v1", cachev1alpha1.PackageRevisionLifecyclePublished, map[string]string{
+				"Kptfile":         kptfile,
+				"deployment.yaml": deployment(1, "web:1"),
+				"README.md":       "Web app\n",
+			})
+			newUpstream := createRevision("catalog", "v2", cachev1alpha1.PackageRevisionLifecyclePublished, map[string]string{
+				"Kptfile":         kptfile,
+				"deployment.yaml": deployment(2, "web:2"),
+				"service.yaml":    "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+				"README.md":       "Web app v2\n",
+			})
+			local := createRevision("site-a", "v1", cachev1alpha1.PackageRevisionLifecyclePublished, map[string]string{
+				"Kptfile":         kptfile,
+				"deployment.yaml": deployment(3, "web:1"),
+				"README.md":       "Web app for site A\n",
+			})
+
+			By("creating a draft that upgrades the local revision")
+			draft := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "site-a.web.upgrade", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "web",
+					RepositoryName: "site-a",
+					WorkspaceName:  "upgrade",
+					Lifecycle:      cachev1alpha1.PackageRevisionLifecycleDraft,
+					Tasks: []cachev1alpha1.Task{{
+						Type: cachev1alpha1.TaskTypeUpgrade,
+						Upgrade: &cachev1alpha1.PackageUpgradeTaskSpec{
+							OldUpstream:             cachev1alpha1.PackageRevisionRef{Name: oldUpstream.Name},
+							NewUpstream:             cachev1alpha1.PackageRevisionRef{Name: newUpstream.Name},
+							LocalPackageRevisionRef: cachev1alpha1.PackageRevisionRef{Name: local.Name},
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, draft)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(draft)})
+			Expect(err).NotTo(HaveOccurred())
+
+			prr := &cachev1alpha1.PackageRevisionResources{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(draft), prr)).To(Succeed())
+			Expect(prr.Spec.Resources["deployment.yaml"]).To(ContainSubstring("image: web:2"))
+			Expect(prr.Spec.Resources["deployment.yaml"]).To(ContainSubstring("replicas: 3"))
+			Expect(prr.Spec.Resources).To(HaveKey("service.yaml"))
+			Expect(prr.Spec.Resources["README.md"]).To(Equal("<<<<<<< local\nWeb app for site A\n" +
+				"||||||| base\nWeb app\n=======\nWeb app v2\n>>>>>>> upstream\n"))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(draft), draft)).To(Succeed())
+			Expect(draft.Status.MergeConflicts).To(ConsistOf(
+				cachev1alpha1.MergeConflict{
+					File:        "deployment.yaml",
+					APIVersion:  "apps/v1",
+					Kind:        "Deployment",
+					Name:        "web",
+					Path:        "spec.replicas",
+					Base:        "1",
+					Local:       "3",
+					Upstream:    "2",
+					Description: "changed both locally and upstream",
+				},
+				cachev1alpha1.MergeConflict{
+					File:        "README.md",
+					Description: "changed both locally and upstream; resolve the conflict markers in the file",
+				},
+			))
+			condition := meta.FindStatusCondition(draft.Status.Conditions, typeMergeConflictPackageRevision)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(Equal("2 merge conflicts must be resolved"))
+			rendered := meta.FindStatusCondition(draft.Status.Conditions, typeRenderedPackageRevision)
+			Expect(rendered).NotTo(BeNil())
+			Expect(rendered.Reason).To(Equal("MergeConflict"))
+
+			By("resolving the conflicts")
+			nodes, err := kpt.ReadResources(prr.Spec.Resources)
+			Expect(err).NotTo(HaveOccurred())
+			for _, node := range nodes {
+				if node.GetKind() == "Deployment" {
+					Expect(node.PipeE(yaml.ClearAnnotation(merge.ConflictsAnnotation))).To(Succeed())
+				}
+			}
+			prr.Spec.Resources, err = kpt.WriteResources(prr.Spec.Resources, nodes)
+			Expect(err).NotTo(HaveOccurred())
+			prr.Spec.Resources["README.md"] = "Web app v2 for site A\n"
+			Expect(k8sClient.Update(ctx, prr)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(draft)})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(draft), draft)).To(Succeed())
+			Expect(draft.Status.MergeConflicts).To(BeEmpty())
+			condition = meta.FindStatusCondition(draft.Status.Conditions, typeMergeConflictPackageRevision)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("Resolved"))
+			Expect(meta.IsStatusConditionTrue(draft.Status.Conditions, typeRenderedPackageRevision)).To(BeTrue())
+		})
+	})
+
+	Context("When rendering a draft", func() {
+		const namespace = "default"
+
+		ctx := context.Background()
+
+		// setLabel is a mutator that sets the label in its config on every resource.
+		setLabel := framework.ResourceListProcessorFunc(func(rl *framework.ResourceList) error {
+			data := rl.FunctionConfig.GetDataMap()
+			for _, item := range rl.Items {
+				labels := item.GetLabels()
+				labels[data["key"]] = data["value"]
+				if err := item.SetLabels(labels); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		// generate is a mutator that adds a ConfigMap to the package, if it is not there already.
+		generate := framework.ResourceListProcessorFunc(func(rl *framework.ResourceList) error {
+			for _, item := range rl.Items {
+				if item.GetName() == "generated" {
+					return nil
+				}
+			}
+			generated, err := yaml.Parse("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: generated\n")
+			if err != nil {
+				return err
+			}
+			rl.Items = append(rl.Items, generated)
+			return nil
+		})
+
+		// requireSite is a validator that fails for ConfigMaps without a site.
+		requireSite := framework.ResourceListProcessorFunc(func(rl *framework.ResourceList) error {
+			for _, item := range rl.Items {
+				if item.GetKind() == "ConfigMap" && item.GetName() != "generated" && item.GetDataMap()["site"] == "" {
+					rl.Results = append(rl.Results, &framework.Result{
+						Message:  "site is required",
+						Severity: framework.Error,
+						ResourceRef: &yaml.ResourceIdentifier{
+							TypeMeta: yaml.TypeMeta{APIVersion: item.GetApiVersion(), Kind: item.GetKind()},
+							NameMeta: yaml.NameMeta{Name: item.GetName()},
+						},
+					})
+				}
+			}
+			if rl.Results.ExitCode() != 0 {
+				return rl.Results
+			}
+			return nil
+		})
+
+		var reconciler *PackageRevisionReconciler
+		var recorder *record.FakeRecorder
+
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(10)
+			reconciler = &PackageRevisionReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+				FunctionRunner: fn.Builtins{
+					"example.com/set-label":    setLabel,
+					"example.com/generate:v1":  generate,
+					"example.com/require-site": requireSite,
+				},
+			}
+		})
+
+		kptfile := `apiVersion: kpt.dev/v1
+kind: Kptfile
+metadata:
+  name: app
+pipeline:
+  mutators:
+  - image: example.com/generate:v1
+  - image: example.com/set-label:v1
+    configMap:
+      key: tier
+      value: edge
+  validators:
+  - image: example.com/require-site
+    selectors:
+    - kind: ConfigMap
+    exclude:
+    - name: labels
+`
+		subKptfile := `apiVersion: kpt.dev/v1
+kind: Kptfile
+metadata:
+  name: db
+pipeline:
+  mutators:
+  - image: example.com/set-label:v1
+    configPath: labels.yaml
+`
+		labels := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: labels\ndata:\n  key: component\n  value: db\n"
+
+		It("should run the pipelines of the package and its subpackages", func() {
+			draft := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-3.app.rendered", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "app",
+					RepositoryName: "edge-3",
+					WorkspaceName:  "rendered",
+					Lifecycle:      cachev1alpha1.PackageRevisionLifecycleDraft,
+				},
+			}
+			Expect(k8sClient.Create(ctx, draft)).To(Succeed())
+			prr := &cachev1alpha1.PackageRevisionResources{
+				ObjectMeta: metav1.ObjectMeta{Name: draft.Name, Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionResourcesSpec{
+					PackageName:    "app",
+					RepositoryName: "edge-3",
+					WorkspaceName:  "rendered",
+					Resources: map[string]string{
+						"Kptfile":        kptfile,
+						"config.yaml":    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\ndata:\n  site: edge-3\n",
+						"db/Kptfile":     subKptfile,
+						"db/labels.yaml": labels,
+						"db/db.yaml":     "apiVersion: v1\nkind: Service\nmetadata:\n  name: db\n",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, prr)).To(Succeed())
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(draft)})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prr), prr)).To(Succeed())
+			Expect(prr.Spec.Resources["config.yaml"]).To(ContainSubstring("tier: edge"))
+			Expect(prr.Spec.Resources["config.yaml"]).NotTo(ContainSubstring("component: db"))
+			Expect(prr.Spec.Resources["db/db.yaml"]).To(ContainSubstring("tier: edge"))
+			Expect(prr.Spec.Resources["db/db.yaml"]).To(ContainSubstring("component: db"))
+			Expect(prr.Spec.Resources["configmap_generated.yaml"]).To(ContainSubstring("tier: edge"))
+			Expect(prr.Spec.Resources["config.yaml"]).NotTo(ContainSubstring("config.kubernetes.io"))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(draft), draft)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(draft.Status.Conditions, typeRenderedPackageRevision)).To(BeTrue())
+			Expect(draft.Status.RenderResults).To(HaveLen(4))
+			Expect(draft.Status.RenderResults[0].Package).To(Equal("db"))
+			Expect(draft.Status.RenderResults[3].Stage).To(Equal("validator"))
+
+			By("rendering the rendered package again")
+			resourceVersion := prr.ResourceVersion
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(draft)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prr), prr)).To(Succeed())
+			Expect(prr.ResourceVersion).To(Equal(resourceVersion))
+
+			By("changing the contents so that validation fails")
+			prr.Spec.Resources["config.yaml"] = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\n"
+			Expect(k8sClient.Update(ctx, prr)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(draft)})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(draft), draft)).To(Succeed())
+			condition := meta.FindStatusCondition(draft.Status.Conditions, typeRenderedPackageRevision)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("RenderFailed"))
+			failed := draft.Status.RenderResults[len(draft.Status.RenderResults)-1]
+			Expect(failed.Image).To(Equal("example.com/require-site"))
+			Expect(failed.Error).NotTo(BeEmpty())
+			Expect(failed.Results).To(ConsistOf(HaveField("Message", "site is required")))
+			Expect(failed.Results[0].ResourceRef.Name).To(Equal("app-config"))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prr), prr)).To(Succeed())
+			Expect(prr.Spec.Resources["config.yaml"]).NotTo(ContainSubstring("tier: edge"))
+		})
+
+		It("should refuse to run functions that a FunctionPolicy does not allow", func() {
+			functionPolicy := &cachev1alpha1.FunctionPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-4-functions"},
+				Spec: cachev1alpha1.FunctionPolicySpec{
+					Repositories: []string{"edge-4"},
+					AllowedFunctions: []cachev1alpha1.AllowedFunction{
+						{Image: "example.com/set-label"},
+						{Image: "example.com/generate", Digests: []string{"sha256:1234"}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, functionPolicy)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, functionPolicy))).To(Succeed())
+			})
+
+			draft := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-4.app.policy", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "app",
+					RepositoryName: "edge-4",
+					WorkspaceName:  "policy",
+					Lifecycle:      cachev1alpha1.PackageRevisionLifecycleDraft,
+				},
+			}
+			Expect(k8sClient.Create(ctx, draft)).To(Succeed())
+			prr := &cachev1alpha1.PackageRevisionResources{
+				ObjectMeta: metav1.ObjectMeta{Name: draft.Name, Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionResourcesSpec{
+					PackageName:    "app",
+					RepositoryName: "edge-4",
+					WorkspaceName:  "policy",
+					Resources: map[string]string{
+						"Kptfile":     kptfile,
+						"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\ndata:\n  site: edge-4\n",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, prr)).To(Succeed())
+
+			Expect(reconciler.mapFunctionPolicyToPackageRevisions(ctx, functionPolicy)).To(
+				ContainElement(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(draft)}))
+
+			for range 2 {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(draft)})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(draft), draft)).To(Succeed())
+			condition := meta.FindStatusCondition(draft.Status.Conditions, typeRenderedPackageRevision)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("FunctionNotAllowed"))
+			Expect(condition.Message).To(ContainSubstring(`FunctionPolicy "edge-4-functions"`))
+			Expect(condition.Message).To(ContainSubstring("example.com/generate:v1"))
+			Expect(recorder.Events).To(HaveLen(1))
+			Expect(<-recorder.Events).To(HavePrefix("Warning FunctionNotAllowed"))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prr), prr)).To(Succeed())
+			Expect(prr.Spec.Resources).NotTo(HaveKey("configmap_generated.yaml"))
+
+			By("refusing the functions in previews, whatever spec they send")
+			response, err := reconciler.Preview(ctx, &cachev1alpha1.PreviewRequest{Namespace: namespace, Name: draft.Name})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Error).To(ContainSubstring("example.com/generate:v1"))
+			spec := draft.Spec.DeepCopy()
+			spec.Tasks = []cachev1alpha1.Task{{Type: cachev1alpha1.TaskTypeInit, Init: &cachev1alpha1.PackageInitTaskSpec{}}}
+			response, err = reconciler.Preview(ctx, &cachev1alpha1.PreviewRequest{Namespace: namespace, Name: draft.Name,
+				Spec: spec, Resources: map[string]string{"Kptfile": kptfile}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Error).To(ContainSubstring("example.com/generate:v1"))
+			spec.RepositoryName = "edge-4-unrestricted"
+			_, err = reconciler.Preview(ctx, &cachev1alpha1.PreviewRequest{Namespace: namespace, Name: draft.Name, Spec: spec})
+			Expect(err).To(MatchError(errPreviewInvalid))
+			Expect(err).To(MatchError(ContainSubstring("repository cannot be changed")))
+
+			By("allowing every function of the package")
+			functionPolicy.Spec.AllowedFunctions = []cachev1alpha1.AllowedFunction{{Image: "example.com/*"}}
+			Expect(k8sClient.Update(ctx, functionPolicy)).To(Succeed())
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(draft)})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(draft), draft)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(draft.Status.Conditions, typeRenderedPackageRevision)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prr), prr)).To(Succeed())
+			Expect(prr.Spec.Resources).To(HaveKey("configmap_generated.yaml"))
+		})
+
+		It("should preview a change to a draft without storing it", func() {
+			draft := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-5.app.preview", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{
+					PackageName:    "app",
+					RepositoryName: "edge-5",
+					WorkspaceName:  "preview",
+					Lifecycle:      cachev1alpha1.PackageRevisionLifecycleDraft,
+				},
+			}
+			Expect(k8sClient.Create(ctx, draft)).To(Succeed())
+			prr := &cachev1alpha1.PackageRevisionResources{
+				ObjectMeta: metav1.ObjectMeta{Name: draft.Name, Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionResourcesSpec{
+					PackageName:    "app",
+					RepositoryName: "edge-5",
+					WorkspaceName:  "preview",
+					Resources: map[string]string{
+						"Kptfile":     kptfile,
+						"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\ndata:\n  site: edge-5\n",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, prr)).To(Succeed())
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(draft)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prr), prr)).To(Succeed())
+			resourceVersion := prr.ResourceVersion
+
+			preview := func(request *cachev1alpha1.PreviewRequest) (*httptest.ResponseRecorder, *cachev1alpha1.PreviewResponse) {
+				body, err := json.Marshal(request)
+				Expect(err).NotTo(HaveOccurred())
+				recorder := httptest.NewRecorder()
+				(&PreviewHandler{Reconciler: reconciler}).ServeHTTP(recorder,
+					httptest.NewRequest(http.MethodPost, PreviewPath, bytes.NewReader(body)))
+				response := &cachev1alpha1.PreviewResponse{}
+				if recorder.Code == http.StatusOK {
+					Expect(json.Unmarshal(recorder.Body.Bytes(), response)).To(Succeed())
+				}
+				return recorder, response
+			}
+
+			recorder, response := preview(&cachev1alpha1.PreviewRequest{
+				Namespace: namespace,
+				Name:      draft.Name,
+				Resources: map[string]string{
+					"service.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n",
+				},
+			})
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(response.Error).To(BeEmpty())
+			Expect(response.Resources["service.yaml"]).To(ContainSubstring("tier: edge"))
+			Expect(response.RenderResults).To(HaveLen(3))
+			Expect(response.Diff).To(ConsistOf(And(
+				HaveField("Path", "service.yaml"),
+				HaveField("Change", cachev1alpha1.ChangeAdded),
+				HaveField("Diff", ContainSubstring("+    tier: edge")),
+			)))
+
+			By("previewing a change that fails validation")
+			recorder, response = preview(&cachev1alpha1.PreviewRequest{
+				Namespace: namespace,
+				Name:      draft.Name,
+				Resources: map[string]string{
+					"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\n",
+				},
+			})
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(response.Error).To(ContainSubstring("example.com/require-site"))
+			Expect(response.Resources).To(BeEmpty())
+			Expect(response.RenderResults[len(response.RenderResults)-1].Results).To(
+				ConsistOf(HaveField("Message", "site is required")))
+
+			By("previewing a package revision that does not exist")
+			recorder, _ = preview(&cachev1alpha1.PreviewRequest{Namespace: namespace, Name: "missing"})
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prr), prr)).To(Succeed())
+			Expect(prr.ResourceVersion).To(Equal(resourceVersion))
+		})
+	})
+
+	Context("When collecting metrics", func() {
+		const namespace = "default"
+		ctx := context.Background()
+
+		newRevision := func(name, repository, pkg string, revision int, lifecycle cachev1alpha1.PackageRevisionLifecycle,
+			tasks ...cachev1alpha1.Task) *cachev1alpha1.PackageRevision {
+			pr := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{PackageName: pkg, RepositoryName: repository,
+					WorkspaceName: name, Revision: revision, Lifecycle: lifecycle, Tasks: tasks},
+			}
+			Expect(k8sClient.Create(ctx, pr)).To(Succeed())
+			return pr
+		}
+
+		// gather returns the values of the metric with the name, by the values of their labels in the
+		// order of the label names.
+		gather := func(name string) map[string]float64 {
+			registry := prometheus.NewPedanticRegistry()
+			Expect(registry.Register(&PackageRevisionCollector{Reader: k8sClient})).To(Succeed())
+			families, err := registry.Gather()
+			Expect(err).NotTo(HaveOccurred())
+			values := map[string]float64{}
+			for _, family := range families {
+				if family.GetName() != name {
+					continue
+				}
+				for _, metric := range family.GetMetric() {
+					var labels []string
+					for _, label := range metric.GetLabel() {
+						labels = append(labels, label.GetValue())
+					}
+					values[strings.Join(labels, "/")] = metric.GetGauge().GetValue()
+				}
+			}
+			return values
+		}
+
+		It("should count PackageRevisions, those behind their upstream, and time their approval", func() {
+			newRevision("metrics-blueprints.app.v1", "metrics-blueprints", "app", 1, cachev1alpha1.PackageRevisionLifecyclePublished)
+			newRevision("metrics-blueprints.app.v2", "metrics-blueprints", "app", 2, cachev1alpha1.PackageRevisionLifecyclePublished)
+			clone := func(upstream string) cachev1alpha1.Task {
+				return cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeClone, Clone: &cachev1alpha1.PackageCloneTaskSpec{
+					Upstream: cachev1alpha1.UpstreamPackage{UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: upstream}}}}
+			}
+			newRevision("metrics-deployments.app.old", "metrics-deployments", "app", 1,
+				cachev1alpha1.PackageRevisionLifecyclePublished, clone("metrics-blueprints.app.v1"))
+			proposed := newRevision("metrics-deployments.app.new", "metrics-deployments", "app", 0,
+				cachev1alpha1.PackageRevisionLifecycleProposed, clone("metrics-blueprints.app.v2"))
+
+			Expect(gather("porch_package_revisions")).To(And(
+				HaveKeyWithValue("Published/metrics-blueprints", 2.0),
+				HaveKeyWithValue("Published/metrics-deployments", 1.0),
+				HaveKeyWithValue("Proposed/metrics-deployments", 1.0)))
+			behind := gather("porch_package_revisions_behind_upstream")
+			Expect(behind).To(HaveKeyWithValue("metrics-deployments", 1.0))
+			Expect(behind).NotTo(HaveKey("metrics-blueprints"))
+
+			By("recording when the proposed revision is proposed and published")
+			reconciler := &PackageRevisionReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			reconcileProposed := func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(proposed)})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(proposed), proposed)).To(Succeed())
+			}
+			reconcileProposed()
+			Expect(proposed.Status.ProposedAt).NotTo(BeNil())
+			Expect(proposed.Status.PublishedAt.IsZero()).To(BeTrue())
+
+			proposed.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecyclePublished
+			Expect(k8sClient.Update(ctx, proposed)).To(Succeed())
+			reconcileProposed()
+			Expect(proposed.Status.PublishedAt.IsZero()).To(BeFalse())
+			Expect(proposed.Status.PublishedAt.Time).NotTo(BeTemporally("<", proposed.Status.ProposedAt.Time))
+		})
+	})
+
+	Context("When tracing", func() {
+		const namespace = "default"
+		ctx := context.Background()
+
+		var exporter *tracetest.InMemoryExporter
+
+		BeforeEach(func() {
+			exporter = tracetest.NewInMemoryExporter()
+			previous := otel.GetTracerProvider()
+			provider := tracing.NewProvider(sdktrace.WithSyncer(exporter))
+			otel.SetTracerProvider(provider)
+			DeferCleanup(func() {
+				otel.SetTracerProvider(previous)
+				Expect(provider.Shutdown(ctx)).To(Succeed())
+			})
+		})
+
+		// attributes returns the attributes of the span by their keys.
+		attributes := func(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+			values := map[attribute.Key]attribute.Value{}
+			for _, kv := range span.Attributes {
+				values[kv.Key] = kv.Value
+			}
+			return values
+		}
+
+		It("should trace the reconcile, its tasks and the functions it runs", func() {
+			upstream := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "traced-blueprints.app.v1", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", RepositoryName: "traced-blueprints",
+					WorkspaceName: "v1", Lifecycle: cachev1alpha1.PackageRevisionLifecyclePublished},
+			}
+			Expect(k8sClient.Create(ctx, upstream)).To(Succeed())
+			Expect(k8sClient.Create(ctx, &cachev1alpha1.PackageRevisionResources{
+				ObjectMeta: metav1.ObjectMeta{Name: upstream.Name, Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionResourcesSpec{PackageName: "app", RepositoryName: "traced-blueprints",
+					WorkspaceName: "v1", Resources: map[string]string{
+						"Kptfile": "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: app\n" +
+							"pipeline:\n  mutators:\n  - image: example.com/noop:v1\n",
+						"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\n",
+					}},
+			})).To(Succeed())
+			draft := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "traced.app.draft", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", RepositoryName: "traced",
+					WorkspaceName: "draft", Lifecycle: cachev1alpha1.PackageRevisionLifecycleDraft,
+					Tasks: []cachev1alpha1.Task{{Type: cachev1alpha1.TaskTypeClone, Clone: &cachev1alpha1.PackageCloneTaskSpec{
+						Upstream: cachev1alpha1.UpstreamPackage{UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: upstream.Name}}}}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, draft)).To(Succeed())
+
+			reconciler := &PackageRevisionReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				FunctionRunner: fn.Builtins{
+					"example.com/noop": framework.ResourceListProcessorFunc(func(*framework.ResourceList) error { return nil }),
+				},
+			}
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(draft)})
+			Expect(err).NotTo(HaveOccurred())
+
+			spans := map[string]tracetest.SpanStub{}
+			for _, span := range exporter.GetSpans() {
+				spans[span.Name] = span
+			}
+			Expect(spans).To(HaveKey("Reconcile PackageRevision"))
+			Expect(spans).To(HaveKey("Apply clone task"))
+			Expect(spans).To(HaveKey("Run function"))
+			reconcileSpan := spans["Reconcile PackageRevision"]
+			Expect(attributes(reconcileSpan)).To(And(
+				HaveKeyWithValue(tracing.PackageRevisionKey, attribute.StringValue(draft.Name)),
+				HaveKeyWithValue(tracing.RepositoryKey, attribute.StringValue("traced")),
+				HaveKeyWithValue(tracing.LifecycleKey, attribute.StringValue("Draft"))))
+
+			task := spans["Apply clone task"]
+			Expect(task.Parent.SpanID()).To(Equal(reconcileSpan.SpanContext.SpanID()))
+			Expect(attributes(task)).To(HaveKeyWithValue(tracing.TaskIndexKey, attribute.IntValue(0)))
+			function := spans["Run function"]
+			Expect(function.SpanContext.TraceID()).To(Equal(reconcileSpan.SpanContext.TraceID()))
+			Expect(attributes(function)).To(And(
+				HaveKeyWithValue(tracing.FunctionImageKey, attribute.StringValue("example.com/noop:v1")),
+				HaveKeyWithValue(tracing.FunctionStageKey, attribute.StringValue("mutator"))))
+		})
+	})
+
+	Context("When recording events", func() {
+		const namespace = "default"
+		ctx := context.Background()
+
+		var reconciler *PackageRevisionReconciler
+		var recorder *record.FakeRecorder
+
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(100)
+			reconciler = &PackageRevisionReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: recorder}
+		})
+
+		reconcileRevision := func(pr *cachev1alpha1.PackageRevision) {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pr)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pr), pr)).To(Succeed())
+		}
+
+		// events returns the events recorded since it was last called.
+		events := func() []string {
+			var recorded []string
+			for len(recorder.Events) > 0 {
+				recorded = append(recorded, <-recorder.Events)
+			}
+			return recorded
+		}
+
+		clone := func(upstream string) []cachev1alpha1.Task {
+			return []cachev1alpha1.Task{{Type: cachev1alpha1.TaskTypeClone, Clone: &cachev1alpha1.PackageCloneTaskSpec{
+				Upstream: cachev1alpha1.UpstreamPackage{UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: upstream}}}}}
+		}
+
+		It("should report a failing task once, not on every retry", func() {
+			draft := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "events-edge.app.failing", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", RepositoryName: "events-edge",
+					WorkspaceName: "failing", Lifecycle: cachev1alpha1.PackageRevisionLifecycleDraft,
+					Tasks: clone("events-blueprints.app.missing")},
+			}
+			Expect(k8sClient.Create(ctx, draft)).To(Succeed())
+
+			reconcileRevision(draft)
+			reconcileRevision(draft)
+			reconcileRevision(draft)
+			Expect(events()).To(ConsistOf(
+				HavePrefix("Normal TaskStarted Applying task 0 (clone)"),
+				HavePrefix("Warning TaskFailed task 0 (clone) failed")))
+		})
+
+		It("should report the changes of lifecycle, and newer upstreams to their downstreams", func() {
+			v1 := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "events-blueprints.app.v1", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", RepositoryName: "events-blueprints",
+					WorkspaceName: "v1", Revision: 1, Lifecycle: cachev1alpha1.PackageRevisionLifecyclePublished},
+			}
+			Expect(k8sClient.Create(ctx, v1)).To(Succeed())
+			downstream := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "events-edge.app.v1", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", RepositoryName: "events-edge",
+					WorkspaceName: "v1", Revision: 1, Lifecycle: cachev1alpha1.PackageRevisionLifecyclePublished,
+					Tasks: clone(v1.Name)},
+			}
+			Expect(k8sClient.Create(ctx, downstream)).To(Succeed())
+			v2 := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "events-blueprints.app.v2", Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", RepositoryName: "events-blueprints",
+					WorkspaceName: "v2", Lifecycle: cachev1alpha1.PackageRevisionLifecycleDraft},
+			}
+			Expect(k8sClient.Create(ctx, v2)).To(Succeed())
+			reconcileRevision(v2)
+			Expect(v2.Status.ObservedLifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecycleDraft))
+			Expect(events()).To(BeEmpty())
+
+			setLifecycle := func(lifecycle cachev1alpha1.PackageRevisionLifecycle) {
+				v2.Spec.Lifecycle = lifecycle
+				if lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished {
+					v2.Spec.Revision = 2
+				}
+				Expect(k8sClient.Update(ctx, v2)).To(Succeed())
+				reconcileRevision(v2)
+			}
+			setLifecycle(cachev1alpha1.PackageRevisionLifecycleProposed)
+			Expect(events()).To(ConsistOf("Normal Proposed Proposed for approval"))
+			setLifecycle(cachev1alpha1.PackageRevisionLifecycleDraft)
+			Expect(events()).To(ConsistOf("Normal Rejected Proposal rejected, back to Draft"))
+			setLifecycle(cachev1alpha1.PackageRevisionLifecycleProposed)
+			Expect(events()).To(ConsistOf("Normal Proposed Proposed for approval"))
+			setLifecycle(cachev1alpha1.PackageRevisionLifecyclePublished)
+			Expect(events()).To(ConsistOf(
+				"Normal Published Published as revision 2",
+				"Normal UpstreamUpdated Upstream events-blueprints.app.v1 has a newer revision events-blueprints.app.v2"))
+
+			By("reconciling the published revision again")
+			reconcileRevision(v2)
+			Expect(events()).To(BeEmpty())
+		})
+	})
+
+	Context("When keeping histories", func() {
+		const namespace = "default"
+		ctx := context.Background()
+
+		var reconciler *PackageRevisionReconciler
+
+		BeforeEach(func() {
+			reconciler = &PackageRevisionReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		})
+
+		reconcileRevision := func(pr *cachev1alpha1.PackageRevision) {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pr)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.IgnoreNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(pr), pr))).To(Succeed())
+		}
+
+		// entries returns the action, actor and lifecycles of the entries of the history.
+		entries := func(name string) []string {
+			h := &cachev1alpha1.PackageRevisionHistory{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, h)).To(Succeed())
+			var recorded []string
+			for _, entry := range h.Spec.Entries {
+				recorded = append(recorded, fmt.Sprintf("%s by %q: %q->%q", entry.Action, entry.Actor,
+					entry.FromLifecycle, entry.ToLifecycle))
+			}
+			return recorded
+		}
+
+		It("should record who created, edited, approved and deleted a revision", func() {
+			pr := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "history-blueprints.app.v1", Namespace: namespace,
+					Annotations: map[string]string{history.ActorAnnotation: "alice"}},
+				Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", RepositoryName: "history-blueprints",
+					WorkspaceName: "v1", Lifecycle: cachev1alpha1.PackageRevisionLifecycleDraft},
+			}
+			Expect(k8sClient.Create(ctx, pr)).To(Succeed())
+			reconcileRevision(pr)
+			reconcileRevision(pr)
+			Expect(entries(pr.Name)).To(Equal([]string{`Created by "alice": ""->"Draft"`}))
+
+			By("editing the resources of the draft")
+			prr := &cachev1alpha1.PackageRevisionResources{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pr), prr)).To(Succeed())
+			prr.Annotations = map[string]string{history.ActorAnnotation: "bob"}
+			prr.Spec.Resources = map[string]string{"Kptfile": "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: app\n"}
+			Expect(k8sClient.Update(ctx, prr)).To(Succeed())
+			reconcileRevision(pr)
+			reconcileRevision(pr)
+			Expect(entries(pr.Name)).To(HaveLen(2))
+			Expect(entries(pr.Name)[1]).To(Equal(`Edited by "bob": "Draft"->"Draft"`))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pr), pr)).To(Succeed())
+			Expect(pr.Status.EditedResourcesGeneration).To(Equal(prr.Generation))
+
+			By("proposing and approving the draft")
+			for _, step := range []struct {
+				actor     string
+				lifecycle cachev1alpha1.PackageRevisionLifecycle
+			}{{"bob", cachev1alpha1.PackageRevisionLifecycleProposed}, {"carol", cachev1alpha1.PackageRevisionLifecyclePublished}} {
+				pr.Annotations[history.ActorAnnotation] = step.actor
+				pr.Spec.Lifecycle = step.lifecycle
+				if step.lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished {
+					pr.Spec.Revision = 1
+				}
+				Expect(k8sClient.Update(ctx, pr)).To(Succeed())
+				reconcileRevision(pr)
+			}
+			Expect(pr.Status.PublishedBy).To(Equal("carol"))
+			Expect(entries(pr.Name)[2:]).To(Equal([]string{
+				`Proposed by "bob": "Draft"->"Proposed"`,
+				`Approved by "carol": "Proposed"->"Published"`,
+			}))
+
+			By("deleting the revision")
+			Expect(k8sClient.Delete(ctx, pr)).To(Succeed())
+			reconcileRevision(pr)
+			Expect(entries(pr.Name)[4:]).To(Equal([]string{`Deleted by "": "Published"->""`}))
+
+			By("refusing to rewrite the history")
+			h := &cachev1alpha1.PackageRevisionHistory{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pr), h)).To(Succeed())
+			Expect(h.Labels).To(HaveKeyWithValue(history.PackageLabel, "app"))
+			h.Spec.Entries = h.Spec.Entries[1:]
+			Expect(k8sClient.Update(ctx, h)).To(MatchError(ContainSubstring("entries are append-only")))
+		})
+
+		It("should go on reconciling a revision once its history is full", func() {
+			pr := &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "history-blueprints.app.full", Namespace: namespace,
+					Annotations: map[string]string{history.ActorAnnotation: "alice"}},
+				Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", RepositoryName: "history-blueprints",
+					WorkspaceName: "full", Lifecycle: cachev1alpha1.PackageRevisionLifecycleDraft},
+			}
+			Expect(k8sClient.Create(ctx, pr)).To(Succeed())
+			reconcileRevision(pr)
+			reconcileRevision(pr)
+
+			By("filling the history with edits")
+			h := &cachev1alpha1.PackageRevisionHistory{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pr), h)).To(Succeed())
+			for len(h.Spec.Entries) < history.MaxEntries {
+				h.Spec.Entries = append(h.Spec.Entries, history.NewEntry(pr, cachev1alpha1.PackageRevisionActionEdited,
+					"bob", pr.Spec.Lifecycle, metav1.Now()))
+			}
+			Expect(k8sClient.Update(ctx, h)).To(Succeed())
+
+			By("editing and proposing the draft")
+			prr := &cachev1alpha1.PackageRevisionResources{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pr), prr)).To(Succeed())
+			prr.Spec.Resources = map[string]string{"Kptfile": "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: app\n"}
+			Expect(k8sClient.Update(ctx, prr)).To(Succeed())
+			reconcileRevision(pr)
+			Expect(meta.IsStatusConditionTrue(pr.Status.Conditions, typeHistoryFullPackageRevision)).To(BeTrue())
+			pr.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecycleProposed
+			Expect(k8sClient.Update(ctx, pr)).To(Succeed())
+			reconcileRevision(pr)
+			Expect(pr.Status.ObservedLifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecycleProposed))
+			Expect(entries(pr.Name)).To(HaveLen(history.MaxEntries))
+		})
+	})
+
+	Context("When validating PackageRevisions", func() {
+		const namespace = "default"
+		ctx := context.Background()
+
+		newRevision := func(name string, tasks ...cachev1alpha1.Task) *cachev1alpha1.PackageRevision {
+			return &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", RepositoryName: "validation-blueprints",
+					WorkspaceName: "v1", Lifecycle: cachev1alpha1.PackageRevisionLifecycleDraft, Tasks: tasks},
+			}
+		}
+		gitUpstream := cachev1alpha1.UpstreamPackage{Type: cachev1alpha1.RepositoryTypeGit,
+			Git: &cachev1alpha1.GitPackage{Repo: "https://example.com/blueprints.git", Ref: "main", Directory: "app"}}
+
+		It("should refuse tasks that do not set exactly the member of their type", func() {
+			for _, tc := range []struct {
+				task    cachev1alpha1.Task
+				message string
+			}{
+				{cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeClone}, "exactly one of init, clone, edit and upgrade must be set"},
+				{cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeInit, Init: &cachev1alpha1.PackageInitTaskSpec{},
+					Clone: &cachev1alpha1.PackageCloneTaskSpec{Upstream: gitUpstream}},
+					"exactly one of init, clone, edit and upgrade must be set"},
+				{cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeEdit, Clone: &cachev1alpha1.PackageCloneTaskSpec{Upstream: gitUpstream}},
+					"the member that is set must be the one of the type"},
+				{cachev1alpha1.Task{Type: "patch", Init: &cachev1alpha1.PackageInitTaskSpec{}}, "Unsupported value"},
+			} {
+				Expect(k8sClient.Create(ctx, newRevision("validation-blueprints.app.tasks", tc.task))).
+					To(MatchError(ContainSubstring(tc.message)))
+			}
+		})
+
+		It("should refuse upstreams that do not set exactly one source", func() {
+			for _, tc := range []struct {
+				upstream cachev1alpha1.UpstreamPackage
+				message  string
+			}{
+				{cachev1alpha1.UpstreamPackage{Type: cachev1alpha1.RepositoryTypeGit}, "exactly one of git, oci and upstreamRef must be set"},
+				{cachev1alpha1.UpstreamPackage{Git: gitUpstream.Git,
+					UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: "blueprints.app.v1"}},
+					"exactly one of git, oci and upstreamRef must be set"},
+				{cachev1alpha1.UpstreamPackage{Type: cachev1alpha1.RepositoryTypeOCI, Git: gitUpstream.Git},
+					"type must name the member that is set"},
+			} {
+				task := cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeClone,
+					Clone: &cachev1alpha1.PackageCloneTaskSpec{Upstream: tc.upstream}}
+				Expect(k8sClient.Create(ctx, newRevision("validation-blueprints.app.upstreams", task))).
+					To(MatchError(ContainSubstring(tc.message)))
+			}
+		})
+
+		It("should keep the package, repository and workspace of a revision immutable", func() {
+			pr := newRevision("validation-blueprints.app.v1", cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeClone,
+				Clone: &cachev1alpha1.PackageCloneTaskSpec{Upstream: gitUpstream}})
+			Expect(k8sClient.Create(ctx, pr)).To(Succeed())
+			DeferCleanup(func() { Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pr))).To(Succeed()) })
+
+			for field, change := range map[string]func(*cachev1alpha1.PackageRevisionSpec){
+				"packageName":   func(spec *cachev1alpha1.PackageRevisionSpec) { spec.PackageName = "other" },
+				"repository":    func(spec *cachev1alpha1.PackageRevisionSpec) { spec.RepositoryName = "other" },
+				"workspaceName": func(spec *cachev1alpha1.PackageRevisionSpec) { spec.WorkspaceName = "" },
+			} {
+				changed := pr.DeepCopy()
+				change(&changed.Spec)
+				Expect(k8sClient.Update(ctx, changed)).To(MatchError(ContainSubstring(field + " is immutable")))
+			}
+
+			By("changing the lifecycle of the revision")
+			pr.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecycleProposed
+			Expect(k8sClient.Update(ctx, pr)).To(Succeed())
+		})
+	})
 })
@@ -0,0 +1,93 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/history"
+)
+
+// recordCreated records the creation of a PackageRevision in its history, unless it is recorded
+// already. A history that ends with a deletion belongs to a deleted PackageRevision of the same
+// name, and goes on with the creation of the new one.
+func (r *PackageRevisionReconciler) recordCreated(ctx context.Context, pr *cachev1alpha1.PackageRevision) error {
+	last, err := history.Last(ctx, r.Client, pr)
+	if err != nil || (last != nil && last.Action != cachev1alpha1.PackageRevisionActionDeleted) {
+		return err
+	}
+	entry := history.NewEntry(pr, cachev1alpha1.PackageRevisionActionCreated, history.Actor(pr), "", pr.CreationTimestamp)
+	return r.appendHistory(pr, history.Append(ctx, r.Client, pr, entry))
+}
+
+// recordEdited records an edit of the resources of a draft in its history, by the user who last
+// updated them, when their generation has changed since the draft was last reconciled. Drafts
+// reconciled before their resources generation was recorded have no edit recorded.
+// EditedResourcesGeneration is kept apart from ObservedResourcesGeneration, which reconcileDraft
+// also sets for the resources the operator writes itself, so that pushes are checked against the
+// generation of the last edit rather than of the last render.
+func (r *PackageRevisionReconciler) recordEdited(ctx context.Context, pr *cachev1alpha1.PackageRevision,
+	prr *cachev1alpha1.PackageRevisionResources) error {
+	if prr.Generation == pr.Status.ObservedResourcesGeneration {
+		return nil
+	}
+	if pr.Status.ObservedResourcesGeneration != 0 {
+		entry := history.NewEntry(pr, cachev1alpha1.PackageRevisionActionEdited, history.Actor(prr), pr.Spec.Lifecycle, metav1.Now())
+		if err := r.appendHistory(pr, history.Append(ctx, r.Client, pr, entry)); err != nil {
+			return err
+		}
+	}
+	pr.Status.ObservedResourcesGeneration = prr.Generation
+	pr.Status.EditedResourcesGeneration = prr.Generation
+	return nil
+}
+
+// recordDeleted records the deletion of a PackageRevision in its history. The admission webhook
+// records deletions with the user who made them, so the deletion is only recorded here, without a
+// user, if the webhook has not recorded it.
+func (r *PackageRevisionReconciler) recordDeleted(ctx context.Context, pr *cachev1alpha1.PackageRevision) error {
+	last, err := history.Last(ctx, r.Client, pr)
+	if err != nil || (last != nil && last.Action == cachev1alpha1.PackageRevisionActionDeleted) {
+		return err
+	}
+	entry := history.NewEntry(pr, cachev1alpha1.PackageRevisionActionDeleted, "", pr.Spec.Lifecycle, metav1.Now())
+	entry.ToLifecycle = ""
+	return r.appendHistory(pr, history.Append(ctx, r.Client, pr, entry))
+}
+
+// appendHistory handles the error of appending an entry to the history of the PackageRevision. A
+// full history is reported by the HistoryFull condition rather than by an error, so that the
+// PackageRevision goes on being reconciled without recording further entries.
+func (r *PackageRevisionReconciler) appendHistory(pr *cachev1alpha1.PackageRevision, err error) error {
+	if !errors.Is(err, history.ErrFull) {
+		return err
+	}
+	condition := metav1.Condition{Type: typeHistoryFullPackageRevision, Status: metav1.ConditionTrue,
+		Reason: "HistoryFull", ObservedGeneration: pr.Generation,
+		Message: fmt.Sprintf("The history holds %d entries: what is done to the revision is no longer recorded",
+			history.MaxEntries)}
+	recordTransition(r.Recorder, pr, pr.Status.Conditions, condition, corev1.EventTypeWarning, ReasonHistoryFull)
+	meta.SetStatusCondition(&pr.Status.Conditions, condition)
+	return nil
+}
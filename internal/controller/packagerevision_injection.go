@@ -0,0 +1,204 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/injection"
+)
+
+// injectableGroupKind is the kind of the in-cluster objects that can be injected, the only one the
+// operator watches and may read. Secrets in particular are never injected, since their data would
+// be stored in plain text in the package and in its repository.
+var injectableGroupKind = schema.GroupKind{Kind: "ConfigMap"}
+
+// injectConfig injects in-cluster objects into the injection points among the package resources.
+// It returns the status of every injection point, and whether any resource was changed.
+//
+// When the PackageRevision has injectors, each injection point is injected from the first unused
+// injector of the same group and kind that exists. Without injectors, an injection point is
+// injected from the object of the same kind and name in the namespace of the PackageRevision.
+// Injection points of other kinds than ConfigMaps are reported but never injected.
+func (r *PackageRevisionReconciler) injectConfig(ctx context.Context, pr *cachev1alpha1.PackageRevision,
+	nodes []*yaml.RNode) ([]cachev1alpha1.InjectionPoint, bool, error) {
+	points, err := injection.FindPoints(nodes)
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed := false
+	used := map[int]bool{}
+	statuses := make([]cachev1alpha1.InjectionPoint, 0, len(points))
+	for _, point := range points {
+		status := cachev1alpha1.InjectionPoint{
+			File:     point.File,
+			Group:    point.GroupVersionKind.Group,
+			Kind:     point.GroupVersionKind.Kind,
+			Name:     point.Name,
+			Required: point.Required,
+		}
+
+		switch gk := point.GroupVersionKind.GroupKind(); {
+		case gk == schema.GroupKind{Kind: "Secret"}:
+			status.Message = "Secrets cannot be injected, their data would be stored in plain text in the package"
+			statuses = append(statuses, status)
+			continue
+		case gk != injectableGroupKind:
+			status.Message = fmt.Sprintf("%s objects cannot be injected, only ConfigMaps can", point.GroupVersionKind.Kind)
+			statuses = append(statuses, status)
+			continue
+		}
+
+		source, err := r.findInjectionSource(ctx, pr, point, used)
+		if err != nil {
+			return nil, false, err
+		}
+		if source == nil {
+			status.Message = "no matching in-cluster object found"
+			statuses = append(statuses, status)
+			continue
+		}
+
+		injected, err := point.Inject(source)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to inject %s %q into %s: %w", source.GetKind(), source.GetName(), point.File, err)
+		}
+		changed = changed || injected
+
+		gvk := source.GroupVersionKind()
+		status.Source = &cachev1alpha1.InjectionSource{
+			InjectionSelector: cachev1alpha1.InjectionSelector{
+				Group:   gvk.Group,
+				Version: gvk.Version,
+				Kind:    gvk.Kind,
+				Name:    source.GetName(),
+			},
+			ResourceVersion: source.GetResourceVersion(),
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, changed, nil
+}
+
+// findInjectionSource returns the in-cluster object to inject into an injection point, or nil if
+// there is none. Injectors that are used are recorded in used, so that each is used only once.
+func (r *PackageRevisionReconciler) findInjectionSource(ctx context.Context, pr *cachev1alpha1.PackageRevision,
+	point *injection.Point, used map[int]bool) (*unstructured.Unstructured, error) {
+	if len(pr.Spec.Injectors) == 0 {
+		return r.getInjectionSource(ctx, pr.Namespace, point.GroupVersionKind, point.Name)
+	}
+
+	for i, injector := range pr.Spec.Injectors {
+		if used[i] {
+			continue
+		}
+		gvk := point.GroupVersionKind
+		if injector.Kind != "" {
+			gvk.Group, gvk.Kind = injector.Group, injector.Kind
+		}
+		if injector.Version != "" {
+			gvk.Version = injector.Version
+		}
+		if gvk.GroupKind() != point.GroupVersionKind.GroupKind() {
+			continue
+		}
+
+		source, err := r.getInjectionSource(ctx, pr.Namespace, gvk, injector.Name)
+		if err != nil {
+			return nil, err
+		}
+		if source != nil {
+			used[i] = true
+			return source, nil
+		}
+	}
+	return nil, nil
+}
+
+// getInjectionSource gets an in-cluster object, returning nil if it does not exist.
+func (r *PackageRevisionReconciler) getInjectionSource(ctx context.Context, namespace string,
+	gvk schema.GroupVersionKind, name string) (*unstructured.Unstructured, error) {
+	source := &unstructured.Unstructured{}
+	source.SetGroupVersionKind(gvk)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, source); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s %q for injection: %w", gvk.Kind, name, err)
+	}
+	return source, nil
+}
+
+// mapInjectionSourceToPackageRevisions returns a map function that enqueues the PackageRevisions that
+// were injected from an object of the given kind, or that could be once it exists, so that they are
+// injected again when the object changes or is created.
+func (r *PackageRevisionReconciler) mapInjectionSourceToPackageRevisions(gk schema.GroupKind) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		prs := &cachev1alpha1.PackageRevisionList{}
+		if err := r.List(ctx, prs, client.InNamespace(obj.GetNamespace())); err != nil {
+			logf.FromContext(ctx).Error(err, "Failed to list PackageRevisions")
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for i := range prs.Items {
+			if injectsFrom(&prs.Items[i], gk, obj.GetName()) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&prs.Items[i])})
+			}
+		}
+		return requests
+	}
+}
+
+// injectsFrom returns true if the PackageRevision was injected from the object of the kind and
+// name, or if one of its injection points of the kind is not injected and would be injected from
+// the object, by its name or by one of the injectors of the PackageRevision.
+func injectsFrom(pr *cachev1alpha1.PackageRevision, gk schema.GroupKind, name string) bool {
+	for _, point := range pr.Status.InjectionPoints {
+		if point.Source != nil {
+			if point.Source.Name == name && point.Source.Group == gk.Group && point.Source.Kind == gk.Kind {
+				return true
+			}
+			continue
+		}
+		if point.Group != gk.Group || point.Kind != gk.Kind {
+			continue
+		}
+		if len(pr.Spec.Injectors) == 0 && point.Name == name {
+			return true
+		}
+		for _, injector := range pr.Spec.Injectors {
+			if injector.Name == name && (injector.Kind == "" || injector.Group == gk.Group && injector.Kind == gk.Kind) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/diff"
+	"github.com/liamfallon/porch-operator/internal/merge"
+)
+
+// upgradePackage returns the local package contents with the changes made between the old and
+// new upstream package revisions applied by the strategy of the task, and locks the package to
+// the new upstream.
+func (r *PackageRevisionReconciler) upgradePackage(ctx context.Context, pr *cachev1alpha1.PackageRevision,
+	spec *cachev1alpha1.PackageUpgradeTaskSpec) (map[string]string, error) {
+	if spec == nil || spec.OldUpstream.Name == "" || spec.NewUpstream.Name == "" || spec.LocalPackageRevisionRef.Name == "" {
+		return nil, fmt.Errorf("oldUpstreamRef, newUpstreamRef and localPackageRevisionRef are required")
+	}
+
+	var base, local, upstream map[string]string
+	var lock *cachev1alpha1.UpstreamLock
+	for _, ref := range []struct {
+		name     string
+		contents *map[string]string
+	}{
+		{spec.OldUpstream.Name, &base},
+		{spec.LocalPackageRevisionRef.Name, &local},
+		{spec.NewUpstream.Name, &upstream},
+	} {
+		source := &cachev1alpha1.PackageRevision{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: ref.name}, source); err != nil {
+			return nil, fmt.Errorf("failed to get PackageRevision %q: %w", ref.name, err)
+		}
+		if ref.contents == &upstream {
+			if source.Spec.Lifecycle != cachev1alpha1.PackageRevisionLifecyclePublished {
+				return nil, fmt.Errorf("upstream PackageRevision %q is not published", source.Name)
+			}
+			var err error
+			if lock, err = r.upstreamLock(ctx, source); err != nil {
+				return nil, err
+			}
+		}
+		contents, err := r.readContents(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		*ref.contents = contents
+	}
+
+	// The upstream revisions are compared with the local package under its own name.
+	var err error
+	name := path.Base(pr.Spec.PackageName)
+	if base, err = renamePackage(base, name); err != nil {
+		return nil, err
+	}
+	if upstream, err = renamePackage(upstream, name); err != nil {
+		return nil, err
+	}
+	pr.Status.UpstreamLock = lock
+
+	switch spec.Strategy {
+	case "", cachev1alpha1.ResourceMerge:
+		merged, _, err := merge.ThreeWay(base, local, upstream)
+		return merged, err
+	case cachev1alpha1.FastForward:
+		resources, files, err := diff.Contents(base, local)
+		if err != nil {
+			return nil, err
+		}
+		if len(resources) > 0 || len(files) > 0 {
+			return nil, fmt.Errorf("local PackageRevision %q is modified, so cannot be fast-forwarded", spec.LocalPackageRevisionRef.Name)
+		}
+		return upstream, nil
+	case cachev1alpha1.ForceDeleteReplace:
+		return upstream, nil
+	case cachev1alpha1.CopyMerge:
+		merged := maps.Clone(local)
+		maps.Copy(merged, upstream)
+		return merged, nil
+	default:
+		return nil, fmt.Errorf("merge strategy %q is not supported", spec.Strategy)
+	}
+}
+
+// findMergeConflicts records the merge conflicts left in the package contents in the status of
+// the PackageRevision, and returns true if there are any.
+func findMergeConflicts(pr *cachev1alpha1.PackageRevision, contents map[string]string) bool {
+	conflicts, err := merge.Conflicts(contents)
+	switch {
+	case err != nil:
+		pr.Status.MergeConflicts = nil
+		meta.SetStatusCondition(&pr.Status.Conditions, metav1.Condition{Type: typeMergeConflictPackageRevision,
+			Status: metav1.ConditionTrue, Reason: "InvalidConflicts", Message: err.Error()})
+		return true
+	case len(conflicts) > 0:
+		pr.Status.MergeConflicts = conflicts
+		meta.SetStatusCondition(&pr.Status.Conditions, metav1.Condition{Type: typeMergeConflictPackageRevision,
+			Status: metav1.ConditionTrue, Reason: "Conflicts",
+			Message: fmt.Sprintf("%d merge conflicts must be resolved", len(conflicts))})
+		return true
+	}
+
+	pr.Status.MergeConflicts = nil
+	if meta.FindStatusCondition(pr.Status.Conditions, typeMergeConflictPackageRevision) != nil {
+		meta.SetStatusCondition(&pr.Status.Conditions, metav1.Condition{Type: typeMergeConflictPackageRevision,
+			Status: metav1.ConditionFalse, Reason: "Resolved", Message: "all merge conflicts are resolved"})
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/diff"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+// PreviewPath is the path the preview API is served on.
+const PreviewPath = "/preview"
+
+// maxPreviewRequestSize is the maximum size of the body of a preview request, in bytes.
+const maxPreviewRequestSize = 8 << 20
+
+var (
+	// errPreviewNotFound is returned when the PackageRevision to preview does not exist.
+	errPreviewNotFound = errors.New("package revision not found")
+	// errPreviewInvalid is returned when the preview request changes what cannot be previewed.
+	errPreviewInvalid = errors.New("invalid preview request")
+)
+
+// PreviewHandler serves previews of PackageRevisions. It reads a PreviewRequest from the body of
+// a POST request, and writes a PreviewResponse.
+type PreviewHandler struct {
+	Reconciler *PackageRevisionReconciler
+}
+
+var _ http.Handler = &PreviewHandler{}
+
+// ServeHTTP serves a preview request.
+func (h *PreviewHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	request := &cachev1alpha1.PreviewRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxPreviewRequestSize)).Decode(request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid preview request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if request.Namespace == "" || request.Name == "" {
+		http.Error(w, "namespace and name are required", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.Reconciler.Preview(req.Context(), request)
+	switch {
+	case errors.Is(err, errPreviewNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errPreviewInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		logf.FromContext(req.Context()).Error(err, "Failed to preview PackageRevision",
+			"namespace", request.Namespace, "name", request.Name)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Preview renders the contents a PackageRevision would have with the changes in the request,
+// without storing anything. Failures to produce or render the contents are reported in the
+// response rather than returned. The functions run under the FunctionPolicies of the stored
+// PackageRevision, and the request cannot change the package, repository or workspace it is of.
+func (r *PackageRevisionReconciler) Preview(ctx context.Context,
+	request *cachev1alpha1.PreviewRequest) (*cachev1alpha1.PreviewResponse, error) {
+	pr := &cachev1alpha1.PackageRevision{}
+	key := types.NamespacedName{Namespace: request.Namespace, Name: request.Name}
+	if err := r.Get(ctx, key, pr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", errPreviewNotFound, key)
+		}
+		return nil, err
+	}
+	current := map[string]string{}
+	prr := &cachev1alpha1.PackageRevisionResources{}
+	if err := r.Get(ctx, key, prr); err == nil {
+		current = prr.Spec.Resources
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	renderer, err := r.rendererFor(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &cachev1alpha1.PreviewResponse{}
+	contents := maps.Clone(current)
+	if request.Spec != nil || prr.Name == "" {
+		if request.Spec != nil {
+			if err := validatePreviewSpec(pr, request.Spec); err != nil {
+				return nil, err
+			}
+			pr = pr.DeepCopy()
+			pr.Spec = *request.Spec
+		}
+		var err error
+		if contents, err = r.applyTasks(ctx, pr, nil); err != nil {
+			response.Error = fmt.Sprintf("failed to apply tasks: %v", err)
+			return response, nil
+		}
+	}
+	maps.Copy(contents, request.Resources)
+	for _, path := range request.DeletedResources {
+		delete(contents, path)
+	}
+
+	nodes, err := kpt.ReadResources(contents)
+	if err != nil {
+		response.Error = err.Error()
+		return response, nil
+	}
+	_, injected, err := r.injectConfig(ctx, pr, nodes)
+	if err != nil {
+		response.Error = fmt.Sprintf("failed to inject config: %v", err)
+		return response, nil
+	}
+	rendered, results, err := renderer.Render(ctx, nodes)
+	response.RenderResults = functionResults(results)
+	if err != nil {
+		response.Error = err.Error()
+		return response, nil
+	}
+
+	response.Resources = contents
+	if injected || len(results) > 0 {
+		if response.Resources, err = kpt.WriteResources(contents, rendered); err != nil {
+			return nil, err
+		}
+	}
+	if response.Diff, err = diff.Files(current, response.Resources); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// validatePreviewSpec refuses a previewed spec that changes the package, repository or workspace
+// of the PackageRevision.
+func validatePreviewSpec(pr *cachev1alpha1.PackageRevision, spec *cachev1alpha1.PackageRevisionSpec) error {
+	for field, changed := range map[string]bool{
+		"packageName":   spec.PackageName != pr.Spec.PackageName,
+		"repository":    spec.RepositoryName != pr.Spec.RepositoryName,
+		"workspaceName": spec.WorkspaceName != pr.Spec.WorkspaceName,
+	} {
+		if changed {
+			return fmt.Errorf("%w: %s cannot be changed", errPreviewInvalid, field)
+		}
+	}
+	return nil
+}
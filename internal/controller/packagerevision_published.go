@@ -0,0 +1,85 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=repositories,verbs=get;list;watch
+
+// cachedContents returns the contents of a Published PackageRevision discovered in a git
+// Repository from the package cache, or false if they are not cached. The git repository is never
+// read, so Published revisions are reconciled without touching the network.
+func (r *PackageRevisionReconciler) cachedContents(ctx context.Context, pr *cachev1alpha1.PackageRevision) (map[string]string, bool, error) {
+	commit, repoName := pr.Annotations[CommitAnnotation], pr.Labels[RepositoryLabel]
+	if r.Packages == nil || pr.Spec.Lifecycle != cachev1alpha1.PackageRevisionLifecyclePublished || commit == "" || repoName == "" {
+		return nil, false, nil
+	}
+	repo := &cachev1alpha1.Repository{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: repoName}, repo); err != nil {
+		return nil, false, client.IgnoreNotFound(err)
+	}
+	if repo.Spec.Git == nil || !metav1.IsControlledBy(pr, repo) {
+		return nil, false, nil
+	}
+	contents, found := r.Packages.Get(packageKey(repo, pr.Spec.PackageName, commit))
+	return contents, found, nil
+}
+
+// restoreContents recreates the contents of a Published PackageRevision discovered in a git
+// Repository from the package cache, when they have been deleted. Contents that are not cached
+// are restored by the next sync of the Repository.
+func (r *PackageRevisionReconciler) restoreContents(ctx context.Context, pr *cachev1alpha1.PackageRevision) error {
+	prr := &cachev1alpha1.PackageRevisionResources{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, prr)
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	contents, found, err := r.cachedContents(ctx, pr)
+	if err != nil || !found {
+		return err
+	}
+
+	logf.FromContext(ctx).Info("Restoring contents of Published PackageRevision from the package cache")
+	prr = &cachev1alpha1.PackageRevisionResources{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pr.Name,
+			Namespace:   pr.Namespace,
+			Annotations: map[string]string{CommitAnnotation: pr.Annotations[CommitAnnotation]},
+		},
+		Spec: cachev1alpha1.PackageRevisionResourcesSpec{
+			PackageName:    pr.Spec.PackageName,
+			RepositoryName: pr.Spec.RepositoryName,
+			WorkspaceName:  pr.Spec.WorkspaceName,
+			Resources:      contents,
+		},
+	}
+	if err := controllerutil.SetControllerReference(pr, prr, r.Scheme); err != nil {
+		return err
+	}
+	return client.IgnoreAlreadyExists(r.Create(ctx, prr))
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/policy"
+)
+
+// maxResultItems is the maximum number of results of a function recorded in the status of a
+// PackageRevision, to keep the status within the size limits of the API server.
+const maxResultItems = 25
+
+// renderContents runs the Kptfile pipelines over the package resources, and records the outcome
+// in the status of the PackageRevision. It returns the rendered resources, and true if any
+// function was run and the package rendered successfully.
+//
+// Only the functions allowed by the FunctionPolicies that apply to the package are run. A
+// function that is not allowed fails rendering. Failures are reported with an event when they
+// are new.
+func (r *PackageRevisionReconciler) renderContents(ctx context.Context, pr *cachev1alpha1.PackageRevision,
+	nodes []*yaml.RNode) ([]*yaml.RNode, bool, error) {
+	renderer, err := r.rendererFor(ctx, pr)
+	if err != nil {
+		return nil, false, err
+	}
+	start := time.Now()
+	rendered, results, err := renderer.Render(ctx, nodes)
+	renderDuration.Observe(time.Since(start).Seconds())
+	pr.Status.RenderResults = functionResults(results)
+	if err != nil {
+		renderFailures.Inc()
+		logf.FromContext(ctx).Info("Failed to render package", "error", err.Error())
+		condition := metav1.Condition{Type: typeRenderedPackageRevision,
+			Status: metav1.ConditionFalse, Reason: ReasonRenderFailed, Message: err.Error()}
+		if errors.Is(err, policy.ErrFunctionNotAllowed) {
+			condition.Reason = ReasonFunctionNotAllowed
+		}
+		recordTransition(r.Recorder, pr, pr.Status.Conditions, condition, corev1.EventTypeWarning, condition.Reason)
+		meta.SetStatusCondition(&pr.Status.Conditions, condition)
+		return nil, false, nil
+	}
+
+	meta.SetStatusCondition(&pr.Status.Conditions, metav1.Condition{Type: typeRenderedPackageRevision,
+		Status: metav1.ConditionTrue, Reason: "Rendered", Message: fmt.Sprintf("%d functions run", len(results))})
+	return rendered, len(results) > 0, nil
+}
+
+// rendererFor returns a Renderer for the package of the PackageRevision, which only runs the
+// functions allowed by the FunctionPolicies that apply to the package.
+func (r *PackageRevisionReconciler) rendererFor(ctx context.Context, pr *cachev1alpha1.PackageRevision) (*fn.Renderer, error) {
+	policies := &cachev1alpha1.FunctionPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		return nil, err
+	}
+	runner := &policy.Runner{Runner: r.FunctionRunner}
+	for i := range policies.Items {
+		if policy.AppliesTo(&policies.Items[i], pr.Namespace, pr.Spec.RepositoryName) {
+			runner.Policies = append(runner.Policies, policies.Items[i])
+		}
+	}
+	return &fn.Renderer{Runner: runner}, nil
+}
+
+// mapFunctionPolicyToPackageRevisions enqueues the drafts a FunctionPolicy applies to, so that
+// they are rendered again when the policy changes.
+func (r *PackageRevisionReconciler) mapFunctionPolicyToPackageRevisions(ctx context.Context,
+	obj client.Object) []reconcile.Request {
+	functionPolicy, ok := obj.(*cachev1alpha1.FunctionPolicy)
+	if !ok {
+		return nil
+	}
+	prs := &cachev1alpha1.PackageRevisionList{}
+	if err := r.List(ctx, prs); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list PackageRevisions")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range prs.Items {
+		pr := &prs.Items[i]
+		if pr.Spec.Lifecycle == cachev1alpha1.PackageRevisionLifecycleDraft &&
+			policy.AppliesTo(functionPolicy, pr.Namespace, pr.Spec.RepositoryName) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pr)})
+		}
+	}
+	return requests
+}
+
+// functionResults converts the results of rendering to their representation in the API.
+func functionResults(results []*fn.Result) []cachev1alpha1.FunctionResult {
+	var converted []cachev1alpha1.FunctionResult
+	for _, result := range results {
+		functionResult := cachev1alpha1.FunctionResult{
+			Image:   result.Function.Image,
+			Name:    result.Function.Name,
+			Package: result.Package,
+			Stage:   string(result.Stage),
+		}
+		if result.Err != nil {
+			functionResult.Error = result.Err.Error()
+		}
+		for _, item := range result.Results {
+			if len(functionResult.Results) == maxResultItems {
+				break
+			}
+			resultItem := cachev1alpha1.ResultItem{
+				Message:  item.Message,
+				Severity: string(item.Severity),
+			}
+			if item.ResourceRef != nil {
+				resultItem.ResourceRef = &cachev1alpha1.ResultResourceRef{
+					APIVersion: item.ResourceRef.APIVersion,
+					Kind:       item.ResourceRef.Kind,
+					Name:       item.ResourceRef.Name,
+					Namespace:  item.ResourceRef.Namespace,
+				}
+			}
+			if item.Field != nil {
+				resultItem.Field = item.Field.Path
+			}
+			if item.File != nil {
+				resultItem.File = item.File.Path
+			}
+			functionResult.Results = append(functionResult.Results, resultItem)
+		}
+		converted = append(converted, functionResult)
+	}
+	return converted
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/git"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+	"github.com/liamfallon/porch-operator/internal/tracing"
+)
+
+// applyTasks produces the initial contents of a draft package by applying its tasks in order, and
+// locks the draft to the upstream it was last cloned from or upgraded to. The start of every task
+// is recorded as an event, unless the recorder is nil.
+func (r *PackageRevisionReconciler) applyTasks(ctx context.Context, pr *cachev1alpha1.PackageRevision,
+	recorder record.EventRecorder) (map[string]string, error) {
+	contents := map[string]string{}
+	pr.Status.UpstreamLock = nil
+	for i, task := range pr.Spec.Tasks {
+		var err error
+		start := time.Now()
+		ctx, span := tracing.Start(ctx, fmt.Sprintf("Apply %s task", task.Type))
+		if span.IsRecording() {
+			span.SetAttributes(tracing.TaskTypeKey.String(string(task.Type)), tracing.TaskIndexKey.Int(i))
+		}
+		eventf(recorder, pr, corev1.EventTypeNormal, ReasonTaskStarted, "Applying task %d (%s)", i, task.Type)
+		switch task.Type {
+		case cachev1alpha1.TaskTypeInit:
+			contents, err = initPackage(pr, contents, task.Init)
+		case cachev1alpha1.TaskTypeClone:
+			contents, err = r.clonePackage(ctx, pr, task.Clone)
+		case cachev1alpha1.TaskTypeEdit:
+			contents, err = r.editPackage(ctx, pr, task.Edit)
+		case cachev1alpha1.TaskTypeUpgrade:
+			contents, err = r.upgradePackage(ctx, pr, task.Upgrade)
+		default:
+			err = fmt.Errorf("task type is not supported")
+		}
+		tracing.End(span, err)
+		taskDuration.WithLabelValues(string(task.Type)).Observe(time.Since(start).Seconds())
+		if err != nil {
+			taskFailures.WithLabelValues(string(task.Type)).Inc()
+			return nil, fmt.Errorf("task %d (%s) failed: %w", i, task.Type, err)
+		}
+	}
+	return contents, nil
+}
+
+// initPackage adds a Kptfile for a new package, or a new subpackage, to the package contents.
+func initPackage(pr *cachev1alpha1.PackageRevision, contents map[string]string,
+	spec *cachev1alpha1.PackageInitTaskSpec) (map[string]string, error) {
+	if spec == nil {
+		spec = &cachev1alpha1.PackageInitTaskSpec{}
+	}
+
+	name := path.Base(pr.Spec.PackageName)
+	kptfilePath := kpt.KptfileName
+	if spec.Subpackage != "" {
+		name = path.Base(spec.Subpackage)
+		kptfilePath = path.Join(spec.Subpackage, kpt.KptfileName)
+	}
+	if _, found := contents[kptfilePath]; found {
+		return nil, fmt.Errorf("%s already exists", kptfilePath)
+	}
+
+	kptfile, err := kpt.NewKptfile(name, &kpt.PackageInfo{
+		Site:        spec.Site,
+		Description: spec.Description,
+		Keywords:    spec.Keywords,
+	})
+	if err != nil {
+		return nil, err
+	}
+	initialized := maps.Clone(contents)
+	initialized[kptfilePath] = kptfile
+	return initialized, nil
+}
+
+// clonePackage returns the contents of the upstream package, renamed for the cloning package.
+func (r *PackageRevisionReconciler) clonePackage(ctx context.Context, pr *cachev1alpha1.PackageRevision,
+	spec *cachev1alpha1.PackageCloneTaskSpec) (map[string]string, error) {
+	if spec == nil || spec.Upstream.UpstreamRef == nil {
+		return nil, fmt.Errorf("only cloning from an upstreamRef is supported")
+	}
+
+	upstream := &cachev1alpha1.PackageRevision{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: spec.Upstream.UpstreamRef.Name}, upstream); err != nil {
+		return nil, fmt.Errorf("failed to get upstream PackageRevision %q: %w", spec.Upstream.UpstreamRef.Name, err)
+	}
+	if upstream.Spec.Lifecycle != cachev1alpha1.PackageRevisionLifecyclePublished {
+		return nil, fmt.Errorf("upstream PackageRevision %q is not published", upstream.Name)
+	}
+
+	contents, err := r.readContents(ctx, upstream)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Status.UpstreamLock, err = r.upstreamLock(ctx, upstream); err != nil {
+		return nil, err
+	}
+	return renamePackage(contents, path.Base(pr.Spec.PackageName))
+}
+
+// upstreamLock returns the lock of a package on an upstream PackageRevision discovered in a git
+// Repository: the ref and the commit its contents were read from. It returns nil for upstreams that
+// were not discovered in git.
+func (r *PackageRevisionReconciler) upstreamLock(ctx context.Context,
+	upstream *cachev1alpha1.PackageRevision) (*cachev1alpha1.UpstreamLock, error) {
+	commit, repoName := upstream.Annotations[CommitAnnotation], upstream.Labels[RepositoryLabel]
+	if commit == "" || repoName == "" {
+		return nil, nil
+	}
+	repo := &cachev1alpha1.Repository{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: upstream.Namespace, Name: repoName}, repo); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if repo.Spec.Git == nil {
+		return nil, nil
+	}
+	return &cachev1alpha1.UpstreamLock{
+		Type: cachev1alpha1.OriginTypeGit,
+		Git: &cachev1alpha1.GitLock{
+			Repo:      repo.Spec.Git.Repo,
+			Directory: git.PackagePath(repo.Spec.Git.Directory, upstream.Spec.PackageName),
+			Ref:       upstream.Annotations[GitRefAnnotation],
+			Commit:    commit,
+		},
+	}, nil
+}
+
+// editPackage returns a copy of the contents of the source package revision.
+func (r *PackageRevisionReconciler) editPackage(ctx context.Context, pr *cachev1alpha1.PackageRevision,
+	spec *cachev1alpha1.PackageEditTaskSpec) (map[string]string, error) {
+	if spec == nil || spec.Source == nil {
+		return nil, fmt.Errorf("sourceRef is required")
+	}
+
+	source := &cachev1alpha1.PackageRevision{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: spec.Source.Name}, source); err != nil {
+		return nil, fmt.Errorf("failed to get source PackageRevision %q: %w", spec.Source.Name, err)
+	}
+	if source.Spec.RepositoryName != pr.Spec.RepositoryName || source.Spec.PackageName != pr.Spec.PackageName {
+		return nil, fmt.Errorf("source PackageRevision %q is not a revision of package %q", source.Name, pr.Spec.PackageName)
+	}
+	return r.readContents(ctx, source)
+}
+
+// readContents returns the contents of a package revision. The contents of a discovered Published
+// revision that have been deleted are read from the package cache.
+func (r *PackageRevisionReconciler) readContents(ctx context.Context, pr *cachev1alpha1.PackageRevision) (map[string]string, error) {
+	prr := &cachev1alpha1.PackageRevisionResources{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, prr); err != nil {
+		if apierrors.IsNotFound(err) {
+			if contents, found, cacheErr := r.cachedContents(ctx, pr); cacheErr == nil && found {
+				return contents, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to read contents of PackageRevision %q: %w", pr.Name, err)
+	}
+	return maps.Clone(prr.Spec.Resources), nil
+}
+
+// renamePackage sets the name in the root Kptfile of the package contents.
+func renamePackage(contents map[string]string, name string) (map[string]string, error) {
+	nodes, err := kpt.ReadResources(contents)
+	if err != nil {
+		return nil, err
+	}
+	kptfile := kpt.FindKptfile(nodes)
+	if kptfile == nil {
+		return contents, nil
+	}
+	if err := kptfile.PipeE(yaml.SetK8sName(name)); err != nil {
+		return nil, err
+	}
+	return kpt.WriteResources(contents, nodes)
+}
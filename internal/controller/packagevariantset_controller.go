@@ -0,0 +1,607 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/revisions"
+	"github.com/liamfallon/porch-operator/internal/shard"
+	"github.com/liamfallon/porch-operator/internal/tracing"
+)
+
+// PackageVariantSetLabel is set on every downstream PackageRevision generated by a PackageVariantSet,
+// its value is the name of the PackageVariantSet.
+const PackageVariantSetLabel = "porch.kpt.dev/packagevariantset"
+
+// managedMetadataAnnotation records, on every downstream PackageRevision, the keys of the labels
+// and annotations its PackageVariantSet sets from its template, so that the keys dropped from the
+// template are removed from the downstream.
+const managedMetadataAnnotation = "porch.kpt.dev/packagevariantset-managed"
+
+// managedMetadata is the value of the managedMetadataAnnotation.
+type managedMetadata struct {
+	Labels      []string `json:"labels,omitempty"`
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// objectSelectorAggregationLabel labels the ClusterRoles that grant the operator the kinds that
+// objectSelector targets select, they are aggregated into the ClusterRole of the operator.
+const objectSelectorAggregationLabel = "porch.kpt.dev/aggregate-to-object-selector"
+
+const (
+	// typeReadyPackageVariantSet represents whether the downstream package variants match the targets
+	typeReadyPackageVariantSet = "Ready"
+
+	// objectSelectorResyncPeriod is how often a PackageVariantSet with object selectors is re-evaluated.
+	// Arbitrary target kinds are not watched, so changes to them are picked up on this period.
+	objectSelectorResyncPeriod = 2 * time.Minute
+
+	// downstreamConflictRetryPeriod is how long to wait before creating again the downstreams whose
+	// names are taken by PackageRevisions the PackageVariantSet does not own.
+	downstreamConflictRetryPeriod = 30 * time.Second
+)
+
+// PackageVariantSetReconciler reconciles a PackageVariantSet object
+type PackageVariantSetReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// Shards are the shards of the PackageVariantSets this replica reconciles. They are spread
+	// over the shards by their own name, as they span Repositories. If nil, every
+	// PackageVariantSet is reconciled.
+	Shards *shard.Shards
+}
+
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagevariantsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagevariantsets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagevariantsets/finalizers,verbs=update
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=repositories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagerevisions,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile generates one downstream PackageRevision per target of the PackageVariantSet, and
+// prunes the downstream PackageRevisions whose targets have disappeared.
+func (r *PackageVariantSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, span := tracing.Start(ctx, "Reconcile PackageVariantSet")
+	defer func() { tracing.End(span, err) }()
+	log := logf.FromContext(ctx)
+
+	pvs := &cachev1alpha1.PackageVariantSet{}
+	if err := r.Get(ctx, req.NamespacedName, pvs); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("PackageVariantSet resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get PackageVariantSet")
+		return ctrl.Result{}, err
+	}
+	if span.IsRecording() {
+		span.SetAttributes(tracing.NamespaceKey.String(pvs.Namespace), tracing.PackageVariantSetKey.String(pvs.Name))
+	}
+
+	// Downstream PackageRevisions are owned by the PackageVariantSet, so the garbage collector
+	// takes care of them when it is deleted.
+	if pvs.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	unlock, owned := r.Shards.Lock(r.shardOf(pvs))
+	if !owned {
+		return ctrl.Result{}, nil
+	}
+	defer unlock()
+
+	var result ctrl.Result
+	for _, target := range pvs.Spec.Targets {
+		if target.ObjectSelector != nil {
+			result.RequeueAfter = objectSelectorResyncPeriod
+		}
+	}
+
+	upstream := &cachev1alpha1.PackageRevision{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pvs.Namespace, Name: pvs.Spec.Upstream.Name}, upstream); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "Failed to get upstream PackageRevision")
+			return ctrl.Result{}, err
+		}
+		return result, r.updateStatus(ctx, pvs, nil, metav1.ConditionFalse, "UpstreamNotFound",
+			fmt.Sprintf("upstream PackageRevision %q not found", pvs.Spec.Upstream.Name))
+	}
+
+	desired, err := r.desiredDownstreams(ctx, pvs, upstream)
+	if err != nil {
+		log.Error(err, "Failed to evaluate PackageVariantSet targets")
+		return result, r.updateStatus(ctx, pvs, nil, metav1.ConditionFalse, "InvalidTargets", err.Error())
+	}
+
+	existing := &cachev1alpha1.PackageRevisionList{}
+	if err := r.List(ctx, existing, client.InNamespace(pvs.Namespace),
+		client.MatchingLabels{PackageVariantSetLabel: pvs.Name}); err != nil {
+		log.Error(err, "Failed to list downstream PackageRevisions")
+		return ctrl.Result{}, err
+	}
+
+	for i := range existing.Items {
+		current := &existing.Items[i]
+		if !metav1.IsControlledBy(current, pvs) {
+			continue
+		}
+		if want, ok := desired[current.Name]; ok {
+			if err := r.updateDownstream(ctx, current, want); err != nil {
+				log.Error(err, "Failed to update downstream PackageRevision", "name", current.Name)
+				return ctrl.Result{}, err
+			}
+			delete(desired, current.Name)
+			continue
+		}
+		if err := r.pruneDownstream(ctx, current); err != nil {
+			log.Error(err, "Failed to prune downstream PackageRevision", "name", current.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	var conflicts []string
+	for _, name := range slices.Sorted(maps.Keys(desired)) {
+		log.Info("Creating downstream PackageRevision", "name", name)
+		if err := r.Create(ctx, desired[name]); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				log.Info("Downstream PackageRevision already exists and is not owned by this PackageVariantSet",
+					"name", name)
+				conflicts = append(conflicts, name)
+				continue
+			}
+			log.Error(err, "Failed to create downstream PackageRevision", "name", name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if len(conflicts) > 0 {
+		if result.RequeueAfter == 0 || result.RequeueAfter > downstreamConflictRetryPeriod {
+			result.RequeueAfter = downstreamConflictRetryPeriod
+		}
+		return result, r.updateStatus(ctx, pvs, nil, metav1.ConditionFalse, "DownstreamConflict",
+			fmt.Sprintf("PackageRevisions %s already exist and are not owned by this PackageVariantSet",
+				strings.Join(conflicts, ", ")))
+	}
+
+	targets, err := r.listDownstreamNames(ctx, pvs)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return result, r.updateStatus(ctx, pvs, targets, metav1.ConditionTrue, "Reconciled",
+		fmt.Sprintf("%d downstream package variants reconciled", len(targets)))
+}
+
+// desiredDownstreams evaluates the targets of the PackageVariantSet into the set of downstream
+// PackageRevisions that should exist, keyed by object name.
+func (r *PackageVariantSetReconciler) desiredDownstreams(ctx context.Context, pvs *cachev1alpha1.PackageVariantSet,
+	upstream *cachev1alpha1.PackageRevision) (map[string]*cachev1alpha1.PackageRevision, error) {
+	upstreamData, err := runtime.DefaultUnstructuredConverter.ToUnstructured(upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := map[string]*cachev1alpha1.PackageRevision{}
+	add := func(pr *cachev1alpha1.PackageRevision) error {
+		if _, found := desired[pr.Name]; found {
+			return fmt.Errorf("more than one target generates downstream PackageRevision %q", pr.Name)
+		}
+		desired[pr.Name] = pr
+		return nil
+	}
+
+	for i, target := range pvs.Spec.Targets {
+		selectors := 0
+		for _, set := range []bool{len(target.Repositories) > 0, target.RepositorySelector != nil, target.ObjectSelector != nil} {
+			if set {
+				selectors++
+			}
+		}
+		if selectors != 1 {
+			return nil, fmt.Errorf("target %d must specify exactly one of repositories, repositorySelector or objectSelector", i)
+		}
+
+		tmpl := target.Template
+		if tmpl == nil {
+			tmpl = &cachev1alpha1.PackageVariantTemplate{}
+		}
+
+		switch {
+		case len(target.Repositories) > 0:
+			for _, repoTarget := range target.Repositories {
+				repo := &cachev1alpha1.Repository{}
+				if err := r.Get(ctx, types.NamespacedName{Namespace: pvs.Namespace, Name: repoTarget.Name}, repo); err != nil {
+					return nil, fmt.Errorf("target %d: failed to get Repository %q: %w", i, repoTarget.Name, err)
+				}
+				repoData, err := runtime.DefaultUnstructuredConverter.ToUnstructured(repo)
+				if err != nil {
+					return nil, err
+				}
+				data := templateData{"target": repoData, "repository": repoData, "upstream": upstreamData}
+
+				packageNames := repoTarget.PackageNames
+				if len(packageNames) == 0 {
+					packageNames = []string{""}
+				}
+				for _, packageName := range packageNames {
+					pr, err := r.newDownstream(pvs, upstream, tmpl, data, repo.Name, packageName)
+					if err != nil {
+						return nil, fmt.Errorf("target %d: %w", i, err)
+					}
+					if err := add(pr); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+		case target.RepositorySelector != nil:
+			selector, err := metav1.LabelSelectorAsSelector(target.RepositorySelector)
+			if err != nil {
+				return nil, fmt.Errorf("target %d: invalid repositorySelector: %w", i, err)
+			}
+			repos := &cachev1alpha1.RepositoryList{}
+			if err := r.List(ctx, repos, client.InNamespace(pvs.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+				return nil, fmt.Errorf("target %d: failed to list Repositories: %w", i, err)
+			}
+			for j := range repos.Items {
+				repoData, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&repos.Items[j])
+				if err != nil {
+					return nil, err
+				}
+				data := templateData{"target": repoData, "repository": repoData, "upstream": upstreamData}
+				pr, err := r.newDownstream(pvs, upstream, tmpl, data, repos.Items[j].Name, "")
+				if err != nil {
+					return nil, fmt.Errorf("target %d: %w", i, err)
+				}
+				if err := add(pr); err != nil {
+					return nil, err
+				}
+			}
+
+		case target.ObjectSelector != nil:
+			if tmpl.Repository == "" {
+				return nil, fmt.Errorf("target %d: template.repository is required for objectSelector targets", i)
+			}
+			objects, err := r.listSelectedObjects(ctx, pvs.Namespace, target.ObjectSelector)
+			if err != nil {
+				return nil, fmt.Errorf("target %d: %w", i, err)
+			}
+			for j := range objects.Items {
+				data := templateData{"target": objects.Items[j].Object, "upstream": upstreamData}
+				repoName, err := data.expand("repository", tmpl.Repository)
+				if err != nil {
+					return nil, fmt.Errorf("target %d: %w", i, err)
+				}
+				repo := &cachev1alpha1.Repository{}
+				if err := r.Get(ctx, types.NamespacedName{Namespace: pvs.Namespace, Name: repoName}, repo); err != nil {
+					return nil, fmt.Errorf("target %d: failed to get Repository %q for %s %q: %w",
+						i, repoName, objects.Items[j].GetKind(), objects.Items[j].GetName(), err)
+				}
+				if data["repository"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(repo); err != nil {
+					return nil, err
+				}
+				pr, err := r.newDownstream(pvs, upstream, tmpl, data, repoName, "")
+				if err != nil {
+					return nil, fmt.Errorf("target %d: %w", i, err)
+				}
+				if err := add(pr); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return desired, nil
+}
+
+// listSelectedObjects lists the objects matching an ObjectSelector in the given namespace. The
+// operator may only list the kinds its ClusterRoles grant, ConfigMaps and the porch.kpt.dev kinds
+// by default; other kinds are granted by ClusterRoles aggregated into the object selector role.
+func (r *PackageVariantSetReconciler) listSelectedObjects(ctx context.Context, namespace string,
+	objectSelector *cachev1alpha1.ObjectSelector) (*unstructured.UnstructuredList, error) {
+	gv, err := schema.ParseGroupVersion(objectSelector.APIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid objectSelector apiVersion %q: %w", objectSelector.APIVersion, err)
+	}
+	selector := labels.Everything()
+	if objectSelector.Selector != nil {
+		if selector, err = metav1.LabelSelectorAsSelector(objectSelector.Selector); err != nil {
+			return nil, fmt.Errorf("invalid objectSelector selector: %w", err)
+		}
+	}
+
+	objects := &unstructured.UnstructuredList{}
+	objects.SetGroupVersionKind(gv.WithKind(objectSelector.Kind + "List"))
+	if err := r.List(ctx, objects, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		if apierrors.IsForbidden(err) {
+			return nil, fmt.Errorf("the operator may not list %s objects, grant it with a ClusterRole labeled %s=true: %w",
+				objectSelector.Kind, objectSelectorAggregationLabel, err)
+		}
+		return nil, fmt.Errorf("failed to list %s objects: %w", objectSelector.Kind, err)
+	}
+	return objects, nil
+}
+
+// newDownstream generates the downstream PackageRevision for a single target. If repoName or
+// packageName are empty, they are taken from the template.
+func (r *PackageVariantSetReconciler) newDownstream(pvs *cachev1alpha1.PackageVariantSet, upstream *cachev1alpha1.PackageRevision,
+	tmpl *cachev1alpha1.PackageVariantTemplate, data templateData, repoName, packageName string) (*cachev1alpha1.PackageRevision, error) {
+	var err error
+	if repoName == "" {
+		if repoName, err = data.expand("repository", tmpl.Repository); err != nil {
+			return nil, err
+		}
+	}
+	if packageName == "" {
+		if packageName, err = data.expand("packageName", tmpl.PackageName); err != nil {
+			return nil, err
+		}
+	}
+	if packageName == "" {
+		packageName = upstream.Spec.PackageName
+	}
+	workspaceName, err := data.expand("workspaceName", tmpl.WorkspaceName)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceName == "" {
+		workspaceName = pvs.Name
+	}
+
+	downstreamLabels, err := data.expandMap("labels", tmpl.Labels)
+	if err != nil {
+		return nil, err
+	}
+	annotations, err := data.expandMap("annotations", tmpl.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	managed, err := json.Marshal(managedMetadata{
+		Labels:      slices.Sorted(maps.Keys(downstreamLabels)),
+		Annotations: slices.Sorted(maps.Keys(annotations)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	downstreamLabels[PackageVariantSetLabel] = pvs.Name
+	annotations[managedMetadataAnnotation] = string(managed)
+
+	var injectors []cachev1alpha1.InjectionSelector
+	for _, injector := range tmpl.Injectors {
+		if injector.Name, err = data.expand("injectors", injector.Name); err != nil {
+			return nil, err
+		}
+		injectors = append(injectors, injector)
+	}
+
+	name := revisions.Name(repoName, packageName, workspaceName)
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, fmt.Errorf("generated downstream name %q is invalid: %s", name, strings.Join(errs, ", "))
+	}
+
+	pr := &cachev1alpha1.PackageRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   pvs.Namespace,
+			Labels:      downstreamLabels,
+			Annotations: annotations,
+		},
+		Spec: cachev1alpha1.PackageRevisionSpec{
+			PackageName:    packageName,
+			RepositoryName: repoName,
+			WorkspaceName:  workspaceName,
+			Lifecycle:      cachev1alpha1.PackageRevisionLifecycleDraft,
+			Tasks: []cachev1alpha1.Task{{
+				Type: cachev1alpha1.TaskTypeClone,
+				Clone: &cachev1alpha1.PackageCloneTaskSpec{
+					Upstream: cachev1alpha1.UpstreamPackage{
+						UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: upstream.Name},
+					},
+				},
+			}},
+			Injectors: injectors,
+		},
+	}
+	if err := controllerutil.SetControllerReference(pvs, pr, r.Scheme); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// updateDownstream brings the generated metadata and injectors of an existing downstream
+// PackageRevision in line with the template. The labels and annotations that were set from the
+// template but no longer are, according to the managedMetadataAnnotation, are removed. The tasks
+// and lifecycle are left alone, and so are the injectors of published downstreams, whose spec is
+// immutable.
+func (r *PackageVariantSetReconciler) updateDownstream(ctx context.Context, current, want *cachev1alpha1.PackageRevision) error {
+	updated := current.DeepCopy()
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	var previous managedMetadata
+	if value, found := current.Annotations[managedMetadataAnnotation]; found {
+		if err := json.Unmarshal([]byte(value), &previous); err != nil {
+			logf.FromContext(ctx).Info("Ignoring invalid managed metadata annotation", "name", current.Name,
+				"error", err.Error())
+		}
+	}
+	for _, key := range previous.Labels {
+		if _, wanted := want.Labels[key]; !wanted {
+			delete(updated.Labels, key)
+		}
+	}
+	for _, key := range previous.Annotations {
+		if _, wanted := want.Annotations[key]; !wanted {
+			delete(updated.Annotations, key)
+		}
+	}
+	maps.Copy(updated.Labels, want.Labels)
+	maps.Copy(updated.Annotations, want.Annotations)
+	switch current.Spec.Lifecycle {
+	case cachev1alpha1.PackageRevisionLifecyclePublished, cachev1alpha1.PackageRevisionLifecycleDeletionProposed:
+	default:
+		updated.Spec.Injectors = want.Spec.Injectors
+	}
+
+	if maps.Equal(updated.Labels, current.Labels) && maps.Equal(updated.Annotations, current.Annotations) &&
+		slices.Equal(updated.Spec.Injectors, current.Spec.Injectors) {
+		return nil
+	}
+	return r.Update(ctx, updated)
+}
+
+// pruneDownstream removes a downstream PackageRevision whose target has disappeared. Unpublished
+// revisions are deleted, published revisions are proposed for deletion so that removal goes through approval.
+func (r *PackageVariantSetReconciler) pruneDownstream(ctx context.Context, pr *cachev1alpha1.PackageRevision) error {
+	log := logf.FromContext(ctx)
+
+	switch pr.Spec.Lifecycle {
+	case cachev1alpha1.PackageRevisionLifecyclePublished:
+		log.Info("Proposing deletion of downstream PackageRevision", "name", pr.Name)
+		pr.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecycleDeletionProposed
+		return r.Update(ctx, pr)
+	case cachev1alpha1.PackageRevisionLifecycleDeletionProposed:
+		return nil
+	default:
+		log.Info("Deleting downstream PackageRevision", "name", pr.Name)
+		return client.IgnoreNotFound(r.Delete(ctx, pr))
+	}
+}
+
+// listDownstreamNames returns the sorted names of the PackageRevisions owned by the PackageVariantSet.
+func (r *PackageVariantSetReconciler) listDownstreamNames(ctx context.Context, pvs *cachev1alpha1.PackageVariantSet) ([]string, error) {
+	downstreams := &cachev1alpha1.PackageRevisionList{}
+	if err := r.List(ctx, downstreams, client.InNamespace(pvs.Namespace),
+		client.MatchingLabels{PackageVariantSetLabel: pvs.Name}); err != nil {
+		return nil, err
+	}
+	var names []string
+	for i := range downstreams.Items {
+		if metav1.IsControlledBy(&downstreams.Items[i], pvs) {
+			names = append(names, downstreams.Items[i].Name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// updateStatus records the outcome of the reconciliation on the PackageVariantSet.
+func (r *PackageVariantSetReconciler) updateStatus(ctx context.Context, pvs *cachev1alpha1.PackageVariantSet,
+	targets []string, status metav1.ConditionStatus, reason, message string) error {
+	if status == metav1.ConditionTrue {
+		pvs.Status.DownstreamTargets = targets
+	}
+	meta.SetStatusCondition(&pvs.Status.Conditions, metav1.Condition{Type: typeReadyPackageVariantSet,
+		Status: status, Reason: reason, Message: message, ObservedGeneration: pvs.Generation})
+	if err := r.Status().Update(ctx, pvs); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to update PackageVariantSet status")
+		return err
+	}
+	return nil
+}
+
+// templateData is the data that PackageVariantTemplate fields are evaluated against.
+type templateData map[string]any
+
+// expand evaluates a single template string. Referring to a field that does not exist is an error.
+func (d templateData) expand(field, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	t, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template for %s: %w", field, err)
+	}
+	var out strings.Builder
+	if err := t.Execute(&out, map[string]any(d)); err != nil {
+		return "", fmt.Errorf("failed to evaluate template for %s: %w", field, err)
+	}
+	return out.String(), nil
+}
+
+// expandMap evaluates every value of a map of template strings.
+func (d templateData) expandMap(field string, texts map[string]string) (map[string]string, error) {
+	expanded := make(map[string]string, len(texts))
+	for k, text := range texts {
+		v, err := d.expand(field+"."+k, text)
+		if err != nil {
+			return nil, err
+		}
+		expanded[k] = v
+	}
+	return expanded, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// A change to any Repository re-evaluates every PackageVariantSet in its namespace, since
+// repository selectors and templates may refer to it.
+func (r *PackageVariantSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&cachev1alpha1.PackageVariantSet{}).
+		Named("PackageVariantSet").
+		Owns(&cachev1alpha1.PackageRevision{}).
+		Watches(&cachev1alpha1.Repository{}, handler.EnqueueRequestsFromMapFunc(r.mapRepositoryToPackageVariantSets))
+	return watchShards(b, mgr.GetClient(), r.Shards, &cachev1alpha1.PackageVariantSetList{}, r.shardOf).Complete(r)
+}
+
+// shardOf returns the shard of a PackageVariantSet.
+func (r *PackageVariantSetReconciler) shardOf(obj client.Object) int {
+	return r.Shards.For(obj.GetNamespace(), obj.GetName())
+}
+
+// mapRepositoryToPackageVariantSets enqueues all PackageVariantSets in the namespace of a Repository.
+func (r *PackageVariantSetReconciler) mapRepositoryToPackageVariantSets(ctx context.Context, obj client.Object) []reconcile.Request {
+	sets := &cachev1alpha1.PackageVariantSetList{}
+	if err := r.List(ctx, sets, client.InNamespace(obj.GetNamespace())); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list PackageVariantSets")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(sets.Items))
+	for i := range sets.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&sets.Items[i])})
+	}
+	return requests
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+var _ = Describe("PackageVariantSet Controller", func() {
+	const namespace = "default"
+
+	ctx := context.Background()
+
+	var reconciler *PackageVariantSetReconciler
+
+	newRepository := func(name string, labels map[string]string) *cachev1alpha1.Repository {
+		return &cachev1alpha1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+			Spec: cachev1alpha1.RepositorySpec{
+				Type: cachev1alpha1.RepositoryTypeGit,
+				Git:  &cachev1alpha1.GitRepository{Repo: "https://example.com/" + name + ".git"},
+			},
+		}
+	}
+
+	reconcileSet := func(name string) {
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: namespace, Name: name},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	downstreams := func(pvsName string) []cachev1alpha1.PackageRevision {
+		list := &cachev1alpha1.PackageRevisionList{}
+		Expect(k8sClient.List(ctx, list, client.InNamespace(namespace),
+			client.MatchingLabels{PackageVariantSetLabel: pvsName})).To(Succeed())
+		return list.Items
+	}
+
+	BeforeEach(func() {
+		reconciler = &PackageVariantSetReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+
+		By("creating the upstream PackageRevision")
+		upstream := &cachev1alpha1.PackageRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "blueprints.network.v1", Namespace: namespace},
+			Spec: cachev1alpha1.PackageRevisionSpec{
+				PackageName:    "network",
+				RepositoryName: "blueprints",
+				Lifecycle:      cachev1alpha1.PackageRevisionLifecyclePublished,
+			},
+		}
+		Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, upstream))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(ctx, &cachev1alpha1.PackageVariantSet{}, client.InNamespace(namespace))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &cachev1alpha1.PackageRevision{}, client.InNamespace(namespace),
+			client.HasLabels{PackageVariantSetLabel})).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &cachev1alpha1.Repository{}, client.InNamespace(namespace))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &corev1.ConfigMap{}, client.InNamespace(namespace),
+			client.HasLabels{"site"})).To(Succeed())
+	})
+
+	It("should create and prune downstreams for repositories selected by label", func() {
+		Expect(k8sClient.Create(ctx, newRepository("edge-1", map[string]string{"tier": "edge"}))).To(Succeed())
+		Expect(k8sClient.Create(ctx, newRepository("edge-2", map[string]string{"tier": "edge"}))).To(Succeed())
+		Expect(k8sClient.Create(ctx, newRepository("core", map[string]string{"tier": "core"}))).To(Succeed())
+
+		pvs := &cachev1alpha1.PackageVariantSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "edge-network", Namespace: namespace},
+			Spec: cachev1alpha1.PackageVariantSetSpec{
+				Upstream: cachev1alpha1.PackageRevisionRef{Name: "blueprints.network.v1"},
+				Targets: []cachev1alpha1.Target{{
+					RepositorySelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "edge"}},
+					Template: &cachev1alpha1.PackageVariantTemplate{
+						Labels: map[string]string{"repo": "{{ .repository.metadata.name }}"},
+					},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pvs)).To(Succeed())
+
+		By("reconciling the PackageVariantSet")
+		reconcileSet(pvs.Name)
+
+		prs := downstreams(pvs.Name)
+		Expect(prs).To(HaveLen(2))
+		for _, pr := range prs {
+			Expect(pr.Spec.PackageName).To(Equal("network"))
+			Expect(pr.Spec.WorkspaceName).To(Equal("edge-network"))
+			Expect(pr.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecycleDraft))
+			Expect(pr.Labels).To(HaveKeyWithValue("repo", pr.Spec.RepositoryName))
+			Expect(pr.Spec.Tasks).To(HaveLen(1))
+			Expect(pr.Spec.Tasks[0].Type).To(Equal(cachev1alpha1.TaskTypeClone))
+			Expect(pr.Spec.Tasks[0].Clone.Upstream.UpstreamRef.Name).To(Equal("blueprints.network.v1"))
+		}
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvs), pvs)).To(Succeed())
+		Expect(pvs.Status.DownstreamTargets).To(Equal([]string{"edge-1.network.edge-network", "edge-2.network.edge-network"}))
+		Expect(meta.IsStatusConditionTrue(pvs.Status.Conditions, typeReadyPackageVariantSet)).To(BeTrue())
+
+		By("removing a repository from the selection")
+		repo := &cachev1alpha1.Repository{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "edge-2"}, repo)).To(Succeed())
+		repo.Labels["tier"] = "core"
+		Expect(k8sClient.Update(ctx, repo)).To(Succeed())
+
+		reconcileSet(pvs.Name)
+
+		prs = downstreams(pvs.Name)
+		Expect(prs).To(HaveLen(1))
+		Expect(prs[0].Spec.RepositoryName).To(Equal("edge-1"))
+	})
+
+	It("should create the other downstreams when the name of one is taken", func() {
+		Expect(k8sClient.Create(ctx, newRepository("taken-1", map[string]string{"tier": "taken"}))).To(Succeed())
+		Expect(k8sClient.Create(ctx, newRepository("taken-2", map[string]string{"tier": "taken"}))).To(Succeed())
+		unowned := &cachev1alpha1.PackageRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "taken-1.network.taken-network", Namespace: namespace},
+			Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "network", RepositoryName: "taken-1",
+				WorkspaceName: "taken-network", Lifecycle: cachev1alpha1.PackageRevisionLifecycleDraft},
+		}
+		Expect(k8sClient.Create(ctx, unowned)).To(Succeed())
+		DeferCleanup(func() { Expect(k8sClient.Delete(ctx, unowned)).To(Succeed()) })
+
+		pvs := &cachev1alpha1.PackageVariantSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "taken-network", Namespace: namespace},
+			Spec: cachev1alpha1.PackageVariantSetSpec{
+				Upstream: cachev1alpha1.PackageRevisionRef{Name: "blueprints.network.v1"},
+				Targets: []cachev1alpha1.Target{{
+					RepositorySelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "taken"}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pvs)).To(Succeed())
+
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pvs)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(downstreamConflictRetryPeriod))
+
+		prs := downstreams(pvs.Name)
+		Expect(prs).To(HaveLen(1))
+		Expect(prs[0].Name).To(Equal("taken-2.network.taken-network"))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvs), pvs)).To(Succeed())
+		condition := meta.FindStatusCondition(pvs.Status.Conditions, typeReadyPackageVariantSet)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("DownstreamConflict"))
+		Expect(condition.Message).To(ContainSubstring("taken-1.network.taken-network"))
+	})
+
+	It("should template downstreams from arbitrary selected objects", func() {
+		Expect(k8sClient.Create(ctx, newRepository("site-repo", nil))).To(Succeed())
+		for _, site := range []string{"dublin", "paris"} {
+			Expect(k8sClient.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: site, Namespace: namespace, Labels: map[string]string{"site": "true"}},
+				Data:       map[string]string{"repository": "site-repo"},
+			})).To(Succeed())
+		}
+
+		pvs := &cachev1alpha1.PackageVariantSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "sites", Namespace: namespace},
+			Spec: cachev1alpha1.PackageVariantSetSpec{
+				Upstream: cachev1alpha1.PackageRevisionRef{Name: "blueprints.network.v1"},
+				Targets: []cachev1alpha1.Target{{
+					ObjectSelector: &cachev1alpha1.ObjectSelector{
+						APIVersion: "v1",
+						Kind:       "ConfigMap",
+						Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"site": "true"}},
+					},
+					Template: &cachev1alpha1.PackageVariantTemplate{
+						Repository:    "{{ .target.data.repository }}",
+						PackageName:   "network-{{ .target.metadata.name }}",
+						WorkspaceName: "v1",
+						Injectors:     []cachev1alpha1.InjectionSelector{{Kind: "ConfigMap", Name: "{{ .target.metadata.name }}"}},
+					},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pvs)).To(Succeed())
+
+		reconcileSet(pvs.Name)
+
+		pr := &cachev1alpha1.PackageRevision{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "site-repo.network-paris.v1"}, pr)).To(Succeed())
+		Expect(pr.Spec.RepositoryName).To(Equal("site-repo"))
+		Expect(pr.Spec.PackageName).To(Equal("network-paris"))
+		Expect(pr.Spec.Injectors).To(Equal([]cachev1alpha1.InjectionSelector{{Kind: "ConfigMap", Name: "paris"}}))
+		Expect(downstreams(pvs.Name)).To(HaveLen(2))
+
+		By("changing the template after a downstream is published")
+		pr.Spec.Lifecycle, pr.Spec.Revision = cachev1alpha1.PackageRevisionLifecyclePublished, 1
+		Expect(k8sClient.Update(ctx, pr)).To(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvs), pvs)).To(Succeed())
+		pvs.Spec.Targets[0].Template.Labels = map[string]string{"site": "{{ .target.metadata.name }}"}
+		pvs.Spec.Targets[0].Template.Injectors = []cachev1alpha1.InjectionSelector{{Kind: "ConfigMap", Name: "{{ .target.metadata.name }}-v2"}}
+		Expect(k8sClient.Update(ctx, pvs)).To(Succeed())
+
+		reconcileSet(pvs.Name)
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pr), pr)).To(Succeed())
+		Expect(pr.Labels).To(HaveKeyWithValue("site", "paris"))
+		Expect(pr.Spec.Injectors).To(Equal([]cachev1alpha1.InjectionSelector{{Kind: "ConfigMap", Name: "paris"}}))
+		draft := &cachev1alpha1.PackageRevision{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "site-repo.network-dublin.v1"}, draft)).To(Succeed())
+		Expect(draft.Spec.Injectors).To(Equal([]cachev1alpha1.InjectionSelector{{Kind: "ConfigMap", Name: "dublin-v2"}}))
+
+		By("dropping the labels from the template")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvs), pvs)).To(Succeed())
+		pvs.Spec.Targets[0].Template.Labels = nil
+		Expect(k8sClient.Update(ctx, pvs)).To(Succeed())
+		pr.Labels["owner"] = "network-team"
+		Expect(k8sClient.Update(ctx, pr)).To(Succeed())
+
+		reconcileSet(pvs.Name)
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pr), pr)).To(Succeed())
+		Expect(pr.Labels).NotTo(HaveKey("site"))
+		Expect(pr.Labels).To(HaveKeyWithValue("owner", "network-team"))
+		Expect(pr.Labels).To(HaveKeyWithValue(PackageVariantSetLabel, pvs.Name))
+	})
+
+	It("should select objects of any kind the operator may list", func() {
+		Expect(k8sClient.Create(ctx, newRepository("eu-1", map[string]string{"region": "eu"}))).To(Succeed())
+		Expect(k8sClient.Create(ctx, newRepository("us-1", map[string]string{"region": "us"}))).To(Succeed())
+
+		pvs := &cachev1alpha1.PackageVariantSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "regions", Namespace: namespace},
+			Spec: cachev1alpha1.PackageVariantSetSpec{
+				Upstream: cachev1alpha1.PackageRevisionRef{Name: "blueprints.network.v1"},
+				Targets: []cachev1alpha1.Target{{
+					ObjectSelector: &cachev1alpha1.ObjectSelector{
+						APIVersion: cachev1alpha1.GroupVersion.String(),
+						Kind:       "Repository",
+						Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"region": "eu"}},
+					},
+					Template: &cachev1alpha1.PackageVariantTemplate{
+						Repository:  "{{ .target.metadata.name }}",
+						PackageName: "network-{{ index .target.metadata.labels \"region\" }}",
+					},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pvs)).To(Succeed())
+
+		reconcileSet(pvs.Name)
+
+		prs := downstreams(pvs.Name)
+		Expect(prs).To(HaveLen(1))
+		Expect(prs[0].Name).To(Equal("eu-1.network-eu.regions"))
+
+		By("explaining how to grant the kinds the operator may not list")
+		withWatch, err := client.NewWithWatch(cfg, client.Options{Scheme: k8sClient.Scheme()})
+		Expect(err).NotTo(HaveOccurred())
+		reconciler.Client = interceptor.NewClient(withWatch, interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				if objects, ok := list.(*unstructured.UnstructuredList); ok && objects.GetKind() == "SiteList" {
+					return apierrors.NewForbidden(schema.GroupResource{Group: "infra.example.com", Resource: "sites"}, "", nil)
+				}
+				return c.List(ctx, list, opts...)
+			},
+		})
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvs), pvs)).To(Succeed())
+		pvs.Spec.Targets[0].ObjectSelector = &cachev1alpha1.ObjectSelector{APIVersion: "infra.example.com/v1", Kind: "Site"}
+		Expect(k8sClient.Update(ctx, pvs)).To(Succeed())
+
+		reconcileSet(pvs.Name)
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvs), pvs)).To(Succeed())
+		condition := meta.FindStatusCondition(pvs.Status.Conditions, typeReadyPackageVariantSet)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(ContainSubstring(objectSelectorAggregationLabel + "=true"))
+	})
+
+	It("should report targets that cannot be evaluated", func() {
+		pvs := &cachev1alpha1.PackageVariantSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "broken", Namespace: namespace},
+			Spec: cachev1alpha1.PackageVariantSetSpec{
+				Upstream: cachev1alpha1.PackageRevisionRef{Name: "blueprints.network.v1"},
+				Targets: []cachev1alpha1.Target{{
+					Repositories: []cachev1alpha1.RepositoryTarget{{Name: "does-not-exist"}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pvs)).To(Succeed())
+
+		reconcileSet(pvs.Name)
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvs), pvs)).To(Succeed())
+		condition := meta.FindStatusCondition(pvs.Status.Conditions, typeReadyPackageVariantSet)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("InvalidTargets"))
+		Expect(downstreams(pvs.Name)).To(BeEmpty())
+	})
+
+	It("should reject names that cannot label the downstreams", func() {
+		pvs := &cachev1alpha1.PackageVariantSet{
+			ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("n", 64), Namespace: namespace},
+			Spec: cachev1alpha1.PackageVariantSetSpec{
+				Upstream: cachev1alpha1.PackageRevisionRef{Name: "blueprints.network.v1"},
+			},
+		}
+		err := k8sClient.Create(ctx, pvs)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("name must be no more than 63 characters"))
+	})
+})
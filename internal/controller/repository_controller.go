@@ -0,0 +1,512 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/git"
+	packagecache "github.com/liamfallon/porch-operator/internal/git/cache"
+	"github.com/liamfallon/porch-operator/internal/revisions"
+	"github.com/liamfallon/porch-operator/internal/shard"
+	"github.com/liamfallon/porch-operator/internal/tracing"
+)
+
+const (
+	// RepositoryLabel is set on every PackageRevision discovered in a Repository, its value is
+	// the name of the Repository.
+	RepositoryLabel = "porch.kpt.dev/repository"
+
+	// GitRefAnnotation records the git ref a discovered PackageRevision is stored in.
+	GitRefAnnotation = "porch.kpt.dev/git-ref"
+
+	// CommitAnnotation records the commit the contents of a discovered PackageRevision were read
+	// from, on both the PackageRevision and its PackageRevisionResources.
+	CommitAnnotation = "porch.kpt.dev/commit"
+
+	// typeReadyRepository represents whether the PackageRevisions of a Repository match its contents
+	typeReadyRepository = "Ready"
+)
+
+// SyncRequestAnnotation requests an immediate sync of a Repository when it is set or its value
+// changes, for example to the current time.
+const SyncRequestAnnotation = "porch.kpt.dev/sync-requested"
+
+// RepositoryReconciler reconciles a Repository object
+type RepositoryReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// Repositories holds the mirrors of the git repositories, so that they are fetched
+	// incrementally.
+	Repositories *git.Repositories
+
+	// Packages caches the contents of the packages, so that they are read from the mirrors once.
+	// If nil, contents are read from the mirrors every time they are needed.
+	Packages *packagecache.Cache
+
+	// SyncInterval is how often Repositories that do not specify an interval are synced. If
+	// zero, they are only synced when they change.
+	SyncInterval time.Duration
+
+	// SyncJitter is the fraction of the interval by which syncs are randomly delayed, so that
+	// Repositories created together are not synced together.
+	SyncJitter float64
+
+	// Shards are the shards of the Repositories this replica syncs. If nil, every Repository is
+	// synced.
+	Shards *shard.Shards
+}
+
+// syncFailure is a failure to sync a Repository that is reported in its status rather than
+// retried at once.
+type syncFailure struct {
+	reason string
+	err    error
+}
+
+func (f *syncFailure) Error() string {
+	return f.err.Error()
+}
+
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=repositories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=repositories/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagerevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagerevisions/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=packagerevisionresources,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+
+// Reconcile syncs a git Repository: it discovers the packages in the repository, and keeps a
+// PackageRevision, with its contents, for every revision of them. The PackageRevisions whose
+// refs have disappeared from the repository are deleted. Repositories are synced again on their
+// sync interval.
+func (r *RepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, span := tracing.Start(ctx, "Reconcile Repository")
+	defer func() { tracing.End(span, err) }()
+	log := logf.FromContext(ctx)
+
+	repo := &cachev1alpha1.Repository{}
+	if err := r.Get(ctx, req.NamespacedName, repo); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("Repository resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get Repository")
+		return ctrl.Result{}, err
+	}
+	tracing.SetRepository(span, repo)
+
+	// Discovered PackageRevisions are owned by the Repository, so the garbage collector takes
+	// care of them when it is deleted.
+	if repo.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	// The replica that owns the shard of the Repository syncs it, and syncs it again when it
+	// acquires the shard.
+	unlock, owned := r.Shards.Lock(r.shardOf(repo))
+	if !owned {
+		return ctrl.Result{}, nil
+	}
+	defer unlock()
+
+	if repo.Spec.Type != cachev1alpha1.RepositoryTypeGit || repo.Spec.Git == nil {
+		return ctrl.Result{}, r.updateStatus(ctx, repo, nil, &syncFailure{reason: "Unsupported",
+			err: fmt.Errorf("sync is not supported for repositories of type %q", repo.Spec.Type)})
+	}
+
+	var result ctrl.Result
+	interval := r.SyncInterval
+	if repo.Spec.SyncInterval != nil {
+		interval = repo.Spec.SyncInterval.Duration
+	}
+	if interval > 0 {
+		result.RequeueAfter = wait.Jitter(interval, r.SyncJitter)
+	}
+
+	snapshot, err := r.sync(ctx, repo)
+	var failure *syncFailure
+	if err != nil && !errors.As(err, &failure) {
+		return ctrl.Result{}, err
+	}
+	if failure != nil {
+		log.Info("Failed to sync repository", "reason", failure.reason, "error", failure.Error())
+	}
+	return result, r.updateStatus(ctx, repo, snapshot, failure)
+}
+
+// sync brings the discovered PackageRevisions of the Repository up to date with the repository,
+// and returns the snapshot of the repository they were synced with.
+func (r *RepositoryReconciler) sync(ctx context.Context, repo *cachev1alpha1.Repository) (*git.Snapshot, error) {
+	log := logf.FromContext(ctx)
+
+	auth, err := r.authFor(ctx, repo)
+	if err != nil {
+		return nil, &syncFailure{reason: "InvalidCredentials", err: err}
+	}
+	mirror, err := r.Repositories.Fetch(ctx, repo.Spec.Git.Repo, auth)
+	if err != nil {
+		return nil, &syncFailure{reason: "FetchFailed", err: err}
+	}
+
+	existing := &cachev1alpha1.PackageRevisionList{}
+	if err := r.List(ctx, existing, client.InNamespace(repo.Namespace),
+		client.MatchingLabels{RepositoryLabel: repo.Name}); err != nil {
+		log.Error(err, "Failed to list discovered PackageRevisions")
+		return nil, err
+	}
+	var discovered []*cachev1alpha1.PackageRevision
+	for i := range existing.Items {
+		if metav1.IsControlledBy(&existing.Items[i], repo) {
+			discovered = append(discovered, &existing.Items[i])
+		}
+	}
+
+	snapshot, err := mirror.Discover(ctx, repo.Spec.Git.Branch, repo.Spec.Git.Directory)
+	if err != nil {
+		return nil, &syncFailure{reason: "DiscoveryFailed", err: err}
+	}
+
+	desired := map[string]git.PackageRef{}
+	for _, ref := range snapshot.Refs {
+		name := revisions.Name(repo.Name, ref.Package, ref.Workspace)
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			log.Info("Ignoring package revision without a valid object name", "ref", ref.Ref, "package", ref.Package,
+				"error", strings.Join(errs, ", "))
+			continue
+		}
+		desired[name] = ref
+	}
+
+	for _, current := range discovered {
+		ref, found := desired[current.Name]
+		if !found {
+			log.Info("Retiring PackageRevision whose ref has disappeared", "name", current.Name)
+			cause := fmt.Sprintf("%s has disappeared", current.Annotations[GitRefAnnotation])
+			if err := r.retire(ctx, current, ReasonRefDeleted, cause); err != nil {
+				return nil, err
+			}
+			r.invalidate(ctx, repo, current)
+			continue
+		}
+		delete(desired, current.Name)
+		if current.Spec.PackageName != ref.Package || current.Spec.WorkspaceName != ref.Workspace {
+			log.Info("Retiring PackageRevision whose ref names another package or workspace", "name", current.Name,
+				"ref", ref.Ref)
+			cause := fmt.Sprintf("%s now names package %s in workspace %s", ref.Ref, ref.Package, ref.Workspace)
+			if err := r.retire(ctx, current, ReasonRenamed, cause); err != nil {
+				return nil, err
+			}
+			r.invalidate(ctx, repo, current)
+			continue
+		}
+		tampered, err := r.checkTampering(ctx, current, ref)
+		if err != nil {
+			return nil, err
+		}
+		if tampered {
+			continue
+		}
+		if current.Annotations[CommitAnnotation] != ref.Commit {
+			r.invalidate(ctx, repo, current)
+		}
+		if err := r.updateDiscovered(ctx, current, ref); err != nil {
+			log.Error(err, "Failed to update discovered PackageRevision", "name", current.Name)
+			return nil, err
+		}
+		if err := r.syncContents(ctx, current, mirror, repo, ref); err != nil {
+			return nil, err
+		}
+	}
+
+	var conflicts []string
+	for _, name := range slices.Sorted(maps.Keys(desired)) {
+		ref := desired[name]
+		log.Info("Creating discovered PackageRevision", "name", name, "ref", ref.Ref)
+		pr := newDiscovered(repo, name, ref)
+		if err := controllerutil.SetControllerReference(repo, pr, r.Scheme); err != nil {
+			return nil, err
+		}
+		if err := r.Create(ctx, pr); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				conflicts = append(conflicts, name)
+				continue
+			}
+			log.Error(err, "Failed to create discovered PackageRevision", "name", name)
+			return nil, err
+		}
+		if err := r.syncContents(ctx, pr, mirror, repo, ref); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return snapshot, &syncFailure{reason: "PackageRevisionConflict", err: fmt.Errorf(
+			"PackageRevisions %s already exist and are not owned by this Repository", strings.Join(conflicts, ", "))}
+	}
+	return snapshot, nil
+}
+
+// authFor returns the credentials for the git repository, or nil if it needs none.
+func (r *RepositoryReconciler) authFor(ctx context.Context, repo *cachev1alpha1.Repository) (transport.AuthMethod, error) {
+	name := repo.Spec.Git.SecretRef.Name
+	if name == "" {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: repo.Namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get Secret %q: %w", name, err)
+	}
+	auth, err := git.AuthFromSecret(secret.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Secret %q: %w", name, err)
+	}
+	return auth, nil
+}
+
+// newDiscovered returns a PackageRevision for a revision discovered in the Repository.
+func newDiscovered(repo *cachev1alpha1.Repository, name string, ref git.PackageRef) *cachev1alpha1.PackageRevision {
+	return &cachev1alpha1.PackageRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   repo.Namespace,
+			Labels:      map[string]string{RepositoryLabel: repo.Name},
+			Annotations: map[string]string{GitRefAnnotation: ref.Ref, CommitAnnotation: ref.Commit},
+		},
+		Spec: cachev1alpha1.PackageRevisionSpec{
+			PackageName:    ref.Package,
+			RepositoryName: repo.Name,
+			WorkspaceName:  ref.Workspace,
+			Revision:       ref.Revision,
+			Lifecycle:      ref.Lifecycle,
+		},
+	}
+}
+
+// updateDiscovered brings a discovered PackageRevision up to date with its ref. A Published
+// revision whose deletion has been proposed stays DeletionProposed.
+func (r *RepositoryReconciler) updateDiscovered(ctx context.Context, pr *cachev1alpha1.PackageRevision, ref git.PackageRef) error {
+	lifecycle := ref.Lifecycle
+	if lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished &&
+		pr.Spec.Lifecycle == cachev1alpha1.PackageRevisionLifecycleDeletionProposed {
+		lifecycle = pr.Spec.Lifecycle
+	}
+	if pr.Spec.Revision == ref.Revision && pr.Spec.Lifecycle == lifecycle && pr.Annotations[GitRefAnnotation] == ref.Ref && pr.Annotations[CommitAnnotation] == ref.Commit {
+		return nil
+	}
+	pr.Spec.Revision, pr.Spec.Lifecycle = ref.Revision, lifecycle
+	if pr.Annotations == nil {
+		pr.Annotations = map[string]string{}
+	}
+	pr.Annotations[GitRefAnnotation], pr.Annotations[CommitAnnotation] = ref.Ref, ref.Commit
+	return r.Update(ctx, pr)
+}
+
+// retire retires a discovered PackageRevision that its ref no longer stands for, because the ref
+// has disappeared or names another package or workspace, which the PackageRevision cannot follow
+// as its package and workspace are immutable. A draft or proposed revision is deleted, and is
+// discovered again under its new names, if any, by the next sync. The deletion of a published
+// revision is proposed instead, so that it is only deleted once approved. The event is recorded
+// with the reason, and a message that starts with the cause.
+func (r *RepositoryReconciler) retire(ctx context.Context, pr *cachev1alpha1.PackageRevision, reason, cause string) error {
+	switch pr.Spec.Lifecycle {
+	case cachev1alpha1.PackageRevisionLifecycleDeletionProposed:
+		return nil
+	case cachev1alpha1.PackageRevisionLifecyclePublished:
+		eventf(r.Recorder, pr, corev1.EventTypeWarning, reason, "%s, proposing the deletion of the revision", cause)
+		pr.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecycleDeletionProposed
+		return r.Update(ctx, pr)
+	default:
+		eventf(r.Recorder, pr, corev1.EventTypeWarning, reason, "%s, deleting the revision", cause)
+		return client.IgnoreNotFound(r.Delete(ctx, pr))
+	}
+}
+
+// checkTampering flags a discovered PackageRevision with the Tampered condition when the tag of its
+// published revision has been moved to another commit out of band, and clears the condition once
+// the tag is moved back. Published revisions are immutable, so a tampered revision keeps the
+// commit and the contents it was published with; it returns true if the revision is tampered.
+// The operator never writes tags: they are pushed to the repository out of band, where a moved
+// tag cannot be refused, so it is flagged here instead.
+func (r *RepositoryReconciler) checkTampering(ctx context.Context, pr *cachev1alpha1.PackageRevision,
+	ref git.PackageRef) (bool, error) {
+	commit := pr.Annotations[CommitAnnotation]
+	tampered := ref.Revision > 0 && commit != "" && commit != ref.Commit
+	if !tampered {
+		if !meta.RemoveStatusCondition(&pr.Status.Conditions, typeTamperedPackageRevision) {
+			return false, nil
+		}
+		return false, r.Status().Update(ctx, pr)
+	}
+
+	condition := metav1.Condition{Type: typeTamperedPackageRevision, Status: metav1.ConditionTrue,
+		Reason: "PublishedRefMoved", ObservedGeneration: pr.Generation,
+		Message: fmt.Sprintf("%s was moved from commit %s to %s out of band, the revision keeps its published contents",
+			ref.Ref, commit, ref.Commit)}
+	if existing := meta.FindStatusCondition(pr.Status.Conditions, condition.Type); existing != nil &&
+		existing.Status == condition.Status && existing.Message == condition.Message {
+		return true, nil
+	}
+	logf.FromContext(ctx).Info("Published ref has been tampered with", "name", pr.Name, "ref", ref.Ref,
+		"published", commit, "commit", ref.Commit)
+	recordTransition(r.Recorder, pr, pr.Status.Conditions, condition, corev1.EventTypeWarning, ReasonTampered)
+	meta.SetStatusCondition(&pr.Status.Conditions, condition)
+	return true, r.Status().Update(ctx, pr)
+}
+
+// syncContents sets the contents of a discovered PackageRevision to the contents of the package
+// at the commit of its ref, unless they were already read from that commit.
+func (r *RepositoryReconciler) syncContents(ctx context.Context, pr *cachev1alpha1.PackageRevision,
+	mirror *git.Repository, repo *cachev1alpha1.Repository, ref git.PackageRef) error {
+	prr := &cachev1alpha1.PackageRevisionResources{ObjectMeta: metav1.ObjectMeta{Name: pr.Name, Namespace: pr.Namespace}}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(prr), prr); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if prr.Annotations[CommitAnnotation] == ref.Commit {
+		return nil
+	}
+
+	contents, err := r.readPackage(ctx, mirror, repo, ref)
+	if err != nil {
+		return err
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, prr, func() error {
+		if prr.Annotations == nil {
+			prr.Annotations = map[string]string{}
+		}
+		prr.Annotations[CommitAnnotation] = ref.Commit
+		prr.Spec = cachev1alpha1.PackageRevisionResourcesSpec{
+			PackageName:    pr.Spec.PackageName,
+			RepositoryName: pr.Spec.RepositoryName,
+			WorkspaceName:  pr.Spec.WorkspaceName,
+			Resources:      contents,
+		}
+		return controllerutil.SetControllerReference(pr, prr, r.Scheme)
+	})
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to write contents of discovered PackageRevision", "name", pr.Name)
+	}
+	return err
+}
+
+// readPackage returns the contents of the package at the commit of its ref, from the package
+// cache when they are there.
+func (r *RepositoryReconciler) readPackage(ctx context.Context, mirror *git.Repository,
+	repo *cachev1alpha1.Repository, ref git.PackageRef) (map[string]string, error) {
+	key := packageKey(repo, ref.Package, ref.Commit)
+	if r.Packages != nil {
+		if contents, found := r.Packages.Get(key); found {
+			return contents, nil
+		}
+	}
+	contents, err := mirror.Contents(ctx, ref.Commit, repo.Spec.Git.Directory, ref.Package)
+	if err != nil || r.Packages == nil {
+		return contents, err
+	}
+	if err := r.Packages.Add(key, contents); err != nil {
+		// The contents are still good, they just have to be read again next time.
+		logf.FromContext(ctx).Error(err, "Failed to cache package contents", "package", ref.Package, "commit", ref.Commit)
+	}
+	return contents, nil
+}
+
+// invalidate removes the contents of a discovered PackageRevision at the commit it was last
+// synced with from the package cache, once its ref has moved away from the commit or been deleted.
+func (r *RepositoryReconciler) invalidate(ctx context.Context, repo *cachev1alpha1.Repository, pr *cachev1alpha1.PackageRevision) {
+	commit := pr.Annotations[CommitAnnotation]
+	if r.Packages == nil || commit == "" {
+		return
+	}
+	if err := r.Packages.Invalidate(packageKey(repo, pr.Spec.PackageName, commit)); err != nil {
+		// Contents never change for a commit, so contents that were not invalidated are only
+		// kept until they are evicted.
+		logf.FromContext(ctx).Error(err, "Failed to invalidate package contents", "name", pr.Name, "commit", commit)
+	}
+}
+
+// packageKey returns the key of the contents of the package of a git Repository at the commit.
+func packageKey(repo *cachev1alpha1.Repository, pkg, commit string) packagecache.Key {
+	return packagecache.Key{Repository: repo.Spec.Git.Repo, Commit: commit, Directory: git.PackagePath(repo.Spec.Git.Directory, pkg)}
+}
+
+// updateStatus records the outcome of a sync on the Repository. The commit is only recorded when
+// the sync succeeded.
+func (r *RepositoryReconciler) updateStatus(ctx context.Context, repo *cachev1alpha1.Repository,
+	snapshot *git.Snapshot, failure *syncFailure) error {
+	now := metav1.Now()
+	repo.Status.LastSyncTime = &now
+	condition := metav1.Condition{Type: typeReadyRepository, ObservedGeneration: repo.Generation}
+	if failure != nil {
+		repo.Status.SyncError = failure.Error()
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, failure.reason, failure.Error()
+		recordTransition(r.Recorder, repo, repo.Status.Conditions, condition, corev1.EventTypeWarning, ReasonSyncFailed)
+	} else {
+		repo.Status.Commit, repo.Status.SyncError = snapshot.Commit, ""
+		condition.Status, condition.Reason = metav1.ConditionTrue, "Synced"
+		condition.Message = fmt.Sprintf("%d package revisions discovered", len(snapshot.Refs))
+	}
+	meta.SetStatusCondition(&repo.Status.Conditions, condition)
+	if r.Shards != nil {
+		repo.Status.Shard = &cachev1alpha1.RepositoryShard{Index: int32(r.shardOf(repo)), Owner: r.Shards.Identity}
+	}
+	if err := r.Status().Update(ctx, repo); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to update Repository status")
+		return err
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Only changes to the spec of a Repository, and requests to sync it through its annotations,
+// trigger a sync, not the status updates it makes.
+func (r *RepositoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&cachev1alpha1.Repository{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		Named("Repository")
+	return watchShards(b, mgr.GetClient(), r.Shards, &cachev1alpha1.RepositoryList{}, r.shardOf).Complete(r)
+}
+
+// shardOf returns the shard of a Repository.
+func (r *RepositoryReconciler) shardOf(obj client.Object) int {
+	return r.Shards.For(obj.GetNamespace(), obj.GetName())
+}
@@ -0,0 +1,508 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/diff"
+	"github.com/liamfallon/porch-operator/internal/git"
+	packagecache "github.com/liamfallon/porch-operator/internal/git/cache"
+	"github.com/liamfallon/porch-operator/internal/shard"
+)
+
+var _ = Describe("Repository Controller", func() {
+	const namespace = "default"
+
+	ctx := context.Background()
+
+	var (
+		reconciler *RepositoryReconciler
+		dir        string
+		remote     *gogit.Repository
+	)
+
+	signature := &object.Signature{Name: "porch", Email: "porch@example.com", When: time.Unix(1700000000, 0)}
+
+	// commit writes the files, relative to the root of the remote repository, and commits them
+	// to the branch that is checked out.
+	commit := func(files map[string]string) plumbing.Hash {
+		worktree, err := remote.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		for name, content := range files {
+			full := filepath.Join(dir, name)
+			Expect(os.MkdirAll(filepath.Dir(full), 0o755)).To(Succeed())
+			Expect(os.WriteFile(full, []byte(content), 0o644)).To(Succeed())
+		}
+		Expect(worktree.AddGlob(".")).To(Succeed())
+		hash, err := worktree.Commit("update packages", &gogit.CommitOptions{Author: signature})
+		Expect(err).NotTo(HaveOccurred())
+		return hash
+	}
+
+	checkout := func(branch string, create bool) {
+		worktree, err := remote.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(worktree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch), Create: create})).
+			To(Succeed())
+	}
+
+	discovered := func() map[string]cachev1alpha1.PackageRevision {
+		list := &cachev1alpha1.PackageRevisionList{}
+		Expect(k8sClient.List(ctx, list, client.InNamespace(namespace),
+			client.MatchingLabels{RepositoryLabel: "platform"})).To(Succeed())
+		revisions := map[string]cachev1alpha1.PackageRevision{}
+		for _, pr := range list.Items {
+			revisions[pr.Name] = pr
+		}
+		return revisions
+	}
+
+	contentsOf := func(name string) map[string]string {
+		prr := &cachev1alpha1.PackageRevisionResources{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, prr)).To(Succeed())
+		return prr.Spec.Resources
+	}
+
+	BeforeEach(func() {
+		reconciler = &RepositoryReconciler{
+			Client:       k8sClient,
+			Scheme:       k8sClient.Scheme(),
+			Repositories: &git.Repositories{},
+		}
+
+		dir = GinkgoT().TempDir()
+		var err error
+		remote, err = gogit.PlainInitWithOptions(dir, &gogit.PlainInitOptions{
+			InitOptions: gogit.InitOptions{DefaultBranch: plumbing.Main},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should discover the revisions of the packages in the repository and keep them in sync", func() {
+		kptfile := func(name string) string {
+			return "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: " + name + "\n"
+		}
+
+		By("populating the remote repository")
+		initial := commit(map[string]string{
+			"README.md":                       "# Platform packages\n",
+			"packages/web/Kptfile":            kptfile("web"),
+			"packages/web/deployment.yaml":    "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+			"packages/web/monitoring/Kptfile": kptfile("monitoring"),
+			"packages/team/db/Kptfile":        kptfile("db"),
+		})
+		_, err := remote.CreateTag("web/v1", initial, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = remote.CreateTag("team/db/v1", initial, &gogit.CreateTagOptions{Tagger: signature, Message: "db v1"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = remote.CreateTag("release-1.0", initial, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		checkout("drafts/web/scale-up", true)
+		draft := commit(map[string]string{
+			"packages/web/deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n" +
+				"spec:\n  replicas: 3\n",
+		})
+		checkout("main", false)
+
+		repo := &cachev1alpha1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform", Namespace: namespace},
+			Spec: cachev1alpha1.RepositorySpec{
+				Type: cachev1alpha1.RepositoryTypeGit,
+				Git:  &cachev1alpha1.GitRepository{Repo: dir, Directory: "/packages"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, repo)).To(Succeed())
+
+		By("discovering the repository")
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+		Expect(err).NotTo(HaveOccurred())
+
+		revisions := discovered()
+		Expect(revisions).To(HaveLen(5))
+		Expect(revisions).To(HaveKey("platform.web.main"))
+		Expect(revisions).To(HaveKey("platform.team.db.main"))
+		Expect(revisions).To(HaveKey("platform.team.db.v1"))
+
+		published := revisions["platform.web.v1"]
+		Expect(published.Spec.PackageName).To(Equal("web"))
+		Expect(published.Spec.WorkspaceName).To(Equal("v1"))
+		Expect(published.Spec.Revision).To(Equal(1))
+		Expect(published.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecyclePublished))
+		Expect(published.Annotations[GitRefAnnotation]).To(Equal("refs/tags/web/v1"))
+		Expect(published.Annotations[CommitAnnotation]).To(Equal(initial.String()))
+		Expect(metav1.IsControlledBy(&published, repo)).To(BeTrue())
+		Expect(contentsOf(published.Name)).To(HaveKey("monitoring/Kptfile"))
+
+		scaleUp := revisions["platform.web.scale-up"]
+		Expect(scaleUp.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecycleDraft))
+		Expect(scaleUp.Spec.Revision).To(BeZero())
+		Expect(scaleUp.Annotations[CommitAnnotation]).To(Equal(draft.String()))
+		Expect(contentsOf(scaleUp.Name)["deployment.yaml"]).To(ContainSubstring("replicas: 3"))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(repo), repo)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(repo.Status.Conditions, typeReadyRepository)).To(BeTrue())
+
+		By("publishing a new revision and removing refs")
+		v2 := commit(map[string]string{
+			"packages/web/deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n" +
+				"spec:\n  replicas: 2\n",
+		})
+		_, err = remote.CreateTag("web/v2", v2, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.DeleteTag("web/v1")).To(Succeed())
+		Expect(remote.Storer.RemoveReference(plumbing.NewBranchReferenceName("drafts/web/scale-up"))).To(Succeed())
+
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+		Expect(err).NotTo(HaveOccurred())
+
+		revisions = discovered()
+		Expect(revisions["platform.web.v1"].Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecycleDeletionProposed))
+		Expect(revisions).NotTo(HaveKey("platform.web.scale-up"))
+		Expect(revisions).To(HaveKey("platform.web.v2"))
+		Expect(revisions["platform.web.main"].Annotations[CommitAnnotation]).To(Equal(v2.String()))
+		Expect(contentsOf("platform.web.main")["deployment.yaml"]).To(ContainSubstring("replicas: 2"))
+		Expect(contentsOf("platform.web.v2")["deployment.yaml"]).To(ContainSubstring("replicas: 2"))
+
+		By("approving the deletion of the published revision whose tag has disappeared")
+		v1 := revisions["platform.web.v1"]
+		Expect(k8sClient.Delete(ctx, &v1)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+		Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "platform.web.v1"}, &cachev1alpha1.PackageRevision{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should sync incrementally on its interval", func() {
+		reconciler.SyncInterval, reconciler.SyncJitter = time.Minute, 0.5
+		kptfile := "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: pkg\n"
+		initial := commit(map[string]string{
+			"web/Kptfile":      kptfile,
+			"db/Kptfile":       kptfile,
+			"db/database.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: db\n",
+		})
+
+		repo := &cachev1alpha1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Name: "infra", Namespace: namespace},
+			Spec: cachev1alpha1.RepositorySpec{
+				Type: cachev1alpha1.RepositoryTypeGit,
+				Git:  &cachev1alpha1.GitRepository{Repo: dir},
+			},
+		}
+		Expect(k8sClient.Create(ctx, repo)).To(Succeed())
+
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">=", time.Minute))
+		Expect(result.RequeueAfter).To(BeNumerically("<=", 90*time.Second))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(repo), repo)).To(Succeed())
+		Expect(repo.Status.Commit).To(Equal(initial.String()))
+		Expect(repo.Status.LastSyncTime).NotTo(BeNil())
+		Expect(repo.Status.SyncError).To(BeEmpty())
+
+		By("changing one package")
+		changed := commit(map[string]string{
+			"db/database.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: db\ndata:\n  size: large\n",
+		})
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(repo), repo)).To(Succeed())
+		Expect(repo.Status.Commit).To(Equal(changed.String()))
+		web := &cachev1alpha1.PackageRevision{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "infra.web.main"}, web)).To(Succeed())
+		Expect(web.Annotations[CommitAnnotation]).To(Equal(initial.String()))
+		db := &cachev1alpha1.PackageRevision{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "infra.db.main"}, db)).To(Succeed())
+		Expect(db.Annotations[CommitAnnotation]).To(Equal(changed.String()))
+		Expect(contentsOf(db.Name)["database.yaml"]).To(ContainSubstring("size: large"))
+
+		By("recording a failed sync")
+		repo.Spec.Git.Repo = filepath.Join(dir, "missing")
+		Expect(k8sClient.Update(ctx, repo)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(repo), repo)).To(Succeed())
+		Expect(repo.Status.SyncError).NotTo(BeEmpty())
+		Expect(repo.Status.Commit).To(Equal(changed.String()))
+	})
+
+	It("should cache package contents and restore Published contents without reading git", func() {
+		packages, err := packagecache.New(1<<20, "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		reconciler.Packages = packages
+		kptfile := "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: web\n"
+		initial := commit(map[string]string{"web/Kptfile": kptfile, "web/service.yaml": "kind: Service\n"})
+
+		repo := &cachev1alpha1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: namespace},
+			Spec: cachev1alpha1.RepositorySpec{
+				Type: cachev1alpha1.RepositoryTypeGit,
+				Git:  &cachev1alpha1.GitRepository{Repo: dir},
+			},
+		}
+		Expect(k8sClient.Create(ctx, repo)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+		Expect(err).NotTo(HaveOccurred())
+		initialKey := packagecache.Key{Repository: dir, Commit: initial.String(), Directory: "web"}
+		_, found := packages.Get(initialKey)
+		Expect(found).To(BeTrue())
+
+		By("invalidating the contents of refs that have moved")
+		changed := commit(map[string]string{"web/service.yaml": "kind: Service\nspec: {}\n"})
+		_, err = remote.CreateTag("web/v1", changed, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+		Expect(err).NotTo(HaveOccurred())
+		_, found = packages.Get(initialKey)
+		Expect(found).To(BeFalse())
+		_, found = packages.Get(packagecache.Key{Repository: dir, Commit: changed.String(), Directory: "web"})
+		Expect(found).To(BeTrue())
+
+		By("restoring deleted contents of a Published revision once git is gone")
+		Expect(os.RemoveAll(dir)).To(Succeed())
+		prr := &cachev1alpha1.PackageRevisionResources{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "apps.web.v1"}, prr)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, prr)).To(Succeed())
+
+		prReconciler := &PackageRevisionReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Packages: packages}
+		_, err = prReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "apps.web.v1"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contentsOf("apps.web.v1")["service.yaml"]).To(ContainSubstring("spec: {}"))
+
+		By("locking a clone to the commit of its upstream, and comparing the clone to the commit")
+		clone := &cachev1alpha1.PackageRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "apps.web-edge.draft", Namespace: namespace},
+			Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "web-edge", RepositoryName: "apps", WorkspaceName: "draft",
+				Lifecycle: cachev1alpha1.PackageRevisionLifecycleDraft,
+				Tasks: []cachev1alpha1.Task{{Type: cachev1alpha1.TaskTypeClone, Clone: &cachev1alpha1.PackageCloneTaskSpec{
+					Upstream: cachev1alpha1.UpstreamPackage{UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: "apps.web.v1"}},
+				}}}},
+		}
+		Expect(k8sClient.Create(ctx, clone)).To(Succeed())
+		for range 2 {
+			_, err = prReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(clone)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(clone), clone)).To(Succeed())
+		Expect(clone.Status.UpstreamLock).To(Equal(&cachev1alpha1.UpstreamLock{Type: cachev1alpha1.OriginTypeGit,
+			Git: &cachev1alpha1.GitLock{Repo: dir, Directory: "web", Ref: "refs/tags/web/v1", Commit: changed.String()}}))
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "apps.web.v1"}, prr)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, prr)).To(Succeed())
+		response, err := diff.Revisions(ctx, k8sClient, packages, &cachev1alpha1.DiffRequest{Namespace: namespace, To: clone.Name})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.From).To(Equal(dir + "//web@" + changed.String()))
+		Expect(response.Resources).To(ConsistOf(
+			And(HaveField("Name", "web"), HaveField("Change", cachev1alpha1.ChangeRemoved)),
+			And(HaveField("Name", "web-edge"), HaveField("Change", cachev1alpha1.ChangeAdded))))
+	})
+
+	It("should flag published revisions whose tags are moved out of band", func() {
+		kptfile := "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: web\n"
+		published := commit(map[string]string{"packages/web/Kptfile": kptfile, "packages/web/service.yaml": "kind: Service\n"})
+		_, err := remote.CreateTag("web/v1", published, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		repo := &cachev1alpha1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Name: "tampered", Namespace: namespace},
+			Spec: cachev1alpha1.RepositorySpec{
+				Type: cachev1alpha1.RepositoryTypeGit,
+				Git:  &cachev1alpha1.GitRepository{Repo: dir, Directory: "/packages"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, repo)).To(Succeed())
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		syncRepository := func() *cachev1alpha1.PackageRevision {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+			Expect(err).NotTo(HaveOccurred())
+			pr := &cachev1alpha1.PackageRevision{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "tampered.web.v1"}, pr)).To(Succeed())
+			return pr
+		}
+		pr := syncRepository()
+		Expect(meta.FindStatusCondition(pr.Status.Conditions, typeTamperedPackageRevision)).To(BeNil())
+
+		By("moving the tag to another commit")
+		rewritten := commit(map[string]string{"packages/web/service.yaml": "kind: Service # rewritten\n"})
+		Expect(remote.DeleteTag("web/v1")).To(Succeed())
+		_, err = remote.CreateTag("web/v1", rewritten, nil)
+		Expect(err).NotTo(HaveOccurred())
+		for range 2 {
+			pr = syncRepository()
+		}
+		Expect(meta.IsStatusConditionTrue(pr.Status.Conditions, typeTamperedPackageRevision)).To(BeTrue())
+		Expect(pr.Annotations[CommitAnnotation]).To(Equal(published.String()))
+		Expect(contentsOf(pr.Name)["service.yaml"]).To(Equal("kind: Service\n"))
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(HavePrefix("Warning Tampered refs/tags/web/v1 was moved"))
+
+		By("moving the tag back")
+		Expect(remote.DeleteTag("web/v1")).To(Succeed())
+		_, err = remote.CreateTag("web/v1", published, nil)
+		Expect(err).NotTo(HaveOccurred())
+		pr = syncRepository()
+		Expect(meta.FindStatusCondition(pr.Status.Conditions, typeTamperedPackageRevision)).To(BeNil())
+	})
+
+	It("should retire revisions whose refs come to name another package or workspace", func() {
+		kptfile := func(name string) string {
+			return "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: " + name + "\n"
+		}
+		initial := commit(map[string]string{"packages/a/Kptfile": kptfile("a"), "packages/a/web/Kptfile": kptfile("web")})
+		_, err := remote.CreateTag("a/web/v1", initial, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		repo := &cachev1alpha1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Name: "renamed", Namespace: namespace},
+			Spec: cachev1alpha1.RepositorySpec{
+				Type: cachev1alpha1.RepositoryTypeGit,
+				Git:  &cachev1alpha1.GitRepository{Repo: dir, Directory: "/packages"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, repo)).To(Succeed())
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		syncRepository := func() *cachev1alpha1.PackageRevision {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+			Expect(err).NotTo(HaveOccurred())
+			pr := &cachev1alpha1.PackageRevision{}
+			err = k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "renamed.a.web.v1"}, pr)
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			Expect(err).NotTo(HaveOccurred())
+			return pr
+		}
+		pr := syncRepository()
+		Expect(pr.Spec.PackageName).To(Equal("a/web"))
+		Expect(pr.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecyclePublished))
+
+		By("replacing the tag of the published revision by a draft of another package of the same name")
+		Expect(remote.DeleteTag("a/web/v1")).To(Succeed())
+		checkout("drafts/a/web.v1", true)
+		checkout("main", false)
+		for range 2 {
+			pr = syncRepository()
+		}
+		Expect(pr.Spec.PackageName).To(Equal("a/web"))
+		Expect(pr.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecycleDeletionProposed))
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(Equal("Warning Renamed refs/heads/drafts/a/web.v1 now names package a " +
+			"in workspace web.v1, proposing the deletion of the revision"))
+
+		By("approving the deletion")
+		Expect(k8sClient.Delete(ctx, pr)).To(Succeed())
+		pr = syncRepository()
+		Expect(pr.Spec.PackageName).To(Equal("a"))
+		Expect(pr.Spec.WorkspaceName).To(Equal("web.v1"))
+		Expect(pr.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecycleDraft))
+
+		By("replacing the draft by a draft of the first package")
+		Expect(remote.Storer.RemoveReference(plumbing.NewBranchReferenceName("drafts/a/web.v1"))).To(Succeed())
+		checkout("drafts/a/web/v1", true)
+		checkout("main", false)
+		Expect(syncRepository()).To(BeNil())
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(Equal("Warning Renamed refs/heads/drafts/a/web/v1 now names package a/web " +
+			"in workspace v1, deleting the revision"))
+		pr = syncRepository()
+		Expect(pr.Spec.PackageName).To(Equal("a/web"))
+		Expect(pr.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecycleDraft))
+	})
+
+	It("should report repositories that cannot be fetched", func() {
+		repo := &cachev1alpha1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: namespace},
+			Spec: cachev1alpha1.RepositorySpec{
+				Type: cachev1alpha1.RepositoryTypeGit,
+				Git:  &cachev1alpha1.GitRepository{Repo: filepath.Join(dir, "missing")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, repo)).To(Succeed())
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+
+		for range 2 {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(repo), repo)).To(Succeed())
+		condition := meta.FindStatusCondition(repo.Status.Conditions, typeReadyRepository)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("FetchFailed"))
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(HavePrefix("Warning SyncFailed"))
+	})
+
+	It("should only sync the repositories of the shards it owns", func() {
+		repo := &cachev1alpha1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Name: "sharded", Namespace: namespace},
+			Spec: cachev1alpha1.RepositorySpec{
+				Type: cachev1alpha1.RepositoryTypeGit,
+				Git:  &cachev1alpha1.GitRepository{Repo: filepath.Join(dir, "missing")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, repo)).To(Succeed())
+		reconciler.Shards = &shard.Shards{Client: k8sClient, Namespace: namespace, Identity: "replica-a", Count: 4}
+
+		By("ignoring the repository while another replica owns its shard")
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(repo), repo)).To(Succeed())
+		Expect(repo.Status.LastSyncTime).To(BeNil())
+
+		By("syncing the repository once the shard is acquired")
+		shardCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() { done <- reconciler.Shards.Start(shardCtx) }()
+		defer func() {
+			cancel()
+			Expect(<-done).To(Succeed())
+		}()
+		Eventually(reconciler.Shards.Owned).Should(HaveLen(4))
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(repo)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(repo), repo)).To(Succeed())
+		Expect(repo.Status.LastSyncTime).NotTo(BeNil())
+		Expect(repo.Status.Shard).To(Equal(&cachev1alpha1.RepositoryShard{
+			Index: int32(reconciler.Shards.For(namespace, "sharded")), Owner: "replica-a"}))
+	})
+})
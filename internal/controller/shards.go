@@ -0,0 +1,58 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/liamfallon/porch-operator/internal/shard"
+)
+
+// watchShards makes a controller reconcile the objects of the shards this replica acquires, since
+// the events about them were ignored while another replica owned them. The objects are listed
+// with the list, and shardOf returns the shard of each of them.
+func watchShards(b *builder.Builder, c client.Client, shards *shard.Shards, list client.ObjectList,
+	shardOf func(client.Object) int) *builder.Builder {
+	if shards == nil {
+		return b
+	}
+	return b.WatchesRawSource(source.TypedChannel(shards.Subscribe(),
+		handler.TypedEnqueueRequestsFromMapFunc(func(ctx context.Context, acquired int) []reconcile.Request {
+			objects := list.DeepCopyObject().(client.ObjectList)
+			if err := c.List(ctx, objects); err != nil {
+				logf.FromContext(ctx).Error(err, "Failed to list the objects of an acquired shard", "shard", acquired)
+				return nil
+			}
+			var requests []reconcile.Request
+			_ = meta.EachListItem(objects, func(item runtime.Object) error {
+				if obj := item.(client.Object); shardOf(obj) == acquired {
+					requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+				}
+				return nil
+			})
+			return requests
+		})))
+}
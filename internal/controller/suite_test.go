@@ -74,6 +74,11 @@ var _ = BeforeSuite(func() {
 	if getFirstFoundEnvTestBinaryDir() != "" {
 		testEnv.BinaryAssetsDirectory = getFirstFoundEnvTestBinaryDir()
 	}
+	if testEnv.BinaryAssetsDirectory == "" && os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		if _, err := os.Stat(filepath.Join("/usr", "local", "kubebuilder", "bin", "etcd")); err != nil {
+			Fail("The envtest binaries are not installed: run 'make setup-envtest' first, or run the tests with 'make test'")
+		}
+	}
 
 	// cfg is defined in this file globally.
 	cfg, err = testEnv.Start()
@@ -0,0 +1,198 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	packagecache "github.com/liamfallon/porch-operator/internal/git/cache"
+)
+
+var _ = Describe("Diff", func() {
+	kptfile := "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: app\n"
+	v1 := map[string]string{
+		"Kptfile": kptfile,
+		"deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app # the app
+  labels:
+    app.kubernetes.io/name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+        args: [--verbose, --port=80]
+`,
+		"service.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n",
+		"README.md":    "# App\n",
+	}
+	v2 := map[string]string{
+		"Kptfile": kptfile,
+		"app.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    app.kubernetes.io/name: app
+    tier: edge
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+        args: [--verbose]
+      - name: proxy
+        image: proxy:v1
+`,
+		"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\n",
+		"README.md":   "# App\n\nThe app.\n",
+	}
+
+	It("should report the resources and fields that differ", func() {
+		resources, files, err := Contents(v1, v2)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(resources).To(HaveLen(3))
+		Expect(resources[0]).To(And(
+			HaveField("Kind", "Deployment"),
+			HaveField("File", "app.yaml"),
+			HaveField("Change", cachev1alpha1.ChangeModified),
+		))
+		Expect(resources[0].Fields).To(Equal([]cachev1alpha1.FieldDiff{
+			{Path: "metadata.labels.tier", Change: cachev1alpha1.ChangeAdded, To: "edge"},
+			{Path: "spec.replicas", Change: cachev1alpha1.ChangeModified, From: "1", To: "3"},
+			{Path: "spec.template.spec.containers[name=app].image", Change: cachev1alpha1.ChangeModified,
+				From: "app:v1", To: "app:v2"},
+			{Path: "spec.template.spec.containers[name=app].args[1]", Change: cachev1alpha1.ChangeRemoved,
+				From: "--port=80"},
+			{Path: "spec.template.spec.containers[name=proxy]", Change: cachev1alpha1.ChangeAdded,
+				To: "name: proxy\nimage: proxy:v1"},
+		}))
+		Expect(resources[1]).To(And(
+			HaveField("Kind", "ConfigMap"),
+			HaveField("File", "config.yaml"),
+			HaveField("Change", cachev1alpha1.ChangeAdded),
+		))
+		Expect(resources[2]).To(And(
+			HaveField("Kind", "Service"),
+			HaveField("File", "service.yaml"),
+			HaveField("Change", cachev1alpha1.ChangeRemoved),
+		))
+
+		Expect(files).To(ConsistOf(And(
+			HaveField("Path", "README.md"),
+			HaveField("Change", cachev1alpha1.ChangeModified),
+			HaveField("Diff", ContainSubstring("+The app.")),
+		)))
+	})
+
+	It("should report no differences between identical contents", func() {
+		resources, files, err := Contents(v1, v1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resources).To(BeEmpty())
+		Expect(files).To(BeEmpty())
+	})
+
+	It("should compare a revision to its upstream", func() {
+		scheme := runtime.NewScheme()
+		Expect(cachev1alpha1.AddToScheme(scheme)).To(Succeed())
+		revision := func(name string, tasks ...cachev1alpha1.Task) *cachev1alpha1.PackageRevision {
+			return &cachev1alpha1.PackageRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       cachev1alpha1.PackageRevisionSpec{PackageName: "app", Tasks: tasks},
+			}
+		}
+		contents := func(name string, resources map[string]string) *cachev1alpha1.PackageRevisionResources {
+			return &cachev1alpha1.PackageRevisionResources{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       cachev1alpha1.PackageRevisionResourcesSpec{Resources: resources},
+			}
+		}
+		clone := cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeClone, Clone: &cachev1alpha1.PackageCloneTaskSpec{
+			Upstream: cachev1alpha1.UpstreamPackage{UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: "blueprints.app.v1"}},
+		}}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			revision("blueprints.app.v1"), contents("blueprints.app.v1", v1),
+			revision("edge.app.draft", clone), contents("edge.app.draft", v2),
+			revision("edge.app.new"),
+		).Build()
+
+		response, err := Revisions(context.Background(), c, nil, &cachev1alpha1.DiffRequest{Namespace: "default", To: "edge.app.draft"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.From).To(Equal("blueprints.app.v1"))
+		Expect(response.Resources).To(HaveLen(3))
+
+		_, err = Revisions(context.Background(), c, nil, &cachev1alpha1.DiffRequest{Namespace: "default", To: "edge.app.new"})
+		Expect(err).To(MatchError(ContainSubstring("has no upstream")))
+
+		response, err = Revisions(context.Background(), c, nil,
+			&cachev1alpha1.DiffRequest{Namespace: "default", From: "edge.app.draft", To: "edge.app.new"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Resources).To(HaveLen(3))
+		Expect(response.Resources).To(HaveEach(HaveField("Change", cachev1alpha1.ChangeRemoved)))
+
+		_, err = Revisions(context.Background(), c, nil, &cachev1alpha1.DiffRequest{Namespace: "default", To: "missing"})
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should compare a revision to the commit of its upstream lock", func() {
+		scheme := runtime.NewScheme()
+		Expect(cachev1alpha1.AddToScheme(scheme)).To(Succeed())
+		packages, err := packagecache.New(1<<20, "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		lock := &cachev1alpha1.GitLock{Repo: "https://example.com/blueprints.git", Directory: "packages/app",
+			Ref: "refs/tags/app/v1", Commit: "abc123"}
+		Expect(packages.Add(packagecache.Key{Repository: lock.Repo, Commit: lock.Commit, Directory: lock.Directory}, v1)).To(Succeed())
+
+		draft := &cachev1alpha1.PackageRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "edge.app.draft", Namespace: "default"},
+			Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", Tasks: []cachev1alpha1.Task{{
+				Type: cachev1alpha1.TaskTypeClone, Clone: &cachev1alpha1.PackageCloneTaskSpec{Upstream: cachev1alpha1.UpstreamPackage{
+					Type: cachev1alpha1.RepositoryTypeGit, Git: &cachev1alpha1.GitPackage{Repo: lock.Repo, Ref: "app/v1"}},
+				}}}},
+			Status: cachev1alpha1.PackageRevisionStatus{UpstreamLock: &cachev1alpha1.UpstreamLock{
+				Type: cachev1alpha1.OriginTypeGit, Git: lock}},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(draft, &cachev1alpha1.PackageRevisionResources{
+			ObjectMeta: metav1.ObjectMeta{Name: draft.Name, Namespace: "default"},
+			Spec:       cachev1alpha1.PackageRevisionResourcesSpec{Resources: v2},
+		}).Build()
+
+		response, err := Revisions(context.Background(), c, packages, &cachev1alpha1.DiffRequest{Namespace: "default", To: draft.Name})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.From).To(Equal("https://example.com/blueprints.git//packages/app@abc123"))
+		Expect(response.Resources).To(HaveLen(3))
+
+		By("comparing to an upstream lock whose contents are not cached")
+		Expect(packages.Invalidate(packagecache.Key{Repository: lock.Repo, Commit: lock.Commit, Directory: lock.Directory})).To(Succeed())
+		_, err = Revisions(context.Background(), c, packages, &cachev1alpha1.DiffRequest{Namespace: "default", To: draft.Name})
+		Expect(err).To(MatchError(ContainSubstring("which is not a PackageRevision")))
+	})
+})
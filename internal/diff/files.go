@@ -0,0 +1,96 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diff compares versions of package contents.
+package diff
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+// contextLines is the number of unchanged lines shown around each change in a unified diff.
+const contextLines = 3
+
+// Files returns the files that differ between two versions of package contents, keyed by file
+// path, ordered by path.
+func Files(from, to map[string]string) ([]cachev1alpha1.FileDiff, error) {
+	paths := slices.Sorted(maps.Keys(from))
+	for path := range to {
+		if _, found := from[path]; !found {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	var diffs []cachev1alpha1.FileDiff
+	for _, path := range paths {
+		before, existed := from[path]
+		after, exists := to[path]
+		fileDiff := cachev1alpha1.FileDiff{Path: path}
+		switch {
+		case !existed:
+			fileDiff.Change = cachev1alpha1.ChangeAdded
+		case !exists:
+			fileDiff.Change = cachev1alpha1.ChangeRemoved
+		case before != after:
+			fileDiff.Change = cachev1alpha1.ChangeModified
+		default:
+			continue
+		}
+
+		unified := difflib.UnifiedDiff{
+			A:       lines(before),
+			B:       lines(after),
+			Context: contextLines,
+		}
+		if existed {
+			unified.FromFile = "a/" + path
+		} else {
+			unified.FromFile = "/dev/null"
+		}
+		if exists {
+			unified.ToFile = "b/" + path
+		} else {
+			unified.ToFile = "/dev/null"
+		}
+		var err error
+		if fileDiff.Diff, err = difflib.GetUnifiedDiffString(unified); err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, fileDiff)
+	}
+	return diffs, nil
+}
+
+// lines splits text into lines, each ending in a newline.
+func lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	split := strings.SplitAfter(text, "\n")
+	if last := len(split) - 1; split[last] == "" {
+		split = split[:last]
+	} else {
+		split[last] += "\n"
+	}
+	return split
+}
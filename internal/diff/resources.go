@@ -0,0 +1,303 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+// plainKey matches the map keys that are written in field paths as they are.
+var plainKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// resource is a KRM resource in a version of package contents.
+type resource struct {
+	node *yaml.RNode
+	file string
+}
+
+// Contents returns the differences between two versions of package contents: the KRM resources
+// that differ, ordered by file, and the files holding no KRM resources that differ, ordered by
+// path.
+//
+// Resources are matched between the versions by group, kind, namespace, name and the directory
+// of their file, so a resource that moved to another file of the same package is modified
+// rather than removed and added. Comments and formatting are not compared.
+func Contents(from, to map[string]string) ([]cachev1alpha1.ResourceDiff, []cachev1alpha1.FileDiff, error) {
+	fromResources, err := readResources(from)
+	if err != nil {
+		return nil, nil, err
+	}
+	toResources, err := readResources(to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resources []cachev1alpha1.ResourceDiff
+	for id, after := range toResources {
+		resourceDiff := resourceDiffOf(after)
+		before, found := fromResources[id]
+		switch {
+		case !found:
+			resourceDiff.Change = cachev1alpha1.ChangeAdded
+		default:
+			resourceDiff.Fields = fields("", before.node.YNode(), after.node.YNode(), nil)
+			if len(resourceDiff.Fields) == 0 {
+				continue
+			}
+			resourceDiff.Change = cachev1alpha1.ChangeModified
+		}
+		resources = append(resources, resourceDiff)
+	}
+	for id, before := range fromResources {
+		if _, found := toResources[id]; !found {
+			resourceDiff := resourceDiffOf(before)
+			resourceDiff.Change = cachev1alpha1.ChangeRemoved
+			resources = append(resources, resourceDiff)
+		}
+	}
+	slices.SortFunc(resources, func(a, b cachev1alpha1.ResourceDiff) int {
+		return slices.Compare([]string{a.File, a.Kind, a.Namespace, a.Name}, []string{b.File, b.Kind, b.Namespace, b.Name})
+	})
+
+	// Only the files that hold no resources in either version are compared as files.
+	resourceFiles := map[string]bool{}
+	for _, r := range fromResources {
+		resourceFiles[r.file] = true
+	}
+	for _, r := range toResources {
+		resourceFiles[r.file] = true
+	}
+	otherFiles := func(contents map[string]string) map[string]string {
+		others := maps.Clone(contents)
+		maps.DeleteFunc(others, func(file, _ string) bool { return resourceFiles[file] })
+		return others
+	}
+	files, err := Files(otherFiles(from), otherFiles(to))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resources, files, nil
+}
+
+// readResources returns the resources of package contents by their identity. The annotations
+// that record the files of resources are removed, so that they are not compared.
+func readResources(contents map[string]string) (map[string]*resource, error) {
+	nodes, err := kpt.ReadResources(contents)
+	if err != nil {
+		return nil, err
+	}
+	resources := map[string]*resource{}
+	for _, node := range nodes {
+		id := kpt.IDOf(node)
+		if _, found := resources[id]; found {
+			return nil, fmt.Errorf("%s %q is declared more than once in package %s", node.GetKind(), node.GetName(),
+				path.Dir(kpt.PathOf(node)))
+		}
+		file := kpt.PathOf(node)
+		if node, err = kpt.WithoutFileAnnotations(node); err != nil {
+			return nil, err
+		}
+		resources[id] = &resource{node: node, file: file}
+	}
+	return resources, nil
+}
+
+// resourceDiffOf returns a ResourceDiff identifying the resource.
+func resourceDiffOf(r *resource) cachev1alpha1.ResourceDiff {
+	return cachev1alpha1.ResourceDiff{
+		APIVersion: r.node.GetApiVersion(),
+		Kind:       r.node.GetKind(),
+		Name:       r.node.GetName(),
+		Namespace:  r.node.GetNamespace(),
+		File:       r.file,
+	}
+}
+
+// fields appends the differences between two values of the field at the path to diffs.
+func fields(fieldPath string, before, after *yaml.Node, diffs []cachev1alpha1.FieldDiff) []cachev1alpha1.FieldDiff {
+	before, after = Resolve(before), Resolve(after)
+	switch {
+	case before.Kind == yaml.MappingNode && after.Kind == yaml.MappingNode:
+		beforeFields, afterFields := mapFields(before), mapFields(after)
+		for _, key := range fieldKeys(before, after) {
+			diffs = fieldsOrChange(FieldPath(fieldPath, key), beforeFields[key], afterFields[key], diffs)
+		}
+		return diffs
+	case before.Kind == yaml.SequenceNode && after.Kind == yaml.SequenceNode:
+		beforeNames, afterNames := ElementNames(before), ElementNames(after)
+		if beforeNames != nil && afterNames != nil {
+			beforeElements, afterElements := map[string]*yaml.Node{}, map[string]*yaml.Node{}
+			var names []string
+			for i, name := range beforeNames {
+				beforeElements[name] = before.Content[i]
+				names = append(names, name)
+			}
+			for i, name := range afterNames {
+				if _, found := beforeElements[name]; !found {
+					names = append(names, name)
+				}
+				afterElements[name] = after.Content[i]
+			}
+			for _, name := range names {
+				diffs = fieldsOrChange(ElementPath(fieldPath, name), beforeElements[name], afterElements[name], diffs)
+			}
+			return diffs
+		}
+		for i := range max(len(before.Content), len(after.Content)) {
+			var beforeElement, afterElement *yaml.Node
+			if i < len(before.Content) {
+				beforeElement = before.Content[i]
+			}
+			if i < len(after.Content) {
+				afterElement = after.Content[i]
+			}
+			diffs = fieldsOrChange(fieldPath+"["+strconv.Itoa(i)+"]", beforeElement, afterElement, diffs)
+		}
+		return diffs
+	case before.Kind == yaml.ScalarNode && after.Kind == yaml.ScalarNode &&
+		before.Value == after.Value && before.ShortTag() == after.ShortTag():
+		return diffs
+	}
+	return append(diffs, cachev1alpha1.FieldDiff{Path: fieldPath, Change: cachev1alpha1.ChangeModified,
+		From: Encode(before), To: Encode(after)})
+}
+
+// EqualNodes returns true if two values are the same, ignoring comments and formatting.
+func EqualNodes(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return len(fields("", a, b, nil)) == 0
+}
+
+// fieldsOrChange appends the differences between two values of the field at the path to diffs,
+// where either value may be missing.
+func fieldsOrChange(fieldPath string, before, after *yaml.Node, diffs []cachev1alpha1.FieldDiff) []cachev1alpha1.FieldDiff {
+	switch {
+	case before == nil:
+		return append(diffs, cachev1alpha1.FieldDiff{Path: fieldPath, Change: cachev1alpha1.ChangeAdded, To: Encode(after)})
+	case after == nil:
+		return append(diffs, cachev1alpha1.FieldDiff{Path: fieldPath, Change: cachev1alpha1.ChangeRemoved, From: Encode(before)})
+	}
+	return fields(fieldPath, before, after, diffs)
+}
+
+// Resolve returns the node an alias or document refers to.
+func Resolve(node *yaml.Node) *yaml.Node {
+	for {
+		switch {
+		case node.Kind == yaml.AliasNode && node.Alias != nil:
+			node = node.Alias
+		case node.Kind == yaml.DocumentNode && len(node.Content) == 1:
+			node = node.Content[0]
+		default:
+			return node
+		}
+	}
+}
+
+// mapFields returns the values of the fields of a mapping by key.
+func mapFields(node *yaml.Node) map[string]*yaml.Node {
+	values := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		values[node.Content[i].Value] = node.Content[i+1]
+	}
+	return values
+}
+
+// fieldKeys returns the keys of the fields of two mappings, in the order of the first followed
+// by the keys only in the second.
+func fieldKeys(before, after *yaml.Node) []string {
+	var keys []string
+	for _, node := range []*yaml.Node{before, after} {
+		for i := 0; i < len(node.Content); i += 2 {
+			if !slices.Contains(keys, node.Content[i].Value) {
+				keys = append(keys, node.Content[i].Value)
+			}
+		}
+	}
+	return keys
+}
+
+// ElementNames returns the names of the elements of a sequence, or nil if not every element is
+// a mapping with a unique name.
+func ElementNames(node *yaml.Node) []string {
+	names := make([]string, 0, len(node.Content))
+	for _, element := range node.Content {
+		element = Resolve(element)
+		if element.Kind != yaml.MappingNode {
+			return nil
+		}
+		name := mapFields(element)["name"]
+		if name == nil || name.Kind != yaml.ScalarNode || name.Value == "" || slices.Contains(names, name.Value) {
+			return nil
+		}
+		names = append(names, name.Value)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return names
+}
+
+// FieldPath returns the path of the field with the key in the mapping at the parent path.
+func FieldPath(parent, key string) string {
+	switch {
+	case !plainKey.MatchString(key):
+		return parent + "[" + strconv.Quote(key) + "]"
+	case parent == "":
+		return key
+	}
+	return parent + "." + key
+}
+
+// ElementPath returns the path of the element with the name in the sequence at the parent path.
+func ElementPath(parent, name string) string {
+	return fmt.Sprintf("%s[name=%s]", parent, name)
+}
+
+// Encode returns a value encoded as YAML, without comments.
+func Encode(node *yaml.Node) string {
+	node = stripComments(node)
+	text, err := yaml.String(node)
+	if err != nil {
+		return node.Value
+	}
+	return strings.TrimSuffix(text, "\n")
+}
+
+// stripComments returns a copy of a node without comments.
+func stripComments(node *yaml.Node) *yaml.Node {
+	stripped := *node
+	stripped.HeadComment, stripped.LineComment, stripped.FootComment = "", "", ""
+	stripped.Content = make([]*yaml.Node, 0, len(node.Content))
+	for _, child := range node.Content {
+		stripped.Content = append(stripped.Content, stripComments(child))
+	}
+	return &stripped
+}
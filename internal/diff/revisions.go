@@ -0,0 +1,184 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	packagecache "github.com/liamfallon/porch-operator/internal/git/cache"
+)
+
+// Path is the path the diff API is served on.
+const Path = "/diff"
+
+// maxRequestSize is the maximum size of the body of a diff request, in bytes.
+const maxRequestSize = 64 << 10
+
+// ErrNotFound is returned when a PackageRevision to compare does not exist.
+var ErrNotFound = errors.New("package revision not found")
+
+// Revisions returns the differences between the contents of two PackageRevisions. A
+// PackageRevision compared to its upstream is compared to the contents at the commit of its
+// upstream lock when they are in the package cache, which may be nil, and otherwise to the
+// contents of its upstream PackageRevision.
+func Revisions(ctx context.Context, reader client.Reader, packages *packagecache.Cache,
+	request *cachev1alpha1.DiffRequest) (*cachev1alpha1.DiffResponse, error) {
+	to, err := getRevision(ctx, reader, request.Namespace, request.To)
+	if err != nil {
+		return nil, err
+	}
+	toContents, err := getContents(ctx, reader, to)
+	if err != nil {
+		return nil, err
+	}
+
+	fromName, fromContents, found := "", map[string]string(nil), false
+	if request.From == "" {
+		fromName, fromContents, found = lockedContents(to, packages)
+	}
+	if !found {
+		if fromName = request.From; fromName == "" {
+			if fromName, err = UpstreamOf(to); err != nil {
+				return nil, err
+			}
+		}
+		from, err := getRevision(ctx, reader, request.Namespace, fromName)
+		if err != nil {
+			return nil, err
+		}
+		if fromContents, err = getContents(ctx, reader, from); err != nil {
+			return nil, err
+		}
+	}
+	response := &cachev1alpha1.DiffResponse{From: fromName, To: to.Name}
+	if response.Resources, response.Files, err = Contents(fromContents, toContents); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// UpstreamOf returns the name of the upstream PackageRevision of a PackageRevision: the
+// revision it was last upgraded to, or else the revision it was cloned from.
+func UpstreamOf(pr *cachev1alpha1.PackageRevision) (string, error) {
+	for _, task := range slices.Backward(pr.Spec.Tasks) {
+		switch {
+		case task.Type == cachev1alpha1.TaskTypeUpgrade && task.Upgrade != nil:
+			return task.Upgrade.NewUpstream.Name, nil
+		case task.Type == cachev1alpha1.TaskTypeClone && task.Clone != nil && task.Clone.Upstream.UpstreamRef != nil:
+			return task.Clone.Upstream.UpstreamRef.Name, nil
+		case task.Type == cachev1alpha1.TaskTypeClone && task.Clone != nil && task.Clone.Upstream.Git != nil:
+			git := task.Clone.Upstream.Git
+			return "", fmt.Errorf("upstream of PackageRevision %q is %s of %s, which is not a PackageRevision",
+				pr.Name, git.Ref, git.Repo)
+		}
+	}
+	if lock := pr.Status.UpstreamLock; lock != nil && lock.Git != nil {
+		return "", fmt.Errorf("upstream of PackageRevision %q is commit %s of %s, which is not a PackageRevision",
+			pr.Name, lock.Git.Commit, lock.Git.Repo)
+	}
+	return "", fmt.Errorf("PackageRevision %q has no upstream", pr.Name)
+}
+
+// lockedContents returns the locator of the commit of the upstream lock of a PackageRevision and
+// the contents of the upstream package at the commit, or false if they are not in the package cache.
+func lockedContents(pr *cachev1alpha1.PackageRevision, packages *packagecache.Cache) (string, map[string]string, bool) {
+	lock := pr.Status.UpstreamLock
+	if packages == nil || lock == nil || lock.Git == nil || lock.Git.Commit == "" {
+		return "", nil, false
+	}
+	contents, found := packages.Get(packagecache.Key{Repository: lock.Git.Repo, Commit: lock.Git.Commit,
+		Directory: lock.Git.Directory})
+	if !found {
+		return "", nil, false
+	}
+	return fmt.Sprintf("%s//%s@%s", lock.Git.Repo, lock.Git.Directory, lock.Git.Commit), contents, true
+}
+
+// getRevision gets a PackageRevision.
+func getRevision(ctx context.Context, reader client.Reader, namespace, name string) (*cachev1alpha1.PackageRevision, error) {
+	pr := &cachev1alpha1.PackageRevision{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, pr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, namespace, name)
+		}
+		return nil, err
+	}
+	return pr, nil
+}
+
+// getContents returns the contents of a PackageRevision, which are empty until they are produced.
+func getContents(ctx context.Context, reader client.Reader, pr *cachev1alpha1.PackageRevision) (map[string]string, error) {
+	prr := &cachev1alpha1.PackageRevisionResources{}
+	if err := reader.Get(ctx, client.ObjectKeyFromObject(pr), prr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read contents of PackageRevision %q: %w", pr.Name, err)
+	}
+	return prr.Spec.Resources, nil
+}
+
+// Handler serves diffs between PackageRevisions. It reads a DiffRequest from the body of a POST
+// request, and writes a DiffResponse.
+type Handler struct {
+	Reader client.Reader
+
+	// Packages is the package cache the contents of upstream locks are read from, if any.
+	Packages *packagecache.Cache
+}
+
+var _ http.Handler = &Handler{}
+
+// ServeHTTP serves a diff request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	request := &cachev1alpha1.DiffRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxRequestSize)).Decode(request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid diff request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if request.Namespace == "" || request.To == "" {
+		http.Error(w, "namespace and to are required", http.StatusBadRequest)
+		return
+	}
+
+	response, err := Revisions(req.Context(), h.Reader, h.Packages, request)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
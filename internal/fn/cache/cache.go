@@ -0,0 +1,233 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cache caches the output of KRM functions, so that functions whose input has not
+// changed since they were last run are not run again.
+//
+// Outputs are keyed by a digest of the function image, its input ResourceList, which holds the
+// function config, and the memory and step limits it is run with. Keys depend on nothing but the
+// function, its input and its limits, so a cache kept on disk stays valid across restarts of the
+// operator. Functions are expected to be deterministic: a function whose image is not pinned to a
+// digest is cached under its tag, so a tag that is moved to a different function is only picked
+// up once the cached outputs are evicted.
+//
+// A cached output is only served once the runner below the cache has checked the function, if it
+// is a fn.Checker, so that a function that may no longer be run, such as a WebAssembly module
+// whose digest is no longer allowed, is refused rather than served from the cache.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+// keyVersion is part of every key, and is changed whenever the way function outputs are
+// produced changes, so that outputs cached by earlier versions are not used.
+const keyVersion = "v2"
+
+var (
+	hits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "porch_function_cache_hits_total",
+		Help: "Number of function runs whose output was found in the function cache.",
+	})
+	misses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "porch_function_cache_misses_total",
+		Help: "Number of function runs whose output was not found in the function cache.",
+	})
+	evictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "porch_function_cache_evictions_total",
+		Help: "Number of function outputs evicted from the function cache.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(hits, misses, evictions)
+}
+
+// Cache stores function outputs by key.
+type Cache interface {
+	// Get returns the output stored under the key, if any.
+	Get(key string) ([]byte, bool)
+
+	// Add stores the output under the key, evicting other outputs if the cache is full.
+	Add(key string, output []byte) error
+}
+
+// Runner is a fn.Runner that caches the outputs of another Runner. Only the outputs of
+// functions that succeed are cached.
+type Runner struct {
+	// Runner runs the functions whose output is not cached.
+	Runner fn.Runner
+
+	// Cache stores the function outputs.
+	Cache Cache
+}
+
+var _ fn.Runner = &Runner{}
+
+// Run returns the cached output of the function for the input, once the function is checked, or
+// runs the function and caches its output.
+func (r *Runner) Run(ctx context.Context, function *kpt.Function, input []byte) ([]byte, error) {
+	key := Key(ctx, function, input)
+	if output, found := r.Cache.Get(key); found {
+		if err := fn.Check(ctx, r.Runner, function); err != nil {
+			return nil, err
+		}
+		hits.Inc()
+		return output, nil
+	}
+	misses.Inc()
+
+	output, err := r.Runner.Run(ctx, function, input)
+	if err != nil {
+		return output, err
+	}
+	if err := r.Cache.Add(key, output); err != nil {
+		// The output is still good, it just has to be produced again next time.
+		logf.FromContext(ctx).Error(err, "Failed to cache function output", "image", function.Image)
+	}
+	return output, nil
+}
+
+// Key returns the key of the output of the function for the input, the hex encoded SHA-256
+// digest of the function image, the input, and the memory and step limits of the context. A
+// function that succeeds within limits may fail within lower limits, so its output is only served
+// to runs with the same limits.
+func Key(ctx context.Context, function *kpt.Function, input []byte) string {
+	limits := binary.BigEndian.AppendUint64(nil, fn.MemoryLimit(ctx))
+	limits = binary.BigEndian.AppendUint64(limits, fn.MaxSteps(ctx))
+	hash := sha256.New()
+	for _, part := range [][]byte{[]byte(keyVersion), []byte(function.Image), input, limits} {
+		// Each part is preceded by its length, so that parts cannot run into each other.
+		hash.Write(binary.BigEndian.AppendUint64(nil, uint64(len(part))))
+		hash.Write(part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// lru tracks the sizes of the entries of a cache, from most to least recently used, and which
+// entries to evict to keep the cache within its size. It is not safe for concurrent use.
+type lru struct {
+	maxSize int64
+	size    int64
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// lruEntry is an entry tracked by an lru.
+type lruEntry struct {
+	key  string
+	size int64
+}
+
+func newLRU(maxSize int64) *lru {
+	return &lru{maxSize: maxSize, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// touch marks the entry as the most recently used, and returns false if there is no such entry.
+func (l *lru) touch(key string) bool {
+	element, found := l.entries[key]
+	if found {
+		l.order.MoveToFront(element)
+	}
+	return found
+}
+
+// add adds or replaces an entry as the most recently used, and returns the keys of the entries
+// that must be evicted, which may include the entry itself if it is larger than the cache.
+func (l *lru) add(key string, size int64) []string {
+	if element, found := l.entries[key]; found {
+		l.size -= element.Value.(*lruEntry).size
+		element.Value.(*lruEntry).size = size
+		l.order.MoveToFront(element)
+	} else {
+		l.entries[key] = l.order.PushFront(&lruEntry{key: key, size: size})
+	}
+	l.size += size
+
+	var evicted []string
+	for l.size > l.maxSize && l.order.Len() > 0 {
+		entry := l.order.Remove(l.order.Back()).(*lruEntry)
+		delete(l.entries, entry.key)
+		l.size -= entry.size
+		evicted = append(evicted, entry.key)
+	}
+	evictions.Add(float64(len(evicted)))
+	return evicted
+}
+
+// remove removes an entry.
+func (l *lru) remove(key string) {
+	if element, found := l.entries[key]; found {
+		l.size -= element.Value.(*lruEntry).size
+		l.order.Remove(element)
+		delete(l.entries, key)
+	}
+}
+
+// MemoryCache is a Cache that keeps outputs in memory, evicting the least recently used outputs
+// once their total size exceeds its size.
+type MemoryCache struct {
+	mu      sync.Mutex
+	lru     *lru
+	outputs map[string][]byte
+}
+
+var _ Cache = &MemoryCache{}
+
+// NewMemoryCache returns a MemoryCache that holds up to maxSize bytes of outputs.
+func NewMemoryCache(maxSize int64) *MemoryCache {
+	return &MemoryCache{lru: newLRU(maxSize), outputs: map[string][]byte{}}
+}
+
+// Get returns the output stored under the key, if any.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.lru.touch(key) {
+		return nil, false
+	}
+	return c.outputs[key], true
+}
+
+// Add stores the output under the key.
+func (c *MemoryCache) Add(key string, output []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.outputs[key] = output
+	for _, evicted := range c.lru.add(key, int64(len(output))) {
+		delete(c.outputs, evicted)
+	}
+	return nil
+}
+
+// Len returns the number of outputs in the cache.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.outputs)
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+// countingRunner counts the functions it runs, and fails the functions with an image of "fail".
+// It refuses the functions whose images are refused.
+type countingRunner struct {
+	runs    int
+	refused map[string]bool
+}
+
+func (r *countingRunner) Check(_ context.Context, function *kpt.Function) error {
+	if r.refused[function.Image] {
+		return errors.New("function refused")
+	}
+	return nil
+}
+
+func (r *countingRunner) Run(_ context.Context, function *kpt.Function, input []byte) ([]byte, error) {
+	r.runs++
+	if function.Image == "fail" {
+		return nil, errors.New("function failed")
+	}
+	return append([]byte(function.Image+":"), input...), nil
+}
+
+// value returns the value of a counter.
+func value(counter prometheus.Counter) float64 {
+	metric := &dto.Metric{}
+	Expect(counter.Write(metric)).To(Succeed())
+	return metric.GetCounter().GetValue()
+}
+
+var _ = Describe("Function Cache", func() {
+	ctx := context.Background()
+	function := &kpt.Function{Image: "example.com/fn:v1"}
+
+	It("should only run a function again when its input changes", func() {
+		inner := &countingRunner{}
+		runner := &Runner{Runner: inner, Cache: NewMemoryCache(1 << 20)}
+		hitsBefore, missesBefore := value(hits), value(misses)
+
+		for range 3 {
+			output, err := runner.Run(ctx, function, []byte("a"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output)).To(Equal("example.com/fn:v1:a"))
+		}
+		Expect(inner.runs).To(Equal(1))
+
+		_, err := runner.Run(ctx, function, []byte("b"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = runner.Run(ctx, &kpt.Function{Image: "example.com/fn:v2"}, []byte("a"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner.runs).To(Equal(3))
+
+		Expect(value(hits) - hitsBefore).To(Equal(2.0))
+		Expect(value(misses) - missesBefore).To(Equal(3.0))
+	})
+
+	It("should not cache failed functions", func() {
+		inner := &countingRunner{}
+		runner := &Runner{Runner: inner, Cache: NewMemoryCache(1 << 20)}
+		for range 2 {
+			_, err := runner.Run(ctx, &kpt.Function{Image: "fail"}, []byte("a"))
+			Expect(err).To(HaveOccurred())
+		}
+		Expect(inner.runs).To(Equal(2))
+	})
+
+	It("should not serve the outputs of functions that the runner refuses or that run within other limits", func() {
+		inner := &countingRunner{refused: map[string]bool{}}
+		runner := &Runner{Runner: inner, Cache: NewMemoryCache(1 << 20)}
+		_, err := runner.Run(ctx, function, []byte("a"))
+		Expect(err).NotTo(HaveOccurred())
+
+		inner.refused[function.Image] = true
+		_, err = runner.Run(ctx, function, []byte("a"))
+		Expect(err).To(MatchError("function refused"))
+		Expect(inner.runs).To(Equal(1))
+
+		inner.refused[function.Image] = false
+		for _, limited := range []context.Context{fn.WithMemoryLimit(ctx, 1<<20), fn.WithMaxSteps(ctx, 1000)} {
+			_, err = runner.Run(limited, function, []byte("a"))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(inner.runs).To(Equal(3))
+	})
+
+	It("should use keys that do not depend on the process", func() {
+		Expect(Key(ctx, function, []byte("a"))).To(Equal(Key(ctx, &kpt.Function{Image: "example.com/fn:v1"}, []byte("a"))))
+		Expect(Key(ctx, function, []byte("a"))).To(HaveLen(64))
+		Expect(Key(ctx, &kpt.Function{Image: "ab"}, []byte("c"))).NotTo(Equal(Key(ctx, &kpt.Function{Image: "a"}, []byte("bc"))))
+	})
+
+	It("should evict the least recently used outputs from memory", func() {
+		cache := NewMemoryCache(10)
+		evictionsBefore := value(evictions)
+		Expect(cache.Add("a", []byte("1234"))).To(Succeed())
+		Expect(cache.Add("b", []byte("1234"))).To(Succeed())
+		_, found := cache.Get("a")
+		Expect(found).To(BeTrue())
+		Expect(cache.Add("c", []byte("1234"))).To(Succeed())
+
+		_, found = cache.Get("b")
+		Expect(found).To(BeFalse())
+		Expect(cache.Len()).To(Equal(2))
+		Expect(value(evictions) - evictionsBefore).To(Equal(1.0))
+
+		Expect(cache.Add("d", []byte("12345678901"))).To(Succeed())
+		_, found = cache.Get("d")
+		Expect(found).To(BeFalse())
+	})
+
+	It("should keep outputs on disk across restarts", func() {
+		dir := GinkgoT().TempDir()
+		cache, err := NewDiskCache(dir, 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		inner := &countingRunner{}
+		_, err = (&Runner{Runner: inner, Cache: cache}).Run(ctx, function, []byte("a"))
+		Expect(err).NotTo(HaveOccurred())
+
+		cache, err = NewDiskCache(dir, 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.Len()).To(Equal(1))
+		output, err := (&Runner{Runner: inner, Cache: cache}).Run(ctx, function, []byte("a"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(output)).To(Equal("example.com/fn:v1:a"))
+		Expect(inner.runs).To(Equal(1))
+	})
+
+	It("should evict the least recently used outputs from disk", func() {
+		dir := GinkgoT().TempDir()
+		cache, err := NewDiskCache(dir, 10)
+		Expect(err).NotTo(HaveOccurred())
+		a, b, c := Key(ctx, function, []byte("a")), Key(ctx, function, []byte("b")), Key(ctx, function, []byte("c"))
+		Expect(cache.Add(a, []byte("1234"))).To(Succeed())
+		Expect(cache.Add(b, []byte("1234"))).To(Succeed())
+		_, found := cache.Get(a)
+		Expect(found).To(BeTrue())
+		Expect(cache.Add(c, []byte("1234"))).To(Succeed())
+
+		Expect(filepath.Join(dir, b[:2], b)).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(dir, a[:2], a)).To(BeAnExistingFile())
+
+		// Files that are not outputs are cleaned up, and outputs beyond the size are evicted.
+		Expect(os.WriteFile(filepath.Join(dir, a[:2], a+".123.tmp"), []byte("x"), 0o600)).To(Succeed())
+		cache, err = NewDiskCache(dir, 5)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.Len()).To(Equal(1))
+		Expect(filepath.Join(dir, a[:2], a+".123.tmp")).NotTo(BeAnExistingFile())
+	})
+})
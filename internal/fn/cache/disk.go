@@ -0,0 +1,155 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DiskCache is a Cache that keeps outputs in files in a directory, so that they survive restarts,
+// evicting the least recently used outputs once their total size exceeds its size. The output
+// stored under a key is in the file <key[:2]>/<key> of the directory, and the modification time
+// of the file is the last time it was used.
+type DiskCache struct {
+	dir string
+
+	mu  sync.Mutex
+	lru *lru
+}
+
+var _ Cache = &DiskCache{}
+
+// NewDiskCache returns a DiskCache that holds up to maxSize bytes of outputs in dir, picking up
+// the outputs already there.
+func NewDiskCache(dir string, maxSize int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	type file struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if !isKey(entry.Name()) || filepath.Dir(path) != filepath.Join(dir, entry.Name()[:2]) {
+			// Left behind by a write that did not finish.
+			return os.Remove(path)
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, file{key: entry.Name(), size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read function cache %s: %w", dir, err)
+	}
+
+	c := &DiskCache{dir: dir, lru: newLRU(maxSize)}
+	slices.SortFunc(files, func(a, b file) int { return a.modTime.Compare(b.modTime) })
+	for _, f := range files {
+		for _, evicted := range c.lru.add(f.key, f.size) {
+			_ = os.Remove(c.path(evicted))
+		}
+	}
+	return c, nil
+}
+
+// Get returns the output stored under the key, if any.
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.lru.touch(key) {
+		return nil, false
+	}
+	output, err := os.ReadFile(c.path(key))
+	if err != nil {
+		c.lru.remove(key)
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(c.path(key), now, now)
+	return output, true
+}
+
+// Add stores the output under the key. The output is written to a temporary file that is then
+// renamed, so that a partly written output is never read.
+func (c *DiskCache) Add(key string, output []byte) error {
+	if !isKey(key) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(output)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name())
+		return err
+	}
+
+	for _, evicted := range c.lru.add(key, int64(len(output))) {
+		if err := os.Remove(c.path(evicted)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Len returns the number of outputs in the cache.
+func (c *DiskCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.order.Len()
+}
+
+// path returns the path of the file of the output stored under the key.
+func (c *DiskCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// isKey returns true if name is a key, as returned by Key.
+func isKey(name string) bool {
+	decoded, err := hex.DecodeString(name)
+	return err == nil && len(decoded) == 32
+}
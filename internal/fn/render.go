@@ -0,0 +1,306 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/fn/framework"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	"github.com/liamfallon/porch-operator/internal/kpt"
+	"github.com/liamfallon/porch-operator/internal/tracing"
+)
+
+// Stage is the stage of a pipeline that a function is run in.
+type Stage string
+
+const (
+	StageMutator   Stage = "mutator"
+	StageValidator Stage = "validator"
+)
+
+// rootPackage is the directory of the root package, as returned by path.Dir.
+const rootPackage = "."
+
+// Result is the outcome of running a function in a pipeline.
+type Result struct {
+	// Function is the function, as declared in the pipeline.
+	Function kpt.Function
+
+	// Package is the directory of the package whose pipeline declares the function, or "."
+	// for the root package.
+	Package string
+
+	// Stage is the stage of the pipeline the function was run in.
+	Stage Stage
+
+	// Results are the results the function reported.
+	Results framework.Results
+
+	// Err is set if the function failed.
+	Err error
+}
+
+// Renderer renders packages by running their pipelines.
+type Renderer struct {
+	// Runner runs the functions in the pipelines.
+	Runner Runner
+}
+
+// Render runs the pipelines of a package and its subpackages over the package resources, and
+// returns the rendered resources along with the result of every function run. Subpackages are
+// rendered before the package containing them, and the pipeline of a package is run over its
+// own resources and the rendered resources of its subpackages.
+//
+// Rendering stops at the first function that fails, in which case the error is returned with
+// the results of the functions run so far. The resources passed in are never modified.
+func (r *Renderer) Render(ctx context.Context, nodes []*yaml.RNode) ([]*yaml.RNode, []*Result, error) {
+	var packages []string
+	copies := make([]*yaml.RNode, 0, len(nodes))
+	for _, node := range nodes {
+		if node.GetKind() == kpt.KptfileKind && path.Base(kpt.PathOf(node)) == kpt.KptfileName {
+			packages = append(packages, path.Dir(kpt.PathOf(node)))
+		}
+		copies = append(copies, node.Copy())
+	}
+
+	var results []*Result
+	rendered, err := r.renderPackage(ctx, rootPackage, packages, copies, &results)
+	return rendered, results, err
+}
+
+// renderPackage renders the package in dir, whose resources, including those of its
+// subpackages, are nodes.
+func (r *Renderer) renderPackage(ctx context.Context, dir string, packages []string,
+	nodes []*yaml.RNode, results *[]*Result) ([]*yaml.RNode, error) {
+	for _, subpackage := range subpackagesOf(dir, packages) {
+		var inSubpackage, rest []*yaml.RNode
+		for _, node := range nodes {
+			if inPackage(kpt.PathOf(node), subpackage) {
+				inSubpackage = append(inSubpackage, node)
+			} else {
+				rest = append(rest, node)
+			}
+		}
+		rendered, err := r.renderPackage(ctx, subpackage, packages, inSubpackage, results)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(rest, rendered...)
+	}
+
+	kptfilePath := path.Join(dir, kpt.KptfileName)
+	i := slices.IndexFunc(nodes, func(node *yaml.RNode) bool {
+		return node.GetKind() == kpt.KptfileKind && kpt.PathOf(node) == kptfilePath
+	})
+	if i < 0 {
+		return nodes, nil
+	}
+	kptfile, err := kpt.ParseKptfile(nodes[i])
+	if err != nil {
+		return nil, err
+	}
+	if kptfile.Pipeline == nil {
+		return nodes, nil
+	}
+
+	for i := range kptfile.Pipeline.Mutators {
+		if nodes, err = r.runFunction(ctx, dir, StageMutator, &kptfile.Pipeline.Mutators[i], nodes, results); err != nil {
+			return nil, err
+		}
+	}
+	for i := range kptfile.Pipeline.Validators {
+		// Validators may not change the resources, so their output is discarded.
+		if _, err := r.runFunction(ctx, dir, StageValidator, &kptfile.Pipeline.Validators[i], nodes, results); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// runFunction runs a function of the pipeline of the package in dir, and records its result.
+func (r *Renderer) runFunction(ctx context.Context, dir string, stage Stage, function *kpt.Function,
+	nodes []*yaml.RNode, results *[]*Result) ([]*yaml.RNode, error) {
+	result := &Result{Function: *function, Package: dir, Stage: stage}
+	*results = append(*results, result)
+
+	ctx, span := tracing.Start(ctx, "Run function")
+	if span.IsRecording() {
+		span.SetAttributes(tracing.FunctionImageKey.String(function.Image), tracing.FunctionStageKey.String(string(stage)),
+			tracing.PackageKey.String(dir))
+	}
+	output, err := r.run(ctx, dir, function, nodes, result)
+	tracing.End(span, err)
+	if err != nil {
+		result.Err = err
+		return nil, fmt.Errorf("%s %s of package %q failed: %w", stage, function.Image, dir, err)
+	}
+	return output, nil
+}
+
+// run runs a function over the resources it selects, and returns the resources it did not
+// select together with its output. The results the function reports are set on result.
+func (r *Renderer) run(ctx context.Context, dir string, function *kpt.Function,
+	nodes []*yaml.RNode, result *Result) ([]*yaml.RNode, error) {
+	if function.Image == "" {
+		return nil, fmt.Errorf("image is required")
+	}
+	if r.Runner == nil {
+		return nil, fmt.Errorf("no function runner is configured")
+	}
+	config, err := functionConfig(dir, function, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected, unselected []*yaml.RNode
+	for _, node := range nodes {
+		if selects(function, node) {
+			selected = append(selected, node.Copy())
+		} else {
+			unselected = append(unselected, node)
+		}
+	}
+
+	// The file annotations of the resources are kept consistent across the internal and
+	// legacy annotation keys, as functions may read and write either.
+	annotations, err := kio.PreprocessResourcesForInternalAnnotationMigration(selected)
+	if err != nil {
+		return nil, err
+	}
+	var input bytes.Buffer
+	err = kio.ByteWriter{
+		Writer:                &input,
+		KeepReaderAnnotations: true,
+		FunctionConfig:        config,
+		WrappingAPIVersion:    kio.ResourceListAPIVersion,
+		WrappingKind:          kio.ResourceListKind,
+	}.Write(selected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write function input: %w", err)
+	}
+
+	output, runErr := r.Runner.Run(ctx, function, input.Bytes())
+	reader := &kio.ByteReader{Reader: bytes.NewReader(output), OmitReaderAnnotations: true}
+	items, err := reader.Read()
+	if err != nil && runErr == nil {
+		return nil, fmt.Errorf("failed to read function output: %w", err)
+	}
+	if reader.Results != nil {
+		if err := reader.Results.Document().Decode(&result.Results); err != nil && runErr == nil {
+			return nil, fmt.Errorf("failed to read function results: %w", err)
+		}
+	}
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	if err := kio.ReconcileInternalAnnotations(items, annotations); err != nil {
+		return nil, err
+	}
+	rendered := append(unselected, items...)
+	// Resources created by the function are written to files in the package directory.
+	defaultDir := dir
+	if dir == rootPackage {
+		defaultDir = ""
+	}
+	if err := kioutil.DefaultPathAndIndexAnnotation(defaultDir, rendered); err != nil {
+		return nil, err
+	}
+	return rendered, nil
+}
+
+// functionConfig returns the function config declared for a function, or nil if there is none.
+func functionConfig(dir string, function *kpt.Function, nodes []*yaml.RNode) (*yaml.RNode, error) {
+	switch {
+	case function.ConfigPath != "" && function.ConfigMap != nil:
+		return nil, fmt.Errorf("only one of configPath and configMap may be set")
+	case function.ConfigPath != "":
+		configPath := path.Join(dir, function.ConfigPath)
+		var config *yaml.RNode
+		for _, node := range nodes {
+			if kpt.PathOf(node) != configPath {
+				continue
+			}
+			if config != nil {
+				return nil, fmt.Errorf("function config %s must contain a single resource", configPath)
+			}
+			config = node.Copy()
+		}
+		if config == nil {
+			return nil, fmt.Errorf("function config %s not found", configPath)
+		}
+		return config, nil
+	case function.ConfigMap != nil:
+		return yaml.FromMap(map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]any{"name": "function-input"},
+			"data":       function.ConfigMap,
+		})
+	}
+	return nil, nil
+}
+
+// selects returns true if a function is run on a resource.
+func selects(function *kpt.Function, node *yaml.RNode) bool {
+	for i := range function.Exclusions {
+		if function.Exclusions[i].Matches(node) {
+			return false
+		}
+	}
+	if len(function.Selectors) == 0 {
+		return true
+	}
+	for i := range function.Selectors {
+		if function.Selectors[i].Matches(node) {
+			return true
+		}
+	}
+	return false
+}
+
+// subpackagesOf returns the packages directly contained in the package in dir.
+func subpackagesOf(dir string, packages []string) []string {
+	var subpackages []string
+	for _, p := range packages {
+		if p == dir || !inPackage(p, dir) {
+			continue
+		}
+		nested := slices.ContainsFunc(packages, func(q string) bool {
+			return q != p && q != dir && inPackage(q, dir) && inPackage(p, q)
+		})
+		if !nested {
+			subpackages = append(subpackages, p)
+		}
+	}
+	slices.Sort(subpackages)
+	return subpackages
+}
+
+// inPackage returns true if the file path is within the package directory.
+func inPackage(file, dir string) bool {
+	return dir == rootPackage || strings.HasPrefix(file, dir+"/") || file == dir
+}
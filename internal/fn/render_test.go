@@ -0,0 +1,197 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/kustomize/kyaml/fn/framework"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+// run is a function run by a recordingRunner.
+type run struct {
+	// Function is the name of the function.
+	Function string
+
+	// Resources are the names of the resources the function was run on.
+	Resources []string
+
+	// Config is the name of the function config, if there was one.
+	Config string
+}
+
+// recordingRunner records the functions it runs, and labels the resources it runs them on with the
+// name of the last function run on them. Functions named fail fail.
+type recordingRunner struct {
+	runs []run
+}
+
+func (r *recordingRunner) Run(_ context.Context, function *kpt.Function, input []byte) ([]byte, error) {
+	var output bytes.Buffer
+	err := framework.Execute(framework.ResourceListProcessorFunc(func(rl *framework.ResourceList) error {
+		recorded := run{Function: function.Name}
+		for _, item := range rl.Items {
+			recorded.Resources = append(recorded.Resources, item.GetName())
+			if err := item.PipeE(yaml.SetLabel("rendered-by", function.Name)); err != nil {
+				return err
+			}
+		}
+		if rl.FunctionConfig != nil {
+			recorded.Config = rl.FunctionConfig.GetName()
+		}
+		r.runs = append(r.runs, recorded)
+		if function.Name == "fail" {
+			return errors.New("function failed")
+		}
+		return nil
+	}), &kio.ByteReadWriter{Reader: bytes.NewReader(input), Writer: &output, OmitReaderAnnotations: true, KeepReaderAnnotations: true})
+	return output.Bytes(), err
+}
+
+// kptfile returns a Kptfile whose pipeline runs the mutators.
+func kptfile(name string, mutators ...string) string {
+	kptfile := "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: " + name + "\n"
+	if len(mutators) > 0 {
+		kptfile += "pipeline:\n  mutators:\n"
+		for _, mutator := range mutators {
+			kptfile += mutator
+		}
+	}
+	return kptfile
+}
+
+// configMap returns a ConfigMap with the name and labels.
+func configMap(name string, labels ...string) string {
+	configMap := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n"
+	if len(labels) > 0 {
+		configMap += "  labels:\n"
+		for _, label := range labels {
+			configMap += "    " + label + "\n"
+		}
+	}
+	return configMap
+}
+
+var _ = Describe("Renderer", func() {
+	ctx := context.Background()
+
+	render := func(contents map[string]string) ([]run, map[string]string, error) {
+		nodes, err := kpt.ReadResources(contents)
+		Expect(err).NotTo(HaveOccurred())
+		runner := &recordingRunner{}
+		rendered, _, err := (&Renderer{Runner: runner}).Render(ctx, nodes)
+		if err != nil {
+			return runner.runs, nil, err
+		}
+		labels := map[string]string{}
+		for _, node := range rendered {
+			labels[node.GetName()] = node.GetLabels()["rendered-by"]
+		}
+		return runner.runs, labels, nil
+	}
+
+	DescribeTable("should run the pipelines of a package",
+		func(contents map[string]string, expected []run) {
+			runs, _, err := render(contents)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runs).To(Equal(expected))
+		},
+		Entry("with subpackages rendered before their parents, in order of their directories",
+			map[string]string{
+				"Kptfile":        kptfile("root", "  - image: fn\n    name: root\n"),
+				"root.yaml":      configMap("root-config"),
+				"web/Kptfile":    kptfile("web", "  - image: fn\n    name: web\n"),
+				"web/web.yaml":   configMap("web-config"),
+				"web/db/Kptfile": kptfile("db", "  - image: fn\n    name: db\n"),
+				"app/Kptfile":    kptfile("app", "  - image: fn\n    name: app\n"),
+				"app/app.yaml":   configMap("app-config"),
+			},
+			[]run{
+				{Function: "app", Resources: []string{"app", "app-config"}},
+				{Function: "db", Resources: []string{"db"}},
+				{Function: "web", Resources: []string{"web", "web-config", "db"}},
+				{Function: "root", Resources: []string{"root", "root-config", "app", "app-config", "web", "web-config", "db"}},
+			}),
+		Entry("on the resources that match a selector and no exclusion",
+			map[string]string{
+				"Kptfile": kptfile("app",
+					"  - image: fn\n    name: edge\n    selectors:\n    - labels:\n        tier: edge\n    - name: core\n",
+					"  - image: fn\n    name: configmaps\n    selectors:\n    - kind: ConfigMap\n    exclude:\n    - labels:\n        tier: edge\n"),
+				"edge.yaml":  configMap("edge", "tier: edge"),
+				"core.yaml":  configMap("core", "tier: core"),
+				"other.yaml": configMap("other"),
+			},
+			[]run{
+				{Function: "edge", Resources: []string{"core", "edge"}},
+				{Function: "configmaps", Resources: []string{"other", "core"}},
+			}),
+		Entry("with the function config of their configPath or configMap",
+			map[string]string{
+				"Kptfile": kptfile("app",
+					"  - image: fn\n    name: path\n    configPath: config/labels.yaml\n",
+					"  - image: fn\n    name: map\n    configMap:\n      tier: edge\n",
+					"  - image: fn\n    name: none\n"),
+				"config/labels.yaml": configMap("labels"),
+			},
+			[]run{
+				{Function: "path", Resources: []string{"app", "labels"}, Config: "labels"},
+				{Function: "map", Resources: []string{"app", "labels"}, Config: "function-input"},
+				{Function: "none", Resources: []string{"app", "labels"}},
+			}),
+	)
+
+	It("should keep the resources a function is not run on", func() {
+		_, labels, err := render(map[string]string{
+			"Kptfile":   kptfile("app", "  - image: fn\n    name: edge\n    selectors:\n    - labels:\n        tier: edge\n"),
+			"edge.yaml": configMap("edge", "tier: edge"),
+			"core.yaml": configMap("core", "tier: core"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(labels).To(Equal(map[string]string{"app": "", "edge": "edge", "core": ""}))
+	})
+
+	DescribeTable("should refuse invalid function configs",
+		func(function, message string) {
+			_, _, err := render(map[string]string{
+				"Kptfile":     kptfile("app", function),
+				"config.yaml": configMap("first") + "---\n" + configMap("second"),
+			})
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("with both a configPath and a configMap",
+			"  - image: fn\n    configPath: config.yaml\n    configMap:\n      tier: edge\n", "only one of configPath and configMap"),
+		Entry("with a missing configPath", "  - image: fn\n    configPath: missing.yaml\n", "function config missing.yaml not found"),
+		Entry("with a configPath holding several resources", "  - image: fn\n    configPath: config.yaml\n", "must contain a single resource"),
+	)
+
+	It("should stop at the first function that fails", func() {
+		runs, _, err := render(map[string]string{
+			"Kptfile":     kptfile("app", "  - image: fn\n    name: fail\n", "  - image: fn\n    name: after\n"),
+			"sub/Kptfile": kptfile("sub", "  - image: fn\n    name: sub\n"),
+		})
+		Expect(err).To(MatchError(ContainSubstring(`mutator fn of package "." failed`)))
+		Expect(runs).To(HaveExactElements(HaveField("Function", "sub"), HaveField("Function", "fail")))
+	})
+})
@@ -0,0 +1,172 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fn renders kpt packages by running the KRM functions in their Kptfile pipelines.
+//
+// Functions are run by a Runner, which receives and returns a serialized ResourceList as
+// described by the KRM functions specification. How a function is executed, for example as
+// a Go function compiled into the operator or as a WebAssembly module, is up to the Runner.
+package fn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/fn/framework"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+// ErrFunctionNotFound is returned by a Runner that has no implementation of a function.
+var ErrFunctionNotFound = errors.New("function not found")
+
+// ErrMemoryLimitExceeded is returned by a Runner when a function needs more memory than the
+// limit of the context it is run with.
+var ErrMemoryLimitExceeded = errors.New("function exceeded its memory limit")
+
+type (
+	memoryLimitKey struct{}
+	maxStepsKey    struct{}
+)
+
+// WithMemoryLimit returns a context that limits the memory of the functions run with it, in bytes.
+// Runners that cannot bound the memory of their functions ignore it.
+func WithMemoryLimit(ctx context.Context, limit uint64) context.Context {
+	return context.WithValue(ctx, memoryLimitKey{}, limit)
+}
+
+// MemoryLimit returns the memory limit of the context, in bytes, or 0 if it has none.
+func MemoryLimit(ctx context.Context) uint64 {
+	limit, _ := ctx.Value(memoryLimitKey{}).(uint64)
+	return limit
+}
+
+// WithMaxSteps returns a context that limits the number of execution steps of the functions run
+// with it. Runners of functions that are not interpreted ignore it.
+func WithMaxSteps(ctx context.Context, steps uint64) context.Context {
+	return context.WithValue(ctx, maxStepsKey{}, steps)
+}
+
+// MaxSteps returns the limit on the execution steps of the context, or 0 if it has none.
+func MaxSteps(ctx context.Context) uint64 {
+	steps, _ := ctx.Value(maxStepsKey{}).(uint64)
+	return steps
+}
+
+// Runner runs KRM functions.
+type Runner interface {
+	// Run runs the function with the serialized ResourceList input, and returns the serialized
+	// ResourceList it produced. A function that fails returns an error, and may also return
+	// output whose results explain the failure.
+	Run(ctx context.Context, function *kpt.Function, input []byte) ([]byte, error)
+}
+
+// Checker is implemented by Runners that refuse some functions before running them, such as
+// functions whose modules are not allowed, so that the functions can be checked without running
+// them. Runners that serve functions without running them, such as caches, check them first.
+type Checker interface {
+	// Check returns the error the Runner would refuse to run the function with, or nil if it would
+	// run it.
+	Check(ctx context.Context, function *kpt.Function) error
+}
+
+// Check checks the function with the runner, if the runner is a Checker.
+func Check(ctx context.Context, runner Runner, function *kpt.Function) error {
+	if checker, ok := runner.(Checker); ok {
+		return checker.Check(ctx, function)
+	}
+	return nil
+}
+
+// Builtins is a Runner for functions implemented in Go, keyed by function image. A function is
+// looked up by its full image first, and then by its image without a tag or digest.
+type Builtins map[string]framework.ResourceListProcessor
+
+// Run runs the Go implementation of the function.
+func (b Builtins) Run(_ context.Context, function *kpt.Function, input []byte) ([]byte, error) {
+	processor, found := b[function.Image]
+	if !found {
+		processor, found = b[ImageName(function.Image)]
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrFunctionNotFound, function.Image)
+	}
+
+	var output bytes.Buffer
+	err := framework.Execute(processor, &kio.ByteReadWriter{
+		Reader:                bytes.NewReader(input),
+		Writer:                &output,
+		OmitReaderAnnotations: true,
+		KeepReaderAnnotations: true,
+	})
+	return output.Bytes(), err
+}
+
+// Check returns ErrFunctionNotFound if there is no Go implementation of the function.
+func (b Builtins) Check(_ context.Context, function *kpt.Function) error {
+	_, found := b[function.Image]
+	if !found {
+		_, found = b[ImageName(function.Image)]
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrFunctionNotFound, function.Image)
+	}
+	return nil
+}
+
+// Chain is a Runner that runs each function with the first of its runners that implements it.
+type Chain []Runner
+
+// Run runs the function with the first runner that does not return ErrFunctionNotFound.
+func (c Chain) Run(ctx context.Context, function *kpt.Function, input []byte) ([]byte, error) {
+	for _, runner := range c {
+		output, err := runner.Run(ctx, function, input)
+		if !errors.Is(err, ErrFunctionNotFound) {
+			return output, err
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrFunctionNotFound, function.Image)
+}
+
+// Check checks the function with the first runner that does not return ErrFunctionNotFound. A
+// runner that is not a Checker is assumed to implement the function and to run it.
+func (c Chain) Check(ctx context.Context, function *kpt.Function) error {
+	for _, runner := range c {
+		checker, ok := runner.(Checker)
+		if !ok {
+			return nil
+		}
+		if err := checker.Check(ctx, function); !errors.Is(err, ErrFunctionNotFound) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrFunctionNotFound, function.Image)
+}
+
+// ImageName returns the function image without its tag or digest.
+func ImageName(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
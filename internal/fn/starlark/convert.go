@@ -0,0 +1,246 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package starlark
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"sigs.k8s.io/kustomize/kyaml/fn/framework"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// maxDepth is the maximum nesting of values converted from Starlark, which guards against
+// values that contain themselves.
+const maxDepth = 100
+
+// toStarlark converts a ResourceList to a Starlark dict with items, functionConfig and results.
+func toStarlark(rl *framework.ResourceList) (*starlark.Dict, error) {
+	items := make([]starlark.Value, 0, len(rl.Items))
+	for _, item := range rl.Items {
+		value, err := nodeToStarlark(item.YNode())
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	var config starlark.Value = starlark.None
+	if rl.FunctionConfig != nil {
+		var err error
+		if config, err = nodeToStarlark(rl.FunctionConfig.YNode()); err != nil {
+			return nil, err
+		}
+	}
+
+	resourceList := starlark.NewDict(3)
+	for key, value := range map[string]starlark.Value{
+		"items":          starlark.NewList(items),
+		"functionConfig": config,
+		"results":        starlark.NewList(nil),
+	} {
+		if err := resourceList.SetKey(starlark.String(key), value); err != nil {
+			return nil, err
+		}
+	}
+	return resourceList, nil
+}
+
+// fromStarlark sets the items of the ResourceList, and appends to its results, from the
+// ResourceList dict that a script has run over. Items that the script did not change are kept
+// as they are, so that their comments and formatting are preserved.
+func fromStarlark(resourceList *starlark.Dict, rl *framework.ResourceList) error {
+	list, err := listField(resourceList, "items")
+	if err != nil {
+		return err
+	}
+	items := make([]*yaml.RNode, 0, list.Len())
+	for i := range list.Len() {
+		value := list.Index(i)
+		if i < len(rl.Items) {
+			original, err := nodeToStarlark(rl.Items[i].YNode())
+			if err != nil {
+				return err
+			}
+			if equal, err := starlark.Equal(value, original); err == nil && equal {
+				items = append(items, rl.Items[i])
+				continue
+			}
+		}
+		if _, ok := value.(*starlark.Dict); !ok {
+			return fmt.Errorf(`ctx.resource_list["items"][%d] must be a dict, not a %s`, i, value.Type())
+		}
+		node, err := starlarkToNode(value, 0)
+		if err != nil {
+			return fmt.Errorf(`ctx.resource_list["items"][%d]: %w`, i, err)
+		}
+		items = append(items, yaml.NewRNode(node))
+	}
+
+	list, err = listField(resourceList, "results")
+	if err != nil {
+		return err
+	}
+	var results framework.Results
+	for i := range list.Len() {
+		node, err := starlarkToNode(list.Index(i), 0)
+		if err != nil {
+			return fmt.Errorf(`ctx.resource_list["results"][%d]: %w`, i, err)
+		}
+		result := &framework.Result{}
+		if err := node.Decode(result); err != nil {
+			return fmt.Errorf(`ctx.resource_list["results"][%d] is not a valid result: %w`, i, err)
+		}
+		results = append(results, result)
+	}
+
+	rl.Items = items
+	rl.Results = append(rl.Results, results...)
+	return nil
+}
+
+// listField returns a field of the ResourceList dict that must be a list.
+func listField(resourceList *starlark.Dict, key string) (*starlark.List, error) {
+	value, _, err := resourceList.Get(starlark.String(key))
+	if err != nil {
+		return nil, err
+	}
+	list, ok := value.(*starlark.List)
+	if !ok {
+		return nil, fmt.Errorf(`ctx.resource_list["%s"] must be a list`, key)
+	}
+	return list, nil
+}
+
+// nodeToStarlark converts a YAML node to a Starlark value. Mappings become dicts that keep the
+// order of their keys.
+func nodeToStarlark(node *yaml.Node) (starlark.Value, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return starlark.None, nil
+		}
+		return nodeToStarlark(node.Content[0])
+	case yaml.AliasNode:
+		return nodeToStarlark(node.Alias)
+	case yaml.MappingNode:
+		dict := starlark.NewDict(len(node.Content) / 2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, err := nodeToStarlark(node.Content[i])
+			if err != nil {
+				return nil, err
+			}
+			value, err := nodeToStarlark(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.SetKey(key, value); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	case yaml.SequenceNode:
+		elements := make([]starlark.Value, 0, len(node.Content))
+		for _, content := range node.Content {
+			element, err := nodeToStarlark(content)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		}
+		return starlark.NewList(elements), nil
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case yaml.NodeTagNull:
+			return starlark.None, nil
+		case yaml.NodeTagBool:
+			var b bool
+			if err := node.Decode(&b); err == nil {
+				return starlark.Bool(b), nil
+			}
+		case yaml.NodeTagInt:
+			var i big.Int
+			if _, ok := i.SetString(strings.ReplaceAll(node.Value, "_", ""), 0); ok {
+				return starlark.MakeBigInt(&i), nil
+			}
+		case yaml.NodeTagFloat:
+			var f float64
+			if err := node.Decode(&f); err == nil {
+				return starlark.Float(f), nil
+			}
+		}
+		return starlark.String(node.Value), nil
+	}
+	return nil, fmt.Errorf("unsupported YAML node kind %d at line %d", node.Kind, node.Line)
+}
+
+// starlarkToNode converts a Starlark value to a YAML node.
+func starlarkToNode(value starlark.Value, depth int) (*yaml.Node, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("value is nested more than %d levels deep", maxDepth)
+	}
+	scalar := func(tag, value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+	}
+
+	switch value := value.(type) {
+	case starlark.NoneType:
+		return scalar(yaml.NodeTagNull, "null"), nil
+	case starlark.Bool:
+		return scalar(yaml.NodeTagBool, strconv.FormatBool(bool(value))), nil
+	case starlark.Int:
+		return scalar(yaml.NodeTagInt, value.String()), nil
+	case starlark.Float:
+		f := strconv.FormatFloat(float64(value), 'g', -1, 64)
+		if !strings.ContainsAny(f, ".eEnN") {
+			f += ".0"
+		}
+		return scalar(yaml.NodeTagFloat, f), nil
+	case starlark.String:
+		return scalar(yaml.NodeTagString, string(value)), nil
+	case *starlark.Dict:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: yaml.NodeTagMap}
+		for _, item := range value.Items() {
+			key, err := starlarkToNode(item[0], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("dict key %s is not a scalar", item[0])
+			}
+			element, err := starlarkToNode(item[1], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, key, element)
+		}
+		return node, nil
+	case starlark.Indexable:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: yaml.NodeTagSeq}
+		for i := range value.Len() {
+			element, err := starlarkToNode(value.Index(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, element)
+		}
+		return node, nil
+	}
+	return nil, fmt.Errorf("cannot convert a %s to YAML", value.Type())
+}
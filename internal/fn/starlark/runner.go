@@ -0,0 +1,223 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package starlark runs Starlark scripts as KRM functions in-process.
+//
+// A pipeline runs a script with the Image function, configured with a StarlarkRun resource:
+//
+//	apiVersion: fn.kpt.dev/v1alpha1
+//	kind: StarlarkRun
+//	metadata:
+//	  name: set-namespace
+//	params:
+//	  namespace: edge
+//	source: |
+//	  for resource in ctx.resource_list["items"]:
+//	    resource["metadata"]["namespace"] = ctx.resource_list["functionConfig"]["params"]["namespace"]
+//
+// or with a ConfigMap whose source key holds the script. The script reads and modifies the
+// ResourceList in ctx.resource_list, and may append results, dicts with a message and severity,
+// to its "results" list. Output of print is reported as info results.
+//
+// Scripts have no access to the file system, network or clock, and are stopped after a fixed
+// number of execution steps, so that a script produces the same output every time it is run. The
+// number of steps can be lowered for a run with fn.WithMaxSteps.
+package starlark
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+	"sigs.k8s.io/kustomize/kyaml/fn/framework"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+const (
+	// Image is the image of the Starlark function. Functions with this image, whatever their
+	// tag, are run by the Runner.
+	Image = "gcr.io/kpt-fn/starlark"
+
+	// StarlarkRunKind is the kind of the function config of a Starlark function.
+	StarlarkRunKind = "StarlarkRun"
+
+	// DefaultMaxSteps is the default number of execution steps after which a script is stopped.
+	DefaultMaxSteps = 10_000_000
+)
+
+// Runner is a fn.Runner for Starlark functions.
+type Runner struct {
+	// MaxSteps is the number of execution steps after which a script is stopped. Defaults to
+	// DefaultMaxSteps.
+	MaxSteps uint64
+}
+
+var (
+	_ fn.Runner  = &Runner{}
+	_ fn.Checker = &Runner{}
+)
+
+// Run runs the Starlark script in the function config.
+func (r *Runner) Run(ctx context.Context, function *kpt.Function, input []byte) ([]byte, error) {
+	if err := r.Check(ctx, function); err != nil {
+		return nil, err
+	}
+
+	var output bytes.Buffer
+	err := framework.Execute(framework.ResourceListProcessorFunc(func(rl *framework.ResourceList) error {
+		return r.process(ctx, rl)
+	}), &kio.ByteReadWriter{
+		Reader:                bytes.NewReader(input),
+		Writer:                &output,
+		OmitReaderAnnotations: true,
+		KeepReaderAnnotations: true,
+	})
+	return output.Bytes(), err
+}
+
+// Check returns ErrFunctionNotFound if the function is not the starlark function.
+func (r *Runner) Check(_ context.Context, function *kpt.Function) error {
+	if fn.ImageName(function.Image) != Image {
+		return fmt.Errorf("%w: %s", fn.ErrFunctionNotFound, function.Image)
+	}
+	return nil
+}
+
+// process runs the script over the ResourceList. Failures are reported as error results.
+func (r *Runner) process(ctx context.Context, rl *framework.ResourceList) error {
+	name, source, err := scriptOf(rl.FunctionConfig)
+	if err != nil {
+		return fail(rl, err.Error())
+	}
+
+	value, err := toStarlark(rl)
+	if err != nil {
+		return fail(rl, err.Error())
+	}
+
+	thread := &starlark.Thread{
+		Name: name,
+		Print: func(_ *starlark.Thread, message string) {
+			rl.Results = append(rl.Results, &framework.Result{Message: message, Severity: framework.Info})
+		},
+	}
+	maxSteps := r.MaxSteps
+	if maxSteps == 0 {
+		maxSteps = DefaultMaxSteps
+	}
+	if limit := fn.MaxSteps(ctx); limit != 0 && limit < maxSteps {
+		maxSteps = limit
+	}
+	thread.SetMaxExecutionSteps(maxSteps)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	options := &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true}
+	predeclared := starlark.StringDict{
+		"ctx": starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{"resource_list": value}),
+	}
+	if _, err := starlark.ExecFileOptions(options, thread, name, source, predeclared); err != nil {
+		for _, message := range scriptErrors(name, err) {
+			rl.Results = append(rl.Results, &framework.Result{Message: message, Severity: framework.Error})
+		}
+		return rl.Results
+	}
+
+	if err := fromStarlark(value, rl); err != nil {
+		return fail(rl, err.Error())
+	}
+	if rl.Results.ExitCode() != 0 {
+		return rl.Results
+	}
+	return nil
+}
+
+// fail reports an error result and returns the results as the error of the function.
+func fail(rl *framework.ResourceList, message string) error {
+	rl.Results = append(rl.Results, &framework.Result{Message: message, Severity: framework.Error})
+	return rl.Results
+}
+
+// scriptOf returns the name and source of the script in the function config.
+func scriptOf(config *yaml.RNode) (string, string, error) {
+	if config == nil {
+		return "", "", fmt.Errorf("function config is required")
+	}
+	name := config.GetName()
+	if name == "" {
+		name = "script"
+	}
+
+	var source string
+	switch config.GetKind() {
+	case StarlarkRunKind:
+		field := config.Field("source")
+		if field != nil {
+			source = yaml.GetValue(field.Value)
+		}
+	case "ConfigMap":
+		source = config.GetDataMap()["source"]
+	default:
+		return "", "", fmt.Errorf("function config must be a %s or a ConfigMap, not a %s", StarlarkRunKind, config.GetKind())
+	}
+	if source == "" {
+		return "", "", fmt.Errorf("function config %s has no source", name)
+	}
+	return name, source, nil
+}
+
+// scriptErrors returns messages for the errors of a script, prefixed with the position in the
+// script, file:line:column, where they occurred.
+func scriptErrors(name string, err error) []string {
+	var syntaxErr syntax.Error
+	var resolveErrs resolve.ErrorList
+	var evalErr *starlark.EvalError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return []string{syntaxErr.Error()}
+	case errors.As(err, &resolveErrs):
+		messages := make([]string, 0, len(resolveErrs))
+		for _, resolveErr := range resolveErrs {
+			messages = append(messages, resolveErr.Error())
+		}
+		return messages
+	case errors.As(err, &evalErr):
+		// Report the innermost position in the script, rather than in a built-in function.
+		for i := range evalErr.CallStack {
+			if frame := evalErr.CallStack.At(i); frame.Pos.Filename() == name {
+				return []string{fmt.Sprintf("%s: %s", frame.Pos, evalErr.Msg)}
+			}
+		}
+		return []string{evalErr.Msg}
+	}
+	return []string{err.Error()}
+}
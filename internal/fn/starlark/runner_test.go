@@ -0,0 +1,175 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package starlark
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/kustomize/kyaml/fn/framework"
+
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+var _ = Describe("Starlark Runner", func() {
+	ctx := context.Background()
+
+	kptfile := `apiVersion: kpt.dev/v1
+kind: Kptfile
+metadata:
+  name: app
+pipeline:
+  mutators:
+  - image: gcr.io/kpt-fn/starlark:v0.5
+    configPath: script.yaml
+`
+
+	// render renders a package with the script as the function config of its only function.
+	render := func(runner *Runner, script string) (map[string]string, []*fn.Result, error) {
+		contents := map[string]string{
+			"Kptfile":     kptfile,
+			"script.yaml": script,
+			"config.yaml": "# The configuration of the app.\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\n" +
+				"  annotations:\n    config.kubernetes.io/local-config: \"true\"\ndata:\n  replicas: \"3\"\n",
+			"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\nspec:\n  replicas: 1\n",
+		}
+		nodes, err := kpt.ReadResources(contents)
+		Expect(err).NotTo(HaveOccurred())
+		rendered, results, err := (&fn.Renderer{Runner: runner}).Render(ctx, nodes)
+		if err != nil {
+			return nil, results, err
+		}
+		written, err := kpt.WriteResources(contents, rendered)
+		Expect(err).NotTo(HaveOccurred())
+		return written, results, nil
+	}
+
+	It("should run a script that modifies the resources", func() {
+		contents, results, err := render(&Runner{}, `apiVersion: fn.kpt.dev/v1alpha1
+kind: StarlarkRun
+metadata:
+  name: set-namespace
+  annotations:
+    config.kubernetes.io/local-config: "true"
+params:
+  namespace: edge
+source: |
+  namespace = ctx.resource_list["functionConfig"]["params"]["namespace"]
+  for resource in ctx.resource_list["items"]:
+    if resource["kind"] == "Deployment":
+      resource["metadata"]["namespace"] = namespace
+      resource["spec"]["replicas"] += 1
+      print("updated " + resource["metadata"]["name"])
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contents["deployment.yaml"]).To(Equal(
+			"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n  namespace: edge\nspec:\n  replicas: 2\n"))
+		Expect(contents["config.yaml"]).To(HavePrefix("# The configuration of the app.\n"))
+		Expect(contents["config.yaml"]).To(ContainSubstring(`replicas: "3"`))
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Results).To(ConsistOf(HaveField("Message", "updated app")))
+	})
+
+	It("should run a script from a ConfigMap and report its results", func() {
+		_, results, err := render(&Runner{}, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: check-replicas
+data:
+  source: |
+    for resource in ctx.resource_list["items"]:
+      if resource["kind"] == "Deployment" and resource["spec"]["replicas"] < 2:
+        ctx.resource_list["results"].append({
+          "message": "at least 2 replicas are required",
+          "severity": "error",
+          "resourceRef": {"apiVersion": "apps/v1", "kind": "Deployment", "name": resource["metadata"]["name"]},
+        })
+`)
+		Expect(err).To(HaveOccurred())
+		Expect(results[0].Results).To(HaveLen(1))
+		Expect(results[0].Results[0].Severity).To(Equal(framework.Error))
+		Expect(results[0].Results[0].ResourceRef.Name).To(Equal("app"))
+	})
+
+	It("should report the line of a runtime error", func() {
+		_, results, err := render(&Runner{}, `apiVersion: fn.kpt.dev/v1alpha1
+kind: StarlarkRun
+metadata:
+  name: broken
+source: |
+  for item in ctx.resource_list["items"]:
+    if item["kind"] == "Deployment":
+      item["spec"]["missing"]["field"] = 1
+`)
+		Expect(err).To(HaveOccurred())
+		Expect(results[0].Results).To(ConsistOf(HaveField("Message", Equal("broken:3:17: key \"missing\" not in dict"))))
+	})
+
+	It("should report the line of a syntax error", func() {
+		_, results, err := render(&Runner{}, `apiVersion: fn.kpt.dev/v1alpha1
+kind: StarlarkRun
+metadata:
+  name: broken
+source: |
+  for item in ctx.resource_list["items"]:
+      item["kind"] = = "x"
+`)
+		Expect(err).To(HaveOccurred())
+		Expect(results[0].Results).To(ConsistOf(HaveField("Message", HavePrefix("broken:2:"))))
+	})
+
+	It("should stop a script after its execution steps are used up", func() {
+		_, results, err := render(&Runner{MaxSteps: 1000}, `apiVersion: fn.kpt.dev/v1alpha1
+kind: StarlarkRun
+metadata:
+  name: forever
+source: |
+  while True:
+    pass
+`)
+		Expect(err).To(HaveOccurred())
+		Expect(results[0].Results).To(ConsistOf(HaveField("Message", ContainSubstring("too many steps"))))
+	})
+
+	It("should stop a script after the execution steps of the context are used up", func() {
+		input := []byte(`apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+items: []
+functionConfig:
+  apiVersion: fn.kpt.dev/v1alpha1
+  kind: StarlarkRun
+  metadata:
+    name: count
+  source: |
+    for i in range(100000):
+      pass
+`)
+		function := &kpt.Function{Image: Image}
+		_, err := (&Runner{}).Run(ctx, function, input)
+		Expect(err).NotTo(HaveOccurred())
+		output, err := (&Runner{}).Run(fn.WithMaxSteps(ctx, 1000), function, input)
+		Expect(err).To(HaveOccurred())
+		Expect(string(output)).To(ContainSubstring("too many steps"))
+	})
+
+	It("should only run the Starlark image", func() {
+		_, err := (&Runner{}).Run(ctx, &kpt.Function{Image: "gcr.io/kpt-fn/set-labels:v0.2"}, nil)
+		Expect(err).To(MatchError(fn.ErrFunctionNotFound))
+	})
+})
@@ -0,0 +1,62 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/liamfallon/porch-operator/internal/fn"
+)
+
+// ModuleLoader loads the WebAssembly modules of functions.
+type ModuleLoader interface {
+	// Load returns the module of the function image. It returns an error wrapping
+	// fn.ErrFunctionNotFound if there is no module for the image.
+	Load(ctx context.Context, image string) ([]byte, error)
+}
+
+// DirLoader loads modules from a directory. The module of the function image
+// example.com/fns/set-labels:v1 is example.com/fns/set-labels/v1.wasm in the directory, and
+// the module of an image without a tag is latest.wasm.
+type DirLoader string
+
+var _ ModuleLoader = DirLoader("")
+
+// Load reads the module of the function image from the directory.
+func (d DirLoader) Load(_ context.Context, image string) ([]byte, error) {
+	name := fn.ImageName(image)
+	tag := strings.TrimPrefix(strings.TrimPrefix(strings.SplitN(image, "@", 2)[0], name), ":")
+	if tag == "" {
+		tag = "latest"
+	}
+	file := filepath.FromSlash(name + "/" + tag + ".wasm")
+	if !filepath.IsLocal(file) {
+		return nil, fmt.Errorf("invalid function image %q", image)
+	}
+
+	wasm, err := os.ReadFile(filepath.Join(string(d), file))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", fn.ErrFunctionNotFound, image)
+	}
+	return wasm, err
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// limitedAllocator allocates the memories of a module instance, and bounds them by a limit that
+// is lower than the one of the runtime. A memory is not grown beyond the limit, which the module
+// sees as a failure to grow its memory; a memory whose initial size exceeds the limit, which only
+// happens for memories the module does not export, stops the instance.
+type limitedAllocator struct {
+	limit  uint64
+	cancel context.CancelFunc
+
+	// exceeded is set once a memory of the instance has needed more than the limit.
+	exceeded bool
+}
+
+var _ experimental.MemoryAllocator = &limitedAllocator{}
+
+// Allocate returns a memory bounded by the limit of the allocator.
+func (a *limitedAllocator) Allocate(capacity, _ uint64) experimental.LinearMemory {
+	return &limitedMemory{allocator: a, buffer: make([]byte, 0, min(capacity, a.limit))}
+}
+
+// limitedMemory is a linear memory that does not grow beyond the limit of its allocator.
+type limitedMemory struct {
+	allocator *limitedAllocator
+	buffer    []byte
+
+	// allocated is set once the initial size of the memory is allocated.
+	allocated bool
+}
+
+// Reallocate grows the memory to size bytes, or returns nil if size exceeds the limit.
+func (m *limitedMemory) Reallocate(size uint64) []byte {
+	if size > m.allocator.limit {
+		m.allocator.exceeded = true
+		if m.allocated {
+			return nil
+		}
+		// The initial size of a memory cannot be refused, so the instance is stopped instead.
+		m.allocator.cancel()
+	}
+	m.allocated = true
+	if size <= uint64(cap(m.buffer)) {
+		m.buffer = m.buffer[:size]
+		return m.buffer
+	}
+	buffer := make([]byte, size, max(size, min(2*uint64(cap(m.buffer)), m.allocator.limit)))
+	copy(buffer, m.buffer)
+	m.buffer = buffer
+	return m.buffer
+}
+
+// Free releases the memory.
+func (m *limitedMemory) Free() {
+	m.buffer = nil
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wasm runs KRM functions compiled to WebAssembly in-process.
+//
+// Functions are WASI preview 1 modules that read a ResourceList on stdin and write the resulting
+// ResourceList on stdout, as KRM functions running in containers do. Modules are run with the
+// pure Go wazero runtime, so no container runtime or cgo is needed. Each run gets a fresh module
+// instance with no access to the file system, network or environment, bounded in memory and time.
+// The limits of the runner can be lowered for a run with fn.WithMemoryLimit and a deadline.
+package wasm
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+const (
+	// DefaultMemoryLimit is the default limit on the memory of a function, in bytes.
+	DefaultMemoryLimit = 256 << 20
+
+	// DefaultTimeout is the default limit on how long a function may run.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultCacheSize is the default number of compiled modules that are kept.
+	DefaultCacheSize = 32
+
+	// pageSize is the size of a WebAssembly memory page.
+	pageSize = 64 << 10
+)
+
+// ErrModuleNotAllowed is returned when the module of a function is not in the allowlist.
+var ErrModuleNotAllowed = errors.New("module is not allowed")
+
+// Options configures a Runner.
+type Options struct {
+	// MemoryLimit is the maximum memory of a function, in bytes. Defaults to DefaultMemoryLimit.
+	MemoryLimit uint64
+
+	// Timeout is the maximum time a function may run for. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// AllowedDigests are the digests, in the form sha256:<hex>, of the modules that may be run.
+	// Any module may be run if it is empty.
+	AllowedDigests []string
+
+	// CacheSize is the number of compiled modules that are kept. Defaults to DefaultCacheSize.
+	CacheSize int
+}
+
+// Runner is a fn.Runner for functions compiled to WebAssembly.
+type Runner struct {
+	loader  ModuleLoader
+	options Options
+	runtime wazero.Runtime
+
+	// mu guards the compiled module cache, which is ordered from most to least recently used.
+	mu       sync.Mutex
+	compiled map[string]*list.Element
+	lru      *list.List
+}
+
+var (
+	_ fn.Runner  = &Runner{}
+	_ fn.Checker = &Runner{}
+)
+
+// cachedModule is a compiled module in the cache. A module evicted from the cache is closed once
+// the runs using it are done.
+type cachedModule struct {
+	digest   string
+	module   wazero.CompiledModule
+	users    int
+	evicted  bool
+	compiled chan struct{}
+	err      error
+}
+
+// NewRunner returns a Runner that runs the modules loaded by loader.
+func NewRunner(ctx context.Context, loader ModuleLoader, options Options) (*Runner, error) {
+	if options.MemoryLimit == 0 {
+		options.MemoryLimit = DefaultMemoryLimit
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.CacheSize <= 0 {
+		options.CacheSize = DefaultCacheSize
+	}
+	pages := options.MemoryLimit / pageSize
+	if pages == 0 || pages > 65536 {
+		return nil, fmt.Errorf("memory limit must be between %d and %d bytes", pageSize, uint64(65536)*pageSize)
+	}
+
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(pages)).
+		WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		_ = runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+	return &Runner{
+		loader:   loader,
+		options:  options,
+		runtime:  runtime,
+		compiled: map[string]*list.Element{},
+		lru:      list.New(),
+	}, nil
+}
+
+// Run runs the module of the function with a fresh instance.
+func (r *Runner) Run(ctx context.Context, function *kpt.Function, input []byte) ([]byte, error) {
+	wasm, digest, err := r.load(ctx, function)
+	if err != nil {
+		return nil, err
+	}
+
+	module, release, err := r.compile(ctx, digest, wasm)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// The memory of the function is bounded by the runtime, and further by the limit of the
+	// context, if it is lower.
+	limit := r.options.MemoryLimit
+	if contextLimit := fn.MemoryLimit(ctx); contextLimit != 0 && contextLimit < limit {
+		limit = contextLimit
+	}
+	for _, memory := range module.ExportedMemories() {
+		if needed := uint64(memory.Min()) * pageSize; needed > limit {
+			return nil, fmt.Errorf("%w of %d bytes: it needs %d bytes to start", fn.ErrMemoryLimitExceeded, limit, needed)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.options.Timeout)
+	defer cancel()
+	var allocator *limitedAllocator
+	if limit < r.options.MemoryLimit {
+		allocator = &limitedAllocator{limit: limit, cancel: cancel}
+		ctx = experimental.WithMemoryAllocator(ctx, allocator)
+	}
+	var stdout, stderr bytes.Buffer
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(fn.ImageName(function.Image)).
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(&stdout).
+		WithStderr(&stderr)
+	instance, err := r.runtime.InstantiateModule(ctx, module, config)
+	if instance != nil {
+		_ = instance.Close(context.WithoutCancel(ctx))
+	}
+
+	var exitErr *sys.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 0:
+		err = nil
+	case allocator != nil && allocator.exceeded:
+		err = fmt.Errorf("%w of %d bytes", fn.ErrMemoryLimitExceeded, limit)
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == sys.ExitCodeDeadlineExceeded:
+		err = fmt.Errorf("function did not finish within %s", r.options.Timeout)
+	case errors.As(err, &exitErr):
+		err = fmt.Errorf("function exited with status %d", exitErr.ExitCode())
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			err = fmt.Errorf("%w: %s", err, message)
+		}
+	default:
+		err = fmt.Errorf("function failed: %w", err)
+	}
+	return stdout.Bytes(), err
+}
+
+// Check loads the module of the function, and returns an error if it may not be run.
+func (r *Runner) Check(ctx context.Context, function *kpt.Function) error {
+	_, _, err := r.load(ctx, function)
+	return err
+}
+
+// load loads the module of the function and returns it with its digest, once checked against the
+// digest of the image, if it is pinned to one, and against the allowed digests.
+func (r *Runner) load(ctx context.Context, function *kpt.Function) ([]byte, string, error) {
+	wasm, err := r.loader.Load(ctx, function.Image)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(wasm)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if _, expected, found := strings.Cut(function.Image, "@"); found && expected != digest {
+		return nil, "", fmt.Errorf("module has digest %s, but the image requires %s", digest, expected)
+	}
+	if len(r.options.AllowedDigests) > 0 && !slices.Contains(r.options.AllowedDigests, digest) {
+		return nil, "", fmt.Errorf("%w: %s has digest %s", ErrModuleNotAllowed, function.Image, digest)
+	}
+	return wasm, digest, nil
+}
+
+// Close closes the runtime and all compiled modules.
+func (r *Runner) Close(ctx context.Context) error {
+	return r.runtime.Close(ctx)
+}
+
+// compile returns the compiled module with the digest, compiling it if it is not in the cache.
+// The module must be released once the caller is done with it.
+func (r *Runner) compile(ctx context.Context, digest string, wasm []byte) (wazero.CompiledModule, func(), error) {
+	r.mu.Lock()
+	element, found := r.compiled[digest]
+	if found {
+		r.lru.MoveToFront(element)
+	} else {
+		element = r.lru.PushFront(&cachedModule{digest: digest, compiled: make(chan struct{})})
+		r.compiled[digest] = element
+	}
+	cached := element.Value.(*cachedModule)
+	cached.users++
+	r.mu.Unlock()
+
+	if !found {
+		cached.module, cached.err = r.runtime.CompileModule(ctx, wasm)
+		if cached.err != nil {
+			cached.err = fmt.Errorf("failed to compile module: %w", cached.err)
+		}
+		close(cached.compiled)
+		r.mu.Lock()
+		if cached.err != nil {
+			r.remove(element)
+		}
+		r.evict()
+		r.mu.Unlock()
+	}
+	<-cached.compiled
+
+	release := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		cached.users--
+		if cached.evicted && cached.users == 0 && cached.module != nil {
+			_ = cached.module.Close(context.Background())
+		}
+	}
+	if cached.err != nil {
+		release()
+		return nil, nil, cached.err
+	}
+	return cached.module, release, nil
+}
+
+// evict removes the least recently used modules from the cache until it is within its size.
+func (r *Runner) evict() {
+	for r.lru.Len() > r.options.CacheSize {
+		element := r.lru.Back()
+		cached := element.Value.(*cachedModule)
+		r.remove(element)
+		if cached.users == 0 && cached.module != nil {
+			_ = cached.module.Close(context.Background())
+		}
+	}
+}
+
+// remove removes a module from the cache.
+func (r *Runner) remove(element *list.Element) {
+	cached := element.Value.(*cachedModule)
+	cached.evicted = true
+	r.lru.Remove(element)
+	delete(r.compiled, cached.digest)
+}
+
+// CachedModules returns the number of compiled modules in the cache.
+func (r *Runner) CachedModules() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lru.Len()
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+var _ = Describe("WebAssembly Runner", func() {
+	ctx := context.Background()
+
+	input := []byte(`apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: app-config
+  data:
+    site: edge-1
+`)
+
+	var dir string
+
+	// install copies a test module into the module directory as the module of the image.
+	install := func(module, image string) string {
+		wasm, err := os.ReadFile(filepath.Join("testdata", module+".wasm"))
+		Expect(err).NotTo(HaveOccurred())
+		name, tag := fn.ImageName(image), "latest"
+		if len(image) > len(name) {
+			tag = image[len(name)+1:]
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name), tag+".wasm")
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, wasm, 0o644)).To(Succeed())
+		sum := sha256.Sum256(wasm)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	newRunner := func(options Options) *Runner {
+		runner, err := NewRunner(ctx, DirLoader(dir), options)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(runner.Close, ctx)
+		return runner
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("should run a function and return its output", func() {
+		install("passthrough", "example.com/fns/passthrough:v1")
+		runner := newRunner(Options{})
+
+		output, err := runner.Run(ctx, &kpt.Function{Image: "example.com/fns/passthrough:v1"}, input)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(output)).To(Equal(string(input)))
+	})
+
+	It("should render a package with a function", func() {
+		install("passthrough", "example.com/fns/passthrough")
+		renderer := &fn.Renderer{Runner: newRunner(Options{})}
+
+		nodes, err := kpt.ReadResources(map[string]string{
+			"Kptfile": "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: app\n" +
+				"pipeline:\n  mutators:\n  - image: example.com/fns/passthrough\n",
+			"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\n",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		rendered, results, err := renderer.Render(ctx, nodes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(1))
+		Expect(rendered).To(HaveLen(2))
+		Expect(kpt.PathOf(rendered[1])).To(Equal("config.yaml"))
+	})
+
+	It("should return the results of a function that fails", func() {
+		install("fail", "example.com/fns/fail:v1")
+		renderer := &fn.Renderer{Runner: newRunner(Options{})}
+
+		nodes, err := kpt.ReadResources(map[string]string{
+			"Kptfile": "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: app\n" +
+				"pipeline:\n  validators:\n  - image: example.com/fns/fail:v1\n",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, results, err := renderer.Render(ctx, nodes)
+		Expect(err).To(MatchError(ContainSubstring("exited with status 1")))
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Results).To(HaveLen(1))
+		Expect(results[0].Results[0].Message).To(Equal("rejected by policy"))
+	})
+
+	It("should stop a function that runs for too long", func() {
+		install("loop", "example.com/fns/loop:v1")
+		runner := newRunner(Options{Timeout: 100 * time.Millisecond})
+
+		_, err := runner.Run(ctx, &kpt.Function{Image: "example.com/fns/loop:v1"}, input)
+		Expect(err).To(MatchError(ContainSubstring("did not finish within 100ms")))
+	})
+
+	It("should refuse a function that needs more memory than the limit", func() {
+		install("bigmem", "example.com/fns/bigmem:v1")
+		function := &kpt.Function{Image: "example.com/fns/bigmem:v1"}
+
+		_, err := newRunner(Options{MemoryLimit: 16 << 20}).Run(ctx, function, input)
+		Expect(err).To(HaveOccurred())
+
+		_, err = newRunner(Options{MemoryLimit: 128 << 20}).Run(ctx, function, input)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should bound the memory of a function by the limit of the context", func() {
+		install("grow", "example.com/fns/grow:v1")
+		install("bigmem", "example.com/fns/bigmem:v1")
+		runner := newRunner(Options{MemoryLimit: 128 << 20})
+		limited := fn.WithMemoryLimit(ctx, 16<<20)
+
+		_, err := runner.Run(ctx, &kpt.Function{Image: "example.com/fns/grow:v1"}, input)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = runner.Run(limited, &kpt.Function{Image: "example.com/fns/grow:v1"}, input)
+		Expect(err).To(MatchError(fn.ErrMemoryLimitExceeded))
+		_, err = runner.Run(limited, &kpt.Function{Image: "example.com/fns/bigmem:v1"}, input)
+		Expect(err).To(MatchError(fn.ErrMemoryLimitExceeded))
+		_, err = runner.Run(fn.WithMemoryLimit(ctx, 512<<20), &kpt.Function{Image: "example.com/fns/grow:v1"}, input)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should only run modules whose digests are allowed", func() {
+		allowed := install("passthrough", "example.com/fns/passthrough:v1")
+		install("fail", "example.com/fns/fail:v1")
+		runner := newRunner(Options{AllowedDigests: []string{allowed}})
+
+		_, err := runner.Run(ctx, &kpt.Function{Image: "example.com/fns/passthrough:v1"}, input)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = runner.Run(ctx, &kpt.Function{Image: "example.com/fns/fail:v1"}, input)
+		Expect(err).To(MatchError(ErrModuleNotAllowed))
+
+		By("checking the modules without running them")
+		Expect(runner.Check(ctx, &kpt.Function{Image: "example.com/fns/passthrough:v1"})).To(Succeed())
+		Expect(runner.Check(ctx, &kpt.Function{Image: "example.com/fns/fail:v1"})).To(MatchError(ErrModuleNotAllowed))
+	})
+
+	It("should check the digest of an image pinned by digest", func() {
+		digest := install("passthrough", "example.com/fns/passthrough:v1")
+		runner := newRunner(Options{})
+
+		_, err := runner.Run(ctx, &kpt.Function{Image: "example.com/fns/passthrough:v1@" + digest}, input)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = runner.Run(ctx, &kpt.Function{Image: "example.com/fns/passthrough:v1@sha256:0123"}, input)
+		Expect(err).To(MatchError(ContainSubstring("requires sha256:0123")))
+	})
+
+	It("should report functions without a module as not found", func() {
+		runner := newRunner(Options{})
+
+		_, err := runner.Run(ctx, &kpt.Function{Image: "example.com/fns/missing:v1"}, input)
+		Expect(err).To(MatchError(fn.ErrFunctionNotFound))
+		_, err = runner.Run(ctx, &kpt.Function{Image: "../../etc/passwd"}, input)
+		Expect(err).To(MatchError(ContainSubstring("invalid function image")))
+	})
+
+	It("should keep a bounded cache of compiled modules", func() {
+		install("passthrough", "example.com/fns/passthrough:v1")
+		install("fail", "example.com/fns/fail:v1")
+		runner := newRunner(Options{CacheSize: 1})
+
+		for range 2 {
+			_, err := runner.Run(ctx, &kpt.Function{Image: "example.com/fns/passthrough:v1"}, input)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runner.CachedModules()).To(Equal(1))
+		}
+		_, err := runner.Run(ctx, &kpt.Function{Image: "example.com/fns/fail:v1"}, input)
+		Expect(err).To(HaveOccurred())
+		Expect(runner.CachedModules()).To(Equal(1))
+		_, err = runner.Run(ctx, &kpt.Function{Image: "example.com/fns/passthrough:v1"}, input)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
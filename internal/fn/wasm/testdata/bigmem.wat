@@ -0,0 +1,4 @@
+;; bigmem is a function that needs 64MiB of memory to start.
+(module
+  (memory (export "memory") 1024)
+  (func (export "_start")))
@@ -0,0 +1,6 @@
+;; grow is a function that grows its memory by 64MiB, and traps if it cannot.
+(module
+  (memory (export "memory") 1)
+  (func (export "_start")
+    (if (i32.eq (memory.grow (i32.const 1024)) (i32.const -1))
+      (then unreachable))))
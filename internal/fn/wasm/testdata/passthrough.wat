@@ -0,0 +1,19 @@
+;; passthrough is a KRM function that copies its input ResourceList to its output unchanged.
+(module
+  (import "wasi_snapshot_preview1" "fd_read" (func $fd_read (param i32 i32 i32 i32) (result i32)))
+  (import "wasi_snapshot_preview1" "fd_write" (func $fd_write (param i32 i32 i32 i32) (result i32)))
+  (memory (export "memory") 1)
+  (func (export "_start")
+    (local $n i32)
+    (loop $copy
+      ;; The iovec at 0 points at a buffer filling the rest of the page.
+      (i32.store (i32.const 0) (i32.const 16))
+      (i32.store (i32.const 4) (i32.const 65504))
+      (if (call $fd_read (i32.const 0) (i32.const 0) (i32.const 1) (i32.const 8))
+        (then (return)))
+      (local.set $n (i32.load (i32.const 8)))
+      (if (i32.eqz (local.get $n))
+        (then (return)))
+      (i32.store (i32.const 4) (local.get $n))
+      (drop (call $fd_write (i32.const 1) (i32.const 0) (i32.const 1) (i32.const 12)))
+      (br $copy))))
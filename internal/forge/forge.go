@@ -0,0 +1,336 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package forge receives the push webhooks of git forges, and requests an immediate sync of the
+// Repositories of the pushed git repository.
+//
+// GitHub, GitLab and Gitea are supported. Every forge signs its webhooks with a secret that is
+// shared with the receiver:
+//
+//   - GitHub sends the hex HMAC-SHA256 of the body in X-Hub-Signature-256, prefixed by sha256=
+//   - Gitea sends the hex HMAC-SHA256 of the body in X-Gitea-Signature
+//   - GitLab sends the base64 HMAC-SHA256 of the webhook ID, timestamp and body in
+//     webhook-signature when the webhook has a signing token, as Standard Webhooks specifies,
+//     or else the secret token itself in X-Gitlab-Token
+//
+// A webhook whose signature does not match is refused.
+package forge
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/controller"
+)
+
+// Path is the path push webhooks are received on.
+const Path = "/push"
+
+// maxPayloadSize is the maximum size of a webhook payload, in bytes. Forges cap their payloads
+// well below it.
+const maxPayloadSize = 25 << 20
+
+// signatureTolerance is how far the timestamp of a signed GitLab webhook may be from the time it
+// is received, so that recorded webhooks cannot be replayed later.
+const signatureTolerance = 5 * time.Minute
+
+// Forge is a git forge that sends push webhooks.
+type Forge string
+
+const (
+	GitHub Forge = "github"
+	GitLab Forge = "gitlab"
+	Gitea  Forge = "gitea"
+)
+
+// Forges are the supported forges.
+var Forges = []Forge{GitHub, GitLab, Gitea}
+
+// pushEvent holds the fields of the push events of all the forges that identify the pushed
+// repository.
+type pushEvent struct {
+	Ref        string `json:"ref"`
+	Repository struct {
+		CloneURL   string `json:"clone_url"`
+		SSHURL     string `json:"ssh_url"`
+		HTMLURL    string `json:"html_url"`
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+	} `json:"repository"`
+	Project struct {
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+		WebURL     string `json:"web_url"`
+	} `json:"project"`
+}
+
+// urls returns the URLs of the pushed repository.
+func (e *pushEvent) urls() []string {
+	var urls []string
+	for _, u := range []string{e.Repository.CloneURL, e.Repository.SSHURL, e.Repository.HTMLURL,
+		e.Repository.GitHTTPURL, e.Repository.GitSSHURL, e.Project.GitHTTPURL, e.Project.GitSSHURL, e.Project.WebURL} {
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// Handler receives push webhooks, and requests a sync of every Repository of the pushed git
+// repository by setting its sync request annotation.
+type Handler struct {
+	Client client.Client
+
+	// Secrets are the secrets shared with each forge. Webhooks from forges without a secret are
+	// refused.
+	Secrets map[Forge][]byte
+
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+var _ http.Handler = &Handler{}
+
+// +kubebuilder:rbac:groups=porch.kpt.dev,resources=repositories,verbs=get;list;watch;patch
+
+// ServeHTTP receives a push webhook.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	log := logf.FromContext(req.Context())
+
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	forge, event := identify(req.Header)
+	if forge == "" {
+		http.Error(w, "the request is not a GitHub, GitLab or Gitea webhook", http.StatusBadRequest)
+		return
+	}
+	if err := h.verify(forge, req.Header, body); err != nil {
+		log.Info("Refusing push webhook", "forge", forge, "error", err.Error())
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !isPush(forge, event) {
+		// Forges send other events, such as pings when a webhook is created, that need no sync.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	push := &pushEvent{}
+	if err := json.Unmarshal(body, push); err != nil {
+		http.Error(w, fmt.Sprintf("invalid push event: %v", err), http.StatusBadRequest)
+		return
+	}
+	synced, err := h.requestSync(req, push)
+	if err != nil {
+		log.Error(err, "Failed to request repository sync", "forge", forge)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Info("Received push webhook", "forge", forge, "ref", push.Ref, "repositories", synced)
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = fmt.Fprintf(w, "sync requested for %d repositories\n", synced)
+}
+
+// identify returns the forge that sent a webhook, and its event type.
+func identify(header http.Header) (Forge, string) {
+	// Gitea also sends the GitHub event header, for compatibility, so it is identified first.
+	switch {
+	case header.Get("X-Gitea-Event") != "":
+		return Gitea, header.Get("X-Gitea-Event")
+	case header.Get("X-Gitlab-Event") != "":
+		return GitLab, header.Get("X-Gitlab-Event")
+	case header.Get("X-GitHub-Event") != "":
+		return GitHub, header.Get("X-GitHub-Event")
+	}
+	return "", ""
+}
+
+// isPush returns true if the event of the forge is a push of branches or tags.
+func isPush(forge Forge, event string) bool {
+	if forge == GitLab {
+		return event == "Push Hook" || event == "Tag Push Hook"
+	}
+	return event == "push"
+}
+
+// verify checks the signature of a webhook against the secret shared with the forge.
+func (h *Handler) verify(forge Forge, header http.Header, body []byte) error {
+	secret := h.Secrets[forge]
+	if len(secret) == 0 {
+		return fmt.Errorf("webhooks from %s are not accepted", forge)
+	}
+
+	switch forge {
+	case GitHub:
+		signature, found := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		if !found {
+			return fmt.Errorf("X-Hub-Signature-256 is missing")
+		}
+		return verifyHex(secret, body, signature)
+	case Gitea:
+		signature := header.Get("X-Gitea-Signature")
+		if signature == "" {
+			return fmt.Errorf("X-Gitea-Signature is missing")
+		}
+		return verifyHex(secret, body, signature)
+	case GitLab:
+		if header.Get("webhook-signature") != "" {
+			return h.verifyStandard(secret, header, body)
+		}
+		token := header.Get("X-Gitlab-Token")
+		if token == "" {
+			return fmt.Errorf("webhook-signature and X-Gitlab-Token are missing")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), secret) != 1 {
+			return fmt.Errorf("X-Gitlab-Token does not match")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported forge %s", forge)
+}
+
+// verifyHex checks a hex HMAC-SHA256 signature of the body.
+func verifyHex(secret, body []byte, signature string) error {
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if !hmac.Equal(decoded, mac.Sum(nil)) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+// verifyStandard checks a Standard Webhooks signature: the base64 HMAC-SHA256 of the webhook ID,
+// timestamp and body, keyed by the base64 signing token after its whsec_ prefix. The signature
+// header may hold several space separated signatures, one of which must match.
+func (h *Handler) verifyStandard(secret []byte, header http.Header, body []byte) error {
+	id, timestamp := header.Get("webhook-id"), header.Get("webhook-timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if id == "" || err != nil {
+		return fmt.Errorf("webhook-id or webhook-timestamp is missing or invalid")
+	}
+	now := time.Now
+	if h.Now != nil {
+		now = h.Now
+	}
+	if age := now().Sub(time.Unix(seconds, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return fmt.Errorf("webhook-timestamp is outside the %s tolerance", signatureTolerance)
+	}
+
+	key := secret
+	if encoded, found := strings.CutPrefix(string(secret), "whsec_"); found {
+		if key, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return fmt.Errorf("invalid signing token: %w", err)
+		}
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, versioned := range strings.Fields(header.Get("webhook-signature")) {
+		signature, found := strings.CutPrefix(versioned, "v1,")
+		if !found {
+			continue
+		}
+		if decoded, err := base64.StdEncoding.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature does not match")
+}
+
+// requestSync sets the sync request annotation of every Repository of the pushed repository,
+// and returns how many there are.
+func (h *Handler) requestSync(req *http.Request, push *pushEvent) (int, error) {
+	pushed := map[string]bool{}
+	for _, u := range push.urls() {
+		pushed[normalizeURL(u)] = true
+	}
+
+	repos := &cachev1alpha1.RepositoryList{}
+	if err := h.Client.List(req.Context(), repos); err != nil {
+		return 0, err
+	}
+	now := time.Now
+	if h.Now != nil {
+		now = h.Now
+	}
+	requested := now().UTC().Format(time.RFC3339Nano)
+	synced := 0
+	for i := range repos.Items {
+		repo := &repos.Items[i]
+		if repo.Spec.Git == nil || !pushed[normalizeURL(repo.Spec.Git.Repo)] {
+			continue
+		}
+		patch := client.MergeFrom(repo.DeepCopy())
+		annotations := repo.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[controller.SyncRequestAnnotation] = requested
+		repo.SetAnnotations(annotations)
+		if err := h.Client.Patch(req.Context(), repo, patch); err != nil {
+			return synced, fmt.Errorf("failed to request sync of Repository %s/%s: %w", repo.Namespace, repo.Name, err)
+		}
+		synced++
+	}
+	return synced, nil
+}
+
+// normalizeURL returns the host and path of a git repository URL, so that the HTTPS, SSH and web
+// URLs of a repository compare equal.
+func normalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	host, repoPath := "", raw
+	if !strings.Contains(raw, "://") {
+		// An scp-like SSH address, such as git@github.com:org/repo.git.
+		if at := strings.Index(raw, "@"); at >= 0 {
+			raw = raw[at+1:]
+		}
+		host, repoPath, _ = strings.Cut(raw, ":")
+	} else if u, err := url.Parse(raw); err == nil {
+		host, repoPath = u.Hostname(), u.Path
+	}
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	return strings.ToLower(host + "/" + repoPath)
+}
@@ -0,0 +1,349 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// The synthetic repository the benchmarks run against: a monorepo whose package directory holds
+// a few hundred small packages, next to large assets that are rewritten throughout a long history
+// of which only a few commits are tagged.
+// Run the benchmarks with go test -run '^$' -bench . ./internal/git/
+const (
+	syntheticPackages     = 200
+	syntheticPackageFiles = 10
+	syntheticFileSize     = 1 << 10
+	syntheticAssets       = 2000
+	syntheticAssetSize    = 16 << 10
+	syntheticCommits      = 500
+	syntheticAssetChurn   = 10
+	syntheticTagEvery     = 250
+)
+
+// syntheticRepository writes the synthetic repository to a bare repository in a temporary
+// directory, and returns the directory.
+func syntheticRepository(b *testing.B) string {
+	dir := b.TempDir()
+	bare, err := gogit.PlainInit(dir, true)
+	if err != nil {
+		b.Fatal(err)
+	}
+	// Pushes to the repository would otherwise start garbage collections in the background.
+	cfg, err := bare.Config()
+	if err != nil {
+		b.Fatal(err)
+	}
+	cfg.Raw.Section("receive").SetOption("autogc", "false")
+	if err := bare.SetConfig(cfg); err != nil {
+		b.Fatal(err)
+	}
+	builder := &Repository{repo: bare}
+	random := rand.New(rand.NewSource(1))
+	words := []string{"apiVersion", "kind", "metadata", "name", "namespace", "labels", "spec", "replicas", "image",
+		"containers", "ports", "resources", "limits", "requests", "cpu", "memory", "env", "value", "volumes", "config"}
+	// content returns YAML-like text, which compresses about as well as the resources of packages.
+	content := func(size int) string {
+		var text strings.Builder
+		for text.Len() < size {
+			fmt.Fprintf(&text, "%s%s: %s-%d\n", strings.Repeat("  ", random.Intn(4)), words[random.Intn(len(words))],
+				words[random.Intn(len(words))], random.Intn(100000))
+		}
+		return text.String()
+	}
+	must := func(hash plumbing.Hash, err error) plumbing.Hash {
+		b.Helper()
+		if err != nil {
+			b.Fatal(err)
+		}
+		return hash
+	}
+
+	packageFiles := func(pkg int) map[string]string {
+		files := map[string]string{"Kptfile": fmt.Sprintf("apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: pkg-%03d\n", pkg)}
+		for i := 1; i < syntheticPackageFiles; i++ {
+			files[fmt.Sprintf("resource-%02d.yaml", i)] = content(syntheticFileSize)
+		}
+		return files
+	}
+	root := plumbing.ZeroHash
+	for pkg := range syntheticPackages {
+		root = must(builder.replaceTree(root, []string{"packages", fmt.Sprintf("pkg-%03d", pkg)}, must(builder.writeFiles(packageFiles(pkg)))))
+	}
+	var assets []object.TreeEntry
+	for i := range syntheticAssets {
+		assets = append(assets, object.TreeEntry{Name: fmt.Sprintf("asset-%04d.bin", i), Mode: filemode.Regular,
+			Hash: must(builder.writeBlob(content(syntheticAssetSize)))})
+	}
+
+	var parents []plumbing.Hash
+	for i := range syntheticCommits {
+		if i > 0 {
+			pkg := random.Intn(syntheticPackages)
+			root = must(builder.replaceTree(root, []string{"packages", fmt.Sprintf("pkg-%03d", pkg)}, must(builder.writeFiles(packageFiles(pkg)))))
+			for range syntheticAssetChurn {
+				assets[random.Intn(len(assets))].Hash = must(builder.writeBlob(content(syntheticAssetSize)))
+			}
+		}
+		root = must(builder.replaceTree(root, []string{"assets"}, must(builder.writeTree(slices.Clone(assets)))))
+
+		signature := object.Signature{Name: "porch", Email: "porch@example.com", When: time.Unix(1700000000+int64(i), 0)}
+		commit := &object.Commit{Author: signature, Committer: signature, Message: fmt.Sprintf("commit %d", i),
+			TreeHash: root, ParentHashes: parents}
+		obj := bare.Storer.NewEncodedObject()
+		if err := commit.Encode(obj); err != nil {
+			b.Fatal(err)
+		}
+		hash := must(bare.Storer.SetEncodedObject(obj))
+		parents = []plumbing.Hash{hash}
+		if (i+1)%syntheticTagEvery == 0 {
+			tag := plumbing.NewTagReferenceName(fmt.Sprintf("pkg-%03d/v%d", i%syntheticPackages, (i+1)/syntheticTagEvery))
+			if err := bare.Storer.SetReference(plumbing.NewHashReference(tag, hash)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	if err := bare.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(DefaultBranch), parents[0])); err != nil {
+		b.Fatal(err)
+	}
+	return dir
+}
+
+// dirSize returns the total size of the files in the directory, in bytes.
+func dirSize(b *testing.B, dir string) int64 {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err == nil {
+			size += info.Size()
+		}
+		return err
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return size
+}
+
+func BenchmarkSyntheticRepository(b *testing.B) {
+	ctx := context.Background()
+	remote := syntheticRepository(b)
+	b.Logf("synthetic repository: %.1f MiB", float64(dirSize(b, remote))/(1<<20))
+
+	fetched := func(b *testing.B, repositories *Repositories) *Repository {
+		mirror, err := repositories.Fetch(ctx, remote, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return mirror
+	}
+
+	for _, depth := range []int{0, 1} {
+		b.Run(fmt.Sprintf("Fetch/depth=%d", depth), func(b *testing.B) {
+			var size int64
+			for range b.N {
+				b.StopTimer()
+				stores, err := os.MkdirTemp(b.TempDir(), "stores")
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+				fetched(b, &Repositories{Dir: stores, Depth: depth})
+				b.StopTimer()
+				size = dirSize(b, stores)
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(size)/(1<<20), "store-MiB")
+		})
+	}
+
+	mirror := fetched(b, &Repositories{Dir: b.TempDir(), Depth: 1})
+
+	b.Run("Discover/full", func(b *testing.B) {
+		for range b.N {
+			mirror.snapshots = map[string]*Snapshot{}
+			if _, err := mirror.Discover(ctx, "", "packages"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Discover/incremental", func(b *testing.B) {
+		for range b.N {
+			if _, err := mirror.Discover(ctx, "", "packages"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	snapshot, err := mirror.Discover(ctx, "", "packages")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Contents", func(b *testing.B) {
+		for i := range b.N {
+			ref := snapshot.Refs[i%len(snapshot.Refs)]
+			if _, err := mirror.Contents(ctx, ref.Commit, "packages", ref.Package); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("GC", func(b *testing.B) {
+		for range b.N {
+			if err := mirror.GC(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(dirSize(b, mirror.dir))/(1<<20), "store-MiB")
+	})
+}
+
+// writeFiles writes the files, by path, to the object store as a tree, and returns the hash of
+// the tree, or the zero hash if there are no files.
+func (r *Repository) writeFiles(files map[string]string) (plumbing.Hash, error) {
+	var entries []object.TreeEntry
+	dirs := map[string]map[string]string{}
+	for name, content := range files {
+		if path.IsAbs(name) || path.Clean(name) != name || name == "." || name == ".." || strings.HasPrefix(name, "../") {
+			return plumbing.ZeroHash, fmt.Errorf("invalid file name %q", name)
+		}
+		if dir, rest, nested := strings.Cut(name, "/"); nested {
+			if dirs[dir] == nil {
+				dirs[dir] = map[string]string{}
+			}
+			dirs[dir][rest] = content
+			continue
+		}
+		hash, err := r.writeBlob(content)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: hash})
+	}
+	for dir, nested := range dirs {
+		if slices.ContainsFunc(entries, func(entry object.TreeEntry) bool { return entry.Name == dir }) {
+			return plumbing.ZeroHash, fmt.Errorf("%q is both a file and a directory", dir)
+		}
+		hash, err := r.writeFiles(nested)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: hash})
+	}
+	if len(entries) == 0 {
+		return plumbing.ZeroHash, nil
+	}
+	return r.writeTree(entries)
+}
+
+// replaceTree replaces the directory at the path in a tree with a subtree, writing the trees on
+// the path to the object store, and returns the hash of the new tree. A zero hash is an empty
+// tree: the directory is removed if the subtree is empty, and so are the directories on the path
+// it leaves empty.
+func (r *Repository) replaceTree(tree plumbing.Hash, parts []string, subtree plumbing.Hash) (plumbing.Hash, error) {
+	if len(parts) == 0 {
+		return subtree, nil
+	}
+	var entries []object.TreeEntry
+	if !tree.IsZero() {
+		t, err := r.repo.TreeObject(tree)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = slices.Clone(t.Entries)
+	}
+
+	i := slices.IndexFunc(entries, func(entry object.TreeEntry) bool { return entry.Name == parts[0] })
+	child := plumbing.ZeroHash
+	if i >= 0 && entries[i].Mode == filemode.Dir {
+		child = entries[i].Hash
+	}
+	child, err := r.replaceTree(child, parts[1:], subtree)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	switch {
+	case child.IsZero() && i >= 0:
+		entries = slices.Delete(entries, i, i+1)
+	case child.IsZero():
+	case i >= 0:
+		entries[i] = object.TreeEntry{Name: parts[0], Mode: filemode.Dir, Hash: child}
+	default:
+		entries = append(entries, object.TreeEntry{Name: parts[0], Mode: filemode.Dir, Hash: child})
+	}
+	if len(entries) == 0 {
+		return plumbing.ZeroHash, nil
+	}
+	return r.writeTree(entries)
+}
+
+// writeTree writes a tree with the entries to the object store.
+func (r *Repository) writeTree(entries []object.TreeEntry) (plumbing.Hash, error) {
+	// Git sorts the entries of trees by name, comparing the names of directories as if they
+	// ended with a slash.
+	sortName := func(entry object.TreeEntry) string {
+		if entry.Mode == filemode.Dir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	slices.SortFunc(entries, func(a, b object.TreeEntry) int { return strings.Compare(sortName(a), sortName(b)) })
+
+	obj := r.repo.Storer.NewEncodedObject()
+	if err := (&object.Tree{Entries: entries}).Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.repo.Storer.SetEncodedObject(obj)
+}
+
+// writeBlob writes a blob with the content to the object store.
+func (r *Repository) writeBlob(content string) (plumbing.Hash, error) {
+	obj := r.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	_, err = io.WriteString(w, content)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.repo.Storer.SetEncodedObject(obj)
+}
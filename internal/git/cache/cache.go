@@ -0,0 +1,268 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cache caches the contents of the packages read from git repositories, so that they are
+// read from git once rather than on every reconcile.
+//
+// Contents are keyed by the URL of the git repository, the commit and the directory of the
+// package. A commit never changes, so neither do the contents stored under a key, and Repositories
+// of the same git repository share the cached contents. Entries are only invalidated because
+// they are no longer needed: a sync of a Repository invalidates the contents of the revisions
+// whose refs have moved away from their commit or have been deleted.
+//
+// The cache has two tiers. The memory tier holds the most recently used contents; the optional
+// disk tier holds more of them, and survives restarts when it is kept on a persistent volume.
+// Each tier evicts its least recently used contents once their total size exceeds its size.
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"maps"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// keyVersion is part of every digest, and is changed whenever the way contents are read changes,
+// so that contents cached on disk by earlier versions are not used.
+const keyVersion = "v1"
+
+const (
+	tierMemory = "memory"
+	tierDisk   = "disk"
+)
+
+var (
+	hits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "porch_package_cache_hits_total",
+		Help: "Number of package contents found in the package cache, by tier.",
+	}, []string{"tier"})
+	misses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "porch_package_cache_misses_total",
+		Help: "Number of package contents not found in the package cache.",
+	})
+	evictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "porch_package_cache_evictions_total",
+		Help: "Number of package contents evicted from the package cache, by tier.",
+	}, []string{"tier"})
+	invalidations = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "porch_package_cache_invalidations_total",
+		Help: "Number of package contents invalidated by repository syncs.",
+	})
+	size = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "porch_package_cache_size_bytes",
+		Help: "Total size of the package contents in the package cache, by tier.",
+	}, []string{"tier"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(hits, misses, evictions, invalidations, size)
+}
+
+// Key identifies the contents of a package.
+type Key struct {
+	// Repository is the URL of the git repository.
+	Repository string
+
+	// Commit is the hash of the commit.
+	Commit string
+
+	// Directory is the path of the package relative to the root of the repository.
+	Directory string
+}
+
+// digest returns the hex encoded SHA-256 digest of the key.
+func (k Key) digest() string {
+	hash := sha256.New()
+	for _, part := range []string{keyVersion, k.Repository, k.Commit, k.Directory} {
+		// Each part is preceded by its length, so that parts cannot run into each other.
+		hash.Write(binary.BigEndian.AppendUint64(nil, uint64(len(part))))
+		hash.Write([]byte(part))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// sizeOf returns the size of package contents, in bytes.
+func sizeOf(contents map[string]string) int64 {
+	var n int64
+	for name, content := range contents {
+		n += int64(len(name) + len(content))
+	}
+	return n
+}
+
+// Cache stores package contents by key in memory and, optionally, on disk. It is safe for
+// concurrent use.
+type Cache struct {
+	mu       sync.Mutex
+	lru      *lru
+	contents map[string]map[string]string
+
+	// disk is the disk tier, or nil if there is none.
+	disk *diskTier
+}
+
+// New returns a Cache that holds up to memorySize bytes of contents in memory. If dir is not
+// empty, up to diskSize bytes of contents are also kept in dir, picking up the contents already
+// there.
+func New(memorySize int64, dir string, diskSize int64) (*Cache, error) {
+	c := &Cache{lru: newLRU(tierMemory, memorySize), contents: map[string]map[string]string{}}
+	if dir != "" {
+		disk, err := newDiskTier(dir, diskSize)
+		if err != nil {
+			return nil, err
+		}
+		c.disk = disk
+	}
+	return c, nil
+}
+
+// Get returns a copy of the contents stored under the key, if any. Contents found on disk are
+// kept in memory again.
+func (c *Cache) Get(key Key) (map[string]string, bool) {
+	digest := key.digest()
+	c.mu.Lock()
+	if c.lru.touch(digest) {
+		contents := maps.Clone(c.contents[digest])
+		c.mu.Unlock()
+		hits.WithLabelValues(tierMemory).Inc()
+		return contents, true
+	}
+	c.mu.Unlock()
+
+	if c.disk != nil {
+		if contents, found := c.disk.get(digest, key); found {
+			hits.WithLabelValues(tierDisk).Inc()
+			c.addToMemory(digest, contents)
+			return maps.Clone(contents), true
+		}
+	}
+	misses.Inc()
+	return nil, false
+}
+
+// Add stores a copy of the contents under the key, evicting other contents if the cache is full.
+func (c *Cache) Add(key Key, contents map[string]string) error {
+	digest := key.digest()
+	contents = maps.Clone(contents)
+	c.addToMemory(digest, contents)
+	if c.disk != nil {
+		return c.disk.add(digest, key, contents)
+	}
+	return nil
+}
+
+// addToMemory stores the contents in the memory tier.
+func (c *Cache) addToMemory(digest string, contents map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.contents[digest] = contents
+	for _, evicted := range c.lru.add(digest, sizeOf(contents)) {
+		delete(c.contents, evicted)
+	}
+}
+
+// Invalidate removes the contents stored under the keys from every tier.
+func (c *Cache) Invalidate(keys ...Key) error {
+	for _, key := range keys {
+		digest := key.digest()
+		c.mu.Lock()
+		c.lru.remove(digest)
+		delete(c.contents, digest)
+		c.mu.Unlock()
+		if c.disk != nil {
+			if err := c.disk.remove(digest); err != nil {
+				return err
+			}
+		}
+		invalidations.Inc()
+	}
+	return nil
+}
+
+// Len returns the number of contents in the memory tier.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.contents)
+}
+
+// lru tracks the sizes of the entries of a tier, from most to least recently used, and which
+// entries to evict to keep the tier within its size. It is not safe for concurrent use.
+type lru struct {
+	tier    string
+	maxSize int64
+	size    int64
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// lruEntry is an entry tracked by an lru.
+type lruEntry struct {
+	digest string
+	size   int64
+}
+
+func newLRU(tier string, maxSize int64) *lru {
+	return &lru{tier: tier, maxSize: maxSize, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// touch marks the entry as the most recently used, and returns false if there is no such entry.
+func (l *lru) touch(digest string) bool {
+	element, found := l.entries[digest]
+	if found {
+		l.order.MoveToFront(element)
+	}
+	return found
+}
+
+// add adds or replaces an entry as the most recently used, and returns the digests of the
+// entries that must be evicted, which may include the entry itself if it is larger than the tier.
+func (l *lru) add(digest string, entrySize int64) []string {
+	if element, found := l.entries[digest]; found {
+		l.size -= element.Value.(*lruEntry).size
+		element.Value.(*lruEntry).size = entrySize
+		l.order.MoveToFront(element)
+	} else {
+		l.entries[digest] = l.order.PushFront(&lruEntry{digest: digest, size: entrySize})
+	}
+	l.size += entrySize
+
+	var evicted []string
+	for l.size > l.maxSize && l.order.Len() > 0 {
+		entry := l.order.Remove(l.order.Back()).(*lruEntry)
+		delete(l.entries, entry.digest)
+		l.size -= entry.size
+		evicted = append(evicted, entry.digest)
+	}
+	evictions.WithLabelValues(l.tier).Add(float64(len(evicted)))
+	size.WithLabelValues(l.tier).Set(float64(l.size))
+	return evicted
+}
+
+// remove removes an entry.
+func (l *lru) remove(digest string) {
+	if element, found := l.entries[digest]; found {
+		l.size -= element.Value.(*lruEntry).size
+		l.order.Remove(element)
+		delete(l.entries, digest)
+		size.WithLabelValues(l.tier).Set(float64(l.size))
+	}
+}
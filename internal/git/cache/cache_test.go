@@ -0,0 +1,168 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// value returns the value of a counter.
+func value(counter prometheus.Counter) float64 {
+	metric := &dto.Metric{}
+	Expect(counter.Write(metric)).To(Succeed())
+	return metric.GetCounter().GetValue()
+}
+
+var _ = Describe("Package Cache", func() {
+	key := func(commit string) Key {
+		return Key{Repository: "https://example.com/blueprints.git", Commit: commit, Directory: "packages/app"}
+	}
+	contents := map[string]string{"Kptfile": "1234"}
+
+	It("should return copies of the contents stored under a key", func() {
+		cache, err := New(1<<20, "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		hitsBefore, missesBefore := value(hits.WithLabelValues(tierMemory)), value(misses)
+
+		_, found := cache.Get(key("a"))
+		Expect(found).To(BeFalse())
+		Expect(cache.Add(key("a"), contents)).To(Succeed())
+		got, found := cache.Get(key("a"))
+		Expect(found).To(BeTrue())
+		Expect(got).To(Equal(contents))
+		got["Kptfile"] = "changed"
+		got, _ = cache.Get(key("a"))
+		Expect(got).To(Equal(contents))
+
+		other := key("a")
+		other.Directory = "packages/other"
+		_, found = cache.Get(other)
+		Expect(found).To(BeFalse())
+
+		Expect(value(hits.WithLabelValues(tierMemory)) - hitsBefore).To(Equal(2.0))
+		Expect(value(misses) - missesBefore).To(Equal(2.0))
+	})
+
+	It("should use keys that do not depend on the process", func() {
+		Expect(key("a").digest()).To(Equal(key("a").digest()))
+		Expect(key("a").digest()).To(HaveLen(64))
+		Expect(Key{Repository: "ab", Commit: "c"}.digest()).NotTo(Equal(Key{Repository: "a", Commit: "bc"}.digest()))
+	})
+
+	It("should evict the least recently used contents from memory", func() {
+		cache, err := New(25, "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		evictionsBefore := value(evictions.WithLabelValues(tierMemory))
+		Expect(cache.Add(key("a"), contents)).To(Succeed())
+		Expect(cache.Add(key("b"), contents)).To(Succeed())
+		_, found := cache.Get(key("a"))
+		Expect(found).To(BeTrue())
+		Expect(cache.Add(key("c"), contents)).To(Succeed())
+
+		_, found = cache.Get(key("b"))
+		Expect(found).To(BeFalse())
+		Expect(cache.Len()).To(Equal(2))
+		Expect(value(evictions.WithLabelValues(tierMemory)) - evictionsBefore).To(Equal(1.0))
+
+		Expect(cache.Add(key("d"), map[string]string{"Kptfile": "12345678901234567890123456"})).To(Succeed())
+		_, found = cache.Get(key("d"))
+		Expect(found).To(BeFalse())
+	})
+
+	It("should keep contents on disk across restarts", func() {
+		dir := GinkgoT().TempDir()
+		cache, err := New(1<<20, dir, 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.Add(key("a"), contents)).To(Succeed())
+
+		cache, err = New(1<<20, dir, 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.Len()).To(BeZero())
+		diskHitsBefore := value(hits.WithLabelValues(tierDisk))
+		got, found := cache.Get(key("a"))
+		Expect(found).To(BeTrue())
+		Expect(got).To(Equal(contents))
+		Expect(value(hits.WithLabelValues(tierDisk)) - diskHitsBefore).To(Equal(1.0))
+		Expect(cache.Len()).To(Equal(1))
+	})
+
+	It("should evict the least recently used contents from disk", func() {
+		dir := GinkgoT().TempDir()
+		entry, err := json.Marshal(&diskEntry{Key: key("a"), Contents: contents})
+		Expect(err).NotTo(HaveOccurred())
+		entrySize := int64(len(entry))
+		cache, err := New(0, dir, 2*entrySize+entrySize/2)
+		Expect(err).NotTo(HaveOccurred())
+		a, b := key("a").digest(), key("b").digest()
+		Expect(cache.Add(key("a"), contents)).To(Succeed())
+		Expect(cache.Add(key("b"), contents)).To(Succeed())
+		_, found := cache.Get(key("a"))
+		Expect(found).To(BeTrue())
+		Expect(cache.Add(key("c"), contents)).To(Succeed())
+
+		Expect(filepath.Join(dir, b[:2], b)).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(dir, a[:2], a)).To(BeAnExistingFile())
+
+		// Files that are not contents are cleaned up, and contents beyond the size are evicted.
+		Expect(os.WriteFile(filepath.Join(dir, a[:2], a+".123.tmp"), []byte("x"), 0o600)).To(Succeed())
+		cache, err = New(0, dir, entrySize+entrySize/2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.disk.lru.order.Len()).To(Equal(1))
+		Expect(filepath.Join(dir, a[:2], a+".123.tmp")).NotTo(BeAnExistingFile())
+	})
+
+	It("should invalidate contents in every tier", func() {
+		dir := GinkgoT().TempDir()
+		cache, err := New(1<<20, dir, 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.Add(key("a"), contents)).To(Succeed())
+		Expect(cache.Add(key("b"), contents)).To(Succeed())
+		invalidationsBefore := value(invalidations)
+
+		Expect(cache.Invalidate(key("a"))).To(Succeed())
+		_, found := cache.Get(key("a"))
+		Expect(found).To(BeFalse())
+		a := key("a").digest()
+		Expect(filepath.Join(dir, a[:2], a)).NotTo(BeAnExistingFile())
+		_, found = cache.Get(key("b"))
+		Expect(found).To(BeTrue())
+		Expect(value(invalidations) - invalidationsBefore).To(Equal(1.0))
+	})
+
+	It("should not return contents stored on disk for another key", func() {
+		dir := GinkgoT().TempDir()
+		cache, err := New(0, dir, 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.Add(key("a"), contents)).To(Succeed())
+		a, b := key("a").digest(), key("b").digest()
+		Expect(os.MkdirAll(filepath.Join(dir, b[:2]), 0o750)).To(Succeed())
+		Expect(os.Rename(filepath.Join(dir, a[:2], a), filepath.Join(dir, b[:2], b))).To(Succeed())
+
+		cache, err = New(0, dir, 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		_, found := cache.Get(key("b"))
+		Expect(found).To(BeFalse())
+		Expect(filepath.Join(dir, b[:2], b)).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,170 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// diskEntry is the content of the file of an entry of the disk tier. The key is kept with the
+// contents, so that they are never returned for another key.
+type diskEntry struct {
+	Key      Key               `json:"key"`
+	Contents map[string]string `json:"contents"`
+}
+
+// diskTier keeps contents in files in a directory. The contents stored under a digest are in the
+// file <digest[:2]>/<digest> of the directory, and the modification time of the file is the last
+// time they were used.
+type diskTier struct {
+	dir string
+
+	mu  sync.Mutex
+	lru *lru
+}
+
+// newDiskTier returns a diskTier that holds up to maxSize bytes of files in dir, picking up the
+// files already there.
+func newDiskTier(dir string, maxSize int64) (*diskTier, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	type file struct {
+		digest  string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if !isDigest(entry.Name()) || filepath.Dir(path) != filepath.Join(dir, entry.Name()[:2]) {
+			// Left behind by a write that did not finish.
+			return os.Remove(path)
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, file{digest: entry.Name(), size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package cache %s: %w", dir, err)
+	}
+
+	t := &diskTier{dir: dir, lru: newLRU(tierDisk, maxSize)}
+	slices.SortFunc(files, func(a, b file) int { return a.modTime.Compare(b.modTime) })
+	for _, f := range files {
+		for _, evicted := range t.lru.add(f.digest, f.size) {
+			_ = os.Remove(t.path(evicted))
+		}
+	}
+	return t, nil
+}
+
+// get returns the contents stored under the digest for the key, if any.
+func (t *diskTier) get(digest string, key Key) (map[string]string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.lru.touch(digest) {
+		return nil, false
+	}
+	data, err := os.ReadFile(t.path(digest))
+	entry := &diskEntry{}
+	if err == nil {
+		err = json.Unmarshal(data, entry)
+	}
+	if err != nil || entry.Key != key {
+		t.lru.remove(digest)
+		_ = os.Remove(t.path(digest))
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(t.path(digest), now, now)
+	return entry.Contents, true
+}
+
+// add stores the contents of the key under the digest. The contents are written to a temporary
+// file that is then renamed, so that partly written contents are never read.
+func (t *diskTier) add(digest string, key Key, contents map[string]string) error {
+	data, err := json.Marshal(&diskEntry{Key: key, Contents: contents})
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path := t.path(digest)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), digest+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name())
+		return err
+	}
+
+	for _, evicted := range t.lru.add(digest, int64(len(data))) {
+		if err := os.Remove(t.path(evicted)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove removes the contents stored under the digest.
+func (t *diskTier) remove(digest string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lru.remove(digest)
+	if err := os.Remove(t.path(digest)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path returns the path of the file of the contents stored under the digest.
+func (t *diskTier) path(digest string) string {
+	return filepath.Join(t.dir, digest[:2], digest)
+}
+
+// isDigest returns true if name is a digest, as returned by Key.digest.
+func isDigest(name string) bool {
+	decoded, err := hex.DecodeString(name)
+	return err == nil && len(decoded) == 32
+}
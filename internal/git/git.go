@@ -0,0 +1,481 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package git discovers the kpt packages stored in git repositories and reads their contents.
+//
+// Every subdirectory of the package directory of a repository that holds a Kptfile is a package,
+// and is not searched for further packages. The revisions of a package are stored as git refs:
+//
+//   - the package on the branch of the repository is its Published revision -1, in the workspace
+//     named after the branch
+//   - the tag <package>/v<N> is its Published revision N, in the workspace v<N>
+//   - the branch drafts/<package>/<workspace> is a Draft revision in the workspace
+//   - the branch proposed/<package>/<workspace> is a Proposed revision in the workspace
+//
+// where <package> is the path of the package relative to the package directory.
+//
+// Every remote repository is mirrored once, into a bare object store shared by all the
+// Repositories of the remote. Fetches are shallow, so that only the last commits of the refs are
+// stored, and nothing is ever checked out: packages are read as trees of the object store, and
+// only the trees of the package directory are read. Object stores are garbage collected
+// periodically, dropping the objects that the refs no longer need.
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	corev1 "k8s.io/api/core/v1"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+const (
+	// DefaultBranch is the branch holding the packages of a repository that does not name one.
+	DefaultBranch = "main"
+
+	draftsPrefix   = "drafts/"
+	proposedPrefix = "proposed/"
+)
+
+// refSpecs mirror the branches and tags of a remote repository.
+var refSpecs = []config.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+// PackageRef is a revision of a package found in a repository.
+type PackageRef struct {
+	// Package is the path of the package relative to the package directory of the repository.
+	Package string
+
+	// Workspace is the workspace of the revision.
+	Workspace string
+
+	// Revision is the revision number, which is 0 for unpublished revisions.
+	Revision int
+
+	// Lifecycle is the lifecycle of the revision.
+	Lifecycle cachev1alpha1.PackageRevisionLifecycle
+
+	// Ref is the full name of the git ref the revision is stored in.
+	Ref string
+
+	// Commit is the hash of the commit the ref points to.
+	Commit string
+}
+
+// Repository is a local mirror of the branches and tags of a remote git repository. Its objects
+// are kept in a bare object store, in memory or on disk, that is opened by its first fetch.
+type Repository struct {
+	url string
+
+	// dir is the directory of the object store on disk, or empty to keep it in memory.
+	dir string
+
+	// depth is the number of commits fetched from the tips of the refs, or 0 for all of them.
+	depth int
+
+	mu   sync.Mutex
+	repo *gogit.Repository
+
+	// snapshots are the outcomes of the last discoveries, by branch and directory.
+	snapshots map[string]*Snapshot
+}
+
+// Fetch brings the mirror up to date with the remote repository, removing the refs that have
+// been deleted from it. Only the last commits of the refs are fetched, to the depth of the
+// mirror; the commits fetched earlier are kept.
+func (r *Repository) Fetch(ctx context.Context, auth transport.AuthMethod) (err error) {
+	ctx, end := r.start(ctx, operationFetch)
+	defer end(&err)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.repo == nil {
+		repo, err := r.open()
+		if err != nil {
+			return fmt.Errorf("failed to open mirror of %s: %w", r.url, err)
+		}
+		r.repo = repo
+	}
+	err = r.repo.FetchContext(ctx, &gogit.FetchOptions{
+		RefSpecs: refSpecs,
+		Auth:     auth,
+		Depth:    r.depth,
+		Tags:     gogit.AllTags,
+		Prune:    true,
+		Force:    true,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) && !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return fmt.Errorf("failed to fetch %s: %w", r.url, err)
+	}
+	return nil
+}
+
+// Snapshot is the outcome of discovering the packages in a repository.
+type Snapshot struct {
+	// Commit is the commit of the branch of the repository that was discovered.
+	Commit string
+
+	// Refs are the revisions of the packages, sorted by ref and package.
+	Refs []PackageRef
+}
+
+// Discover returns the revisions of the packages in the directory of the repository. The branch
+// holds the Published revisions -1 of the packages.
+//
+// Discovery proceeds incrementally from the previous discovery of the same branch and directory,
+// when there is one. Refs that have not moved are not read again, and the packages on the branch are only searched for again when
+// a Kptfile has been added or removed. The revisions -1 of the packages that have not changed
+// since the previous commit of the branch keep their previous commit, so that their contents
+// need not be read again.
+func (r *Repository) Discover(ctx context.Context, branch, directory string) (_ *Snapshot, err error) {
+	_, end := r.start(ctx, operationDiscover)
+	defer end(&err)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	directory = cleanDirectory(directory)
+	key := branch + "\x00" + directory
+	previous, found := r.snapshots[key]
+	if !found {
+		previous = &Snapshot{}
+	}
+	known := map[string]PackageRef{}
+	for _, ref := range previous.Refs {
+		known[ref.Ref+"\x00"+ref.Package] = ref
+	}
+
+	refs, err := r.repo.References()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		name := ref.Name()
+		commit, err := r.commitOf(ref)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+
+		if name == plumbing.NewBranchReferenceName(branch) {
+			snapshot.Commit = commit.String()
+			found, err := r.branchPackages(name, commit, directory, previous)
+			if err != nil {
+				return fmt.Errorf("failed to find packages on branch %s: %w", branch, err)
+			}
+			snapshot.Refs = append(snapshot.Refs, found...)
+			return nil
+		}
+
+		candidate, ok := parseRef(name)
+		if !ok {
+			return nil
+		}
+		candidate.Ref, candidate.Commit = name.String(), commit.String()
+		// Refs that do not hold a package, such as tags of other software, are not revisions.
+		if prior, found := known[candidate.Ref+"\x00"+candidate.Package]; !found || prior.Commit != candidate.Commit {
+			isPackage, err := r.hasKptfile(commit, path.Join(directory, candidate.Package))
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			if !isPackage {
+				return nil
+			}
+		}
+		snapshot.Refs = append(snapshot.Refs, candidate)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(snapshot.Refs, func(i, j int) bool {
+		if snapshot.Refs[i].Ref != snapshot.Refs[j].Ref {
+			return snapshot.Refs[i].Ref < snapshot.Refs[j].Ref
+		}
+		return snapshot.Refs[i].Package < snapshot.Refs[j].Package
+	})
+	r.snapshots[key] = snapshot
+	return snapshot, nil
+}
+
+// parseRef returns the revision a tag or a draft or proposed branch names, or false if it names
+// none.
+func parseRef(name plumbing.ReferenceName) (PackageRef, bool) {
+	switch {
+	case name.IsTag():
+		tag := name.Short()
+		i := strings.LastIndex(tag, "/v")
+		if i <= 0 {
+			return PackageRef{}, false
+		}
+		pkg, version := tag[:i], tag[i+len("/v"):]
+		revision, err := strconv.Atoi(version)
+		if err != nil || revision <= 0 || strconv.Itoa(revision) != version {
+			return PackageRef{}, false
+		}
+		return PackageRef{Package: pkg, Workspace: "v" + version, Revision: revision,
+			Lifecycle: cachev1alpha1.PackageRevisionLifecyclePublished}, true
+	case name.IsBranch():
+		for prefix, lifecycle := range map[string]cachev1alpha1.PackageRevisionLifecycle{
+			draftsPrefix:   cachev1alpha1.PackageRevisionLifecycleDraft,
+			proposedPrefix: cachev1alpha1.PackageRevisionLifecycleProposed,
+		} {
+			if rest, ok := strings.CutPrefix(name.Short(), prefix); ok {
+				pkg, workspace := path.Split(rest)
+				if pkg = strings.TrimSuffix(pkg, "/"); pkg != "" && workspace != "" {
+					return PackageRef{Package: pkg, Workspace: workspace, Lifecycle: lifecycle}, true
+				}
+			}
+		}
+	}
+	return PackageRef{}, false
+}
+
+// branchPackages returns the revisions -1 of the packages on the branch, proceeding from the
+// revisions found at the previous commit of the branch.
+func (r *Repository) branchPackages(name plumbing.ReferenceName, commit plumbing.Hash, directory string,
+	previous *Snapshot) ([]PackageRef, error) {
+	before := map[string]string{}
+	for _, ref := range previous.Refs {
+		if ref.Ref == name.String() {
+			before[ref.Package] = ref.Commit
+		}
+	}
+
+	// Without a previous commit to compare with, every package has changed.
+	var changed []string
+	rescan, unchanged := true, false
+	switch {
+	case previous.Commit == commit.String():
+		rescan, unchanged = false, true
+	case previous.Commit != "":
+		var err error
+		changed, err = r.changedPaths(plumbing.NewHash(previous.Commit), commit, directory)
+		if err == nil {
+			rescan = slices.ContainsFunc(changed, func(p string) bool { return path.Base(p) == kpt.KptfileName })
+		} else {
+			before = nil
+		}
+	}
+
+	packages := slices.Sorted(maps.Keys(before))
+	if rescan {
+		var err error
+		if packages, err = r.findPackages(commit, directory); err != nil {
+			return nil, err
+		}
+	}
+	var found []PackageRef
+	for _, pkg := range packages {
+		ref := PackageRef{Package: pkg, Workspace: name.Short(), Revision: -1,
+			Lifecycle: cachev1alpha1.PackageRevisionLifecyclePublished, Ref: name.String(), Commit: commit.String()}
+		priorCommit, known := before[pkg]
+		touched := slices.ContainsFunc(changed, func(p string) bool {
+			return p == pkg || strings.HasPrefix(p, pkg+"/")
+		})
+		if known && priorCommit != "" && (unchanged || (previous.Commit != "" && !touched)) {
+			ref.Commit = priorCommit
+		}
+		found = append(found, ref)
+	}
+	return found, nil
+}
+
+// changedPaths returns the paths, relative to the directory, of the files in the directory that
+// differ between two commits.
+func (r *Repository) changedPaths(from, to plumbing.Hash, directory string) ([]string, error) {
+	fromTree, err := r.treeAt(from, directory)
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := r.treeAt(to, directory)
+	if err != nil {
+		return nil, err
+	}
+	if fromTree == nil || toTree == nil {
+		return nil, fmt.Errorf("directory %q not found in both commits", directory)
+	}
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" {
+				changed = append(changed, name)
+			}
+		}
+	}
+	return changed, nil
+}
+
+// Contents returns the contents of the package in the directory of the repository at the commit.
+func (r *Repository) Contents(ctx context.Context, commit, directory, pkg string) (_ map[string]string, err error) {
+	_, end := r.start(ctx, operationContents)
+	defer end(&err)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tree, err := r.treeAt(plumbing.NewHash(commit), PackagePath(directory, pkg))
+	if err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		return nil, fmt.Errorf("package %s not found in commit %s", pkg, commit)
+	}
+	contents := map[string]string{}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		content, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		contents[file.Name] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package %s: %w", pkg, err)
+	}
+	return contents, nil
+}
+
+// commitOf returns the commit a ref points to, through an annotated tag.
+func (r *Repository) commitOf(ref *plumbing.Reference) (plumbing.Hash, error) {
+	tag, err := r.repo.TagObject(ref.Hash())
+	switch {
+	case errors.Is(err, plumbing.ErrObjectNotFound):
+		return ref.Hash(), nil
+	case err != nil:
+		return plumbing.ZeroHash, err
+	}
+	commit, err := tag.Commit()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return commit.Hash, nil
+}
+
+// findPackages returns the paths, relative to the directory, of the packages in the directory
+// of the commit.
+func (r *Repository) findPackages(commit plumbing.Hash, directory string) ([]string, error) {
+	tree, err := r.treeAt(commit, directory)
+	if err != nil || tree == nil {
+		return nil, err
+	}
+	var packages []string
+	var walk func(tree *object.Tree, dir string) error
+	walk = func(tree *object.Tree, dir string) error {
+		for _, entry := range tree.Entries {
+			if entry.Mode.IsFile() {
+				continue
+			}
+			subtree, err := r.repo.TreeObject(entry.Hash)
+			if err != nil {
+				return err
+			}
+			pkg := path.Join(dir, entry.Name)
+			if _, err := subtree.FindEntry(kpt.KptfileName); err == nil {
+				packages = append(packages, pkg)
+				continue
+			}
+			if err := walk(subtree, pkg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return packages, walk(tree, "")
+}
+
+// hasKptfile returns true if the directory of the commit holds a Kptfile.
+func (r *Repository) hasKptfile(commit plumbing.Hash, dir string) (bool, error) {
+	tree, err := r.treeAt(commit, dir)
+	if err != nil || tree == nil {
+		return false, err
+	}
+	_, err = tree.FindEntry(kpt.KptfileName)
+	return err == nil, nil
+}
+
+// treeAt returns the tree of the directory in the commit, or nil if there is no such directory.
+func (r *Repository) treeAt(commit plumbing.Hash, dir string) (*object.Tree, error) {
+	c, err := r.repo.CommitObject(commit)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" || dir == "." {
+		return tree, nil
+	}
+	tree, err = tree.Tree(dir)
+	if errors.Is(err, object.ErrDirectoryNotFound) || errors.Is(err, object.ErrEntryNotFound) {
+		return nil, nil
+	}
+	return tree, err
+}
+
+// cleanDirectory returns the package directory of a repository as a path relative to its root.
+func cleanDirectory(directory string) string {
+	directory = strings.Trim(path.Clean("/"+directory), "/")
+	return directory
+}
+
+// PackagePath returns the path of a package in the package directory of a repository, relative
+// to the root of the repository.
+func PackagePath(directory, pkg string) string {
+	return path.Join(cleanDirectory(directory), pkg)
+}
+
+// AuthFromSecret returns the credentials held in the data of a Secret: a username and password,
+// as in a kubernetes.io/basic-auth Secret, or a bearerToken.
+func AuthFromSecret(data map[string][]byte) (transport.AuthMethod, error) {
+	if token, found := data["bearerToken"]; found {
+		return &githttp.TokenAuth{Token: string(token)}, nil
+	}
+	username, hasUsername := data[corev1.BasicAuthUsernameKey]
+	password, hasPassword := data[corev1.BasicAuthPasswordKey]
+	if !hasUsername || !hasPassword {
+		return nil, fmt.Errorf("secret must hold either %s and %s, or bearerToken",
+			corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey)
+	}
+	return &githttp.BasicAuth{Username: string(username), Password: string(password)}, nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Git", func() {
+	ctx := context.Background()
+	signature := object.Signature{Name: "porch", Email: "porch@example.com", When: time.Unix(1700000000, 0)}
+	kptfile := "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: web\n"
+
+	var (
+		dir    string
+		remote *gogit.Repository
+	)
+
+	// commit writes the files, relative to the root of the remote repository, and commits them
+	// to the branch that is checked out.
+	commit := func(files map[string]string) plumbing.Hash {
+		worktree, err := remote.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		for name, content := range files {
+			full := filepath.Join(dir, name)
+			Expect(os.MkdirAll(filepath.Dir(full), 0o755)).To(Succeed())
+			Expect(os.WriteFile(full, []byte(content), 0o644)).To(Succeed())
+		}
+		Expect(worktree.AddGlob(".")).To(Succeed())
+		hash, err := worktree.Commit("update packages", &gogit.CommitOptions{Author: &signature})
+		Expect(err).NotTo(HaveOccurred())
+		return hash
+	}
+
+	// hasObject returns true if the object store of the mirror holds the object.
+	hasObject := func(mirror *Repository, hash plumbing.Hash) bool {
+		return mirror.repo.Storer.HasEncodedObject(hash) == nil
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		var err error
+		remote, err = gogit.PlainInitWithOptions(dir, &gogit.PlainInitOptions{
+			InitOptions: gogit.InitOptions{DefaultBranch: plumbing.Main},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should keep one shallow object store per remote on disk", func() {
+		first := commit(map[string]string{"packages/web/Kptfile": kptfile, "assets/logo.svg": "<svg/>"})
+		second := commit(map[string]string{"packages/web/service.yaml": "kind: Service\n"})
+
+		stores := GinkgoT().TempDir()
+		repositories := &Repositories{Dir: stores, Depth: 1}
+		mirror, err := repositories.Fetch(ctx, dir, nil)
+		Expect(err).NotTo(HaveOccurred())
+		again, err := repositories.Fetch(ctx, dir, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(BeIdenticalTo(mirror))
+
+		entries, err := os.ReadDir(stores)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(hasObject(mirror, second)).To(BeTrue())
+		Expect(hasObject(mirror, first)).To(BeFalse())
+
+		By("reading packages from the store after a restart")
+		mirror, err = (&Repositories{Dir: stores, Depth: 1}).Fetch(ctx, dir, nil)
+		Expect(err).NotTo(HaveOccurred())
+		snapshot, err := mirror.Discover(ctx, "", "packages")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot.Commit).To(Equal(second.String()))
+		Expect(snapshot.Refs).To(HaveLen(1))
+		contents, err := mirror.Contents(ctx, second.String(), "packages", "web")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contents).To(HaveKey("service.yaml"))
+		Expect(contents).NotTo(HaveKey("logo.svg"))
+	})
+
+	It("should garbage collect the objects the refs no longer need", func() {
+		commit(map[string]string{"packages/web/Kptfile": kptfile})
+		_, err := remote.CreateTag("web/v1", commit(map[string]string{"packages/web/old.yaml": "kind: Old\n"}), nil)
+		Expect(err).NotTo(HaveOccurred())
+		tip := commit(map[string]string{"packages/web/new.yaml": "kind: New\n"})
+
+		for _, stores := range []string{"", GinkgoT().TempDir()} {
+			repositories := &Repositories{Dir: stores}
+			mirror, err := repositories.Fetch(ctx, dir, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = mirror.Discover(ctx, "", "packages")
+			Expect(err).NotTo(HaveOccurred())
+			tag, err := remote.Tag("web/v1")
+			Expect(err).NotTo(HaveOccurred())
+			old, err := remote.CommitObject(tag.Hash())
+			Expect(err).NotTo(HaveOccurred())
+			first := old.ParentHashes[0]
+			Expect(hasObject(mirror, first)).To(BeTrue())
+
+			Expect(repositories.GC(ctx)).To(Succeed())
+			Expect(hasObject(mirror, first)).To(BeFalse())
+			Expect(hasObject(mirror, old.Hash)).To(BeTrue())
+			Expect(hasObject(mirror, tip)).To(BeTrue())
+			contents, err := mirror.Contents(ctx, old.Hash.String(), "packages", "web")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(HaveKey("old.yaml"))
+
+			By("fetching into the garbage collected store")
+			again, err := repositories.Fetch(ctx, dir, nil)
+			Expect(err).NotTo(HaveOccurred())
+			snapshot, err := again.Discover(ctx, "", "packages")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshot.Commit).To(Equal(tip.String()))
+		}
+	})
+})
@@ -0,0 +1,72 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/liamfallon/porch-operator/internal/tracing"
+)
+
+// repositoryType is the type of repository the operations are recorded for, so that the metrics
+// are shared with the other types of repositories.
+const repositoryType = "git"
+
+const (
+	operationFetch    = "fetch"
+	operationDiscover = "discover"
+	operationContents = "contents"
+	operationGC       = "gc"
+)
+
+var (
+	operationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "porch_repository_operation_duration_seconds",
+		Help:    "Duration of the operations on repositories, by type of repository and operation.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 4, 9),
+	}, []string{"type", "operation"})
+	operationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "porch_repository_operation_errors_total",
+		Help: "Number of operations on repositories that failed, by type of repository and operation.",
+	}, []string{"type", "operation"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(operationDuration, operationErrors)
+}
+
+// start starts a span for an operation on the mirror, and returns a function that ends it and
+// records the duration of the operation, and whether it failed. The function is deferred with a
+// pointer to the error the operation returns.
+func (r *Repository) start(ctx context.Context, operation string) (context.Context, func(*error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "git "+operation)
+	if span.IsRecording() {
+		span.SetAttributes(tracing.GitURLKey.String(r.url))
+	}
+	return ctx, func(err *error) {
+		operationDuration.WithLabelValues(repositoryType, operation).Observe(time.Since(start).Seconds())
+		if *err != nil {
+			operationErrors.WithLabelValues(repositoryType, operation).Inc()
+		}
+		tracing.End(span, *err)
+	}
+}
@@ -0,0 +1,360 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// packWindow is the number of objects the packfile encoder searches for deltas when a store is
+// repacked. Objects are packed without deltas: stores only hold few versions of each file, so
+// searching for deltas takes much longer than the space it saves.
+const packWindow = 0
+
+// Repositories holds the mirrors of the remote repositories, one per remote, so that they are
+// fetched incrementally rather than cloned on every use.
+type Repositories struct {
+	// Dir is the directory holding the object stores of the mirrors, a bare repository per
+	// remote, so that they survive restarts. If empty, the object stores are kept in memory.
+	Dir string
+
+	// Depth is the number of commits fetched from the tips of the refs. If 0, the whole history
+	// of the refs is fetched. The trees of the fetched commits are fetched whole, as partial
+	// clones are not supported, but only their package directories are read.
+	Depth int
+
+	// GCInterval is how often the object stores are garbage collected once the Repositories are
+	// started. If 0, they are not garbage collected.
+	GCInterval time.Duration
+
+	mu    sync.Mutex
+	repos map[string]*Repository
+}
+
+// Fetch returns the up to date mirror of the remote repository at the URL.
+func (s *Repositories) Fetch(ctx context.Context, url string, auth transport.AuthMethod) (*Repository, error) {
+	s.mu.Lock()
+	repo, found := s.repos[url]
+	if !found {
+		repo = &Repository{url: url, depth: s.Depth, snapshots: map[string]*Snapshot{}}
+		if s.Dir != "" {
+			repo.dir = filepath.Join(s.Dir, storeName(url))
+		}
+		if s.repos == nil {
+			s.repos = map[string]*Repository{}
+		}
+		s.repos[url] = repo
+	}
+	s.mu.Unlock()
+
+	if err := repo.Fetch(ctx, auth); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// Forget discards the mirror of the remote repository at the URL. Its object store is kept on
+// disk, and picked up again if the remote is fetched again.
+func (s *Repositories) Forget(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.repos, url)
+}
+
+// GC garbage collects the object stores of the mirrors.
+func (s *Repositories) GC(ctx context.Context) error {
+	s.mu.Lock()
+	repos := slices.Collect(maps.Values(s.repos))
+	s.mu.Unlock()
+
+	var errs []error
+	for _, repo := range repos {
+		if err := repo.GC(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Start garbage collects the object stores of the mirrors on the GC interval, until the context
+// is done.
+func (s *Repositories) Start(ctx context.Context) error {
+	if s.GCInterval <= 0 {
+		<-ctx.Done()
+		return nil
+	}
+	ticker := time.NewTicker(s.GCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.GC(ctx); err != nil {
+				logf.FromContext(ctx).Error(err, "Failed to garbage collect git object stores")
+			}
+		}
+	}
+}
+
+// storeName returns the name of the directory of the object store of the remote repository at
+// the URL.
+func storeName(url string) string {
+	digest := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(digest[:]) + ".git"
+}
+
+// open opens the object store of the mirror, creating it if it does not exist.
+func (r *Repository) open() (*gogit.Repository, error) {
+	var repo *gogit.Repository
+	var err error
+	if r.dir == "" {
+		repo, err = gogit.Init(memory.NewStorage(), nil)
+	} else {
+		// A garbage collection that was interrupted leaves the store it was replacing behind.
+		_ = os.RemoveAll(r.dir + ".gc")
+		if _, err := os.Stat(r.dir); os.IsNotExist(err) {
+			_ = os.Rename(r.dir+".old", r.dir)
+		}
+		repo, err = gogit.PlainOpen(r.dir)
+		if errors.Is(err, gogit.ErrRepositoryNotExists) {
+			repo, err = gogit.PlainInit(r.dir, true)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = repo.Remote(gogit.DefaultRemoteName)
+	if errors.Is(err, gogit.ErrRemoteNotFound) {
+		_, err = repo.CreateRemote(&config.RemoteConfig{Name: gogit.DefaultRemoteName, URLs: []string{r.url}, Fetch: refSpecs})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// GC garbage collects the object store of the mirror. Only the objects needed to read the
+// packages at the tips of the refs, and at the commits of the last discoveries, are kept; the
+// history behind them is dropped, and the commits they are kept in become shallow. The kept
+// objects are written to a new store, in a single pack when the store is on disk, that then
+// replaces the old store.
+func (r *Repository) GC(ctx context.Context) (err error) {
+	_, end := r.start(ctx, operationGC)
+	defer end(&err)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.repo == nil {
+		return nil
+	}
+	src := r.repo.Storer
+
+	var roots []plumbing.Hash
+	refs, err := src.IterReferences()
+	if err != nil {
+		return err
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			roots = append(roots, ref.Hash())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range r.snapshots {
+		if snapshot.Commit != "" {
+			roots = append(roots, plumbing.NewHash(snapshot.Commit))
+		}
+		for _, ref := range snapshot.Refs {
+			roots = append(roots, plumbing.NewHash(ref.Commit))
+		}
+	}
+
+	keep := map[plumbing.Hash]bool{}
+	var commits []*object.Commit
+	for _, root := range roots {
+		if err := markReachable(src, root, keep, &commits); err != nil {
+			return fmt.Errorf("failed to garbage collect %s: %w", r.url, err)
+		}
+	}
+	var shallow []plumbing.Hash
+	for _, commit := range commits {
+		if slices.ContainsFunc(commit.ParentHashes, func(parent plumbing.Hash) bool { return !keep[parent] }) {
+			shallow = append(shallow, commit.Hash)
+		}
+	}
+
+	if r.dir == "" {
+		dst := memory.NewStorage()
+		if err := copyStore(src, dst, keep, shallow); err != nil {
+			return fmt.Errorf("failed to garbage collect %s: %w", r.url, err)
+		}
+		r.repo, err = gogit.Open(dst, nil)
+		return err
+	}
+
+	temp := r.dir + ".gc"
+	if err := os.RemoveAll(temp); err != nil {
+		return err
+	}
+	dst := filesystem.NewStorage(osfs.New(temp), cache.NewObjectLRUDefault())
+	err = copyStore(src, dst, keep, shallow)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.RemoveAll(temp)
+		return fmt.Errorf("failed to garbage collect %s: %w", r.url, err)
+	}
+	if closer, ok := src.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	if err := os.Rename(r.dir, r.dir+".old"); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, r.dir); err != nil {
+		_ = os.Rename(r.dir+".old", r.dir)
+		return err
+	}
+	if err := os.RemoveAll(r.dir + ".old"); err != nil {
+		return err
+	}
+	r.repo, err = gogit.PlainOpen(r.dir)
+	return err
+}
+
+// markReachable marks the objects needed to read the object with the hash, without following the
+// parents of commits, and collects the commits.
+func markReachable(s storer.EncodedObjectStorer, hash plumbing.Hash, keep map[plumbing.Hash]bool, commits *[]*object.Commit) error {
+	if keep[hash] {
+		return nil
+	}
+	obj, err := s.EncodedObject(plumbing.AnyObject, hash)
+	if err != nil {
+		return fmt.Errorf("failed to read object %s: %w", hash, err)
+	}
+	keep[hash] = true
+
+	switch obj.Type() {
+	case plumbing.TagObject:
+		tag, err := object.DecodeTag(s, obj)
+		if err != nil {
+			return err
+		}
+		return markReachable(s, tag.Target, keep, commits)
+	case plumbing.CommitObject:
+		commit, err := object.DecodeCommit(s, obj)
+		if err != nil {
+			return err
+		}
+		*commits = append(*commits, commit)
+		return markReachable(s, commit.TreeHash, keep, commits)
+	case plumbing.TreeObject:
+		tree, err := object.DecodeTree(s, obj)
+		if err != nil {
+			return err
+		}
+		for _, entry := range tree.Entries {
+			switch entry.Mode {
+			case filemode.Dir:
+				if err := markReachable(s, entry.Hash, keep, commits); err != nil {
+					return err
+				}
+			case filemode.Submodule:
+				// The commit of a submodule is in another repository.
+			default:
+				keep[entry.Hash] = true
+			}
+		}
+	}
+	return nil
+}
+
+// copyStore copies the configuration, refs and kept objects of an object store to an empty one,
+// and records the shallow commits.
+func copyStore(src, dst storage.Storer, keep map[plumbing.Hash]bool, shallow []plumbing.Hash) error {
+	cfg, err := src.Config()
+	if err != nil {
+		return err
+	}
+	if err := dst.SetConfig(cfg); err != nil {
+		return err
+	}
+
+	hashes := slices.Collect(maps.Keys(keep))
+	if writer, ok := dst.(storer.PackfileWriter); ok {
+		w, err := writer.PackfileWriter()
+		if err != nil {
+			return err
+		}
+		_, err = packfile.NewEncoder(w, src, false).Encode(hashes, packWindow)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, hash := range hashes {
+			obj, err := src.EncodedObject(plumbing.AnyObject, hash)
+			if err != nil {
+				return err
+			}
+			if _, err := dst.SetEncodedObject(obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	refs, err := src.IterReferences()
+	if err != nil {
+		return err
+	}
+	if err := refs.ForEach(dst.SetReference); err != nil {
+		return err
+	}
+	return dst.SetShallow(shallow)
+}
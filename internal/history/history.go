@@ -0,0 +1,125 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package history keeps the durable history of what is done to PackageRevisions, and by whom, in
+// PackageRevisionHistory objects.
+//
+// The API server only tells admission webhooks who makes a request, so the porch admission
+// webhook records the user who last modified a PackageRevision, or its PackageRevisionResources,
+// in the ActorAnnotation of the object. The entries of the history take their actor from it.
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+const (
+	// ActorAnnotation records the user who last created or updated an object.
+	ActorAnnotation = "porch.kpt.dev/last-modified-by"
+
+	// RepositoryLabel and PackageLabel label the histories with the repository and the package of
+	// their PackageRevisions, so that the history of a package is listed by selecting on them.
+	// RepositoryLabel is the label the Repository controller puts on discovered PackageRevisions.
+	RepositoryLabel = "porch.kpt.dev/repository"
+	PackageLabel    = "porch.kpt.dev/package"
+
+	// commitAnnotation is the annotation the Repository controller records the commit of a
+	// discovered PackageRevision in.
+	commitAnnotation = "porch.kpt.dev/commit"
+)
+
+// MaxEntries is the number of entries a history holds at most, as bounded by the schema of
+// PackageRevisionHistories.
+const MaxEntries = 512
+
+// ErrFull is returned when an entry is not appended to a history because the history is full.
+var ErrFull = errors.New("the history is full")
+
+// Actor returns the user who last created or updated the object, or an empty string if it is not
+// known.
+func Actor(obj metav1.Object) string {
+	return obj.GetAnnotations()[ActorAnnotation]
+}
+
+// NewEntry returns an entry of the history of the PackageRevision, for the action done by the
+// actor at the time, that took the PackageRevision from the lifecycle to its current one.
+func NewEntry(pr *cachev1alpha1.PackageRevision, action cachev1alpha1.PackageRevisionAction, actor string,
+	from cachev1alpha1.PackageRevisionLifecycle, now metav1.Time) cachev1alpha1.PackageRevisionHistoryEntry {
+	return cachev1alpha1.PackageRevisionHistoryEntry{
+		Action:        action,
+		Actor:         actor,
+		Timestamp:     now,
+		FromLifecycle: from,
+		ToLifecycle:   pr.Spec.Lifecycle,
+		Commit:        pr.Annotations[commitAnnotation],
+	}
+}
+
+// Append appends the entry to the history of the PackageRevision, and creates the history if it
+// does not exist yet. It returns ErrFull, without appending the entry, if the history is full. Histories are not meant to be cached, so that they are not all held in
+// memory: the client should read them from the API server.
+func Append(ctx context.Context, c client.Client, pr *cachev1alpha1.PackageRevision,
+	entry cachev1alpha1.PackageRevisionHistoryEntry) error {
+	retriable := func(err error) bool { return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) }
+	return retry.OnError(retry.DefaultBackoff, retriable, func() error {
+		history := &cachev1alpha1.PackageRevisionHistory{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, history)
+		if apierrors.IsNotFound(err) {
+			history = &cachev1alpha1.PackageRevisionHistory{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      pr.Name,
+					Namespace: pr.Namespace,
+					Labels:    map[string]string{RepositoryLabel: pr.Spec.RepositoryName, PackageLabel: pr.Spec.PackageName},
+				},
+				Spec: cachev1alpha1.PackageRevisionHistorySpec{
+					PackageName:    pr.Spec.PackageName,
+					RepositoryName: pr.Spec.RepositoryName,
+					WorkspaceName:  pr.Spec.WorkspaceName,
+					Entries:        []cachev1alpha1.PackageRevisionHistoryEntry{entry},
+				},
+			}
+			return c.Create(ctx, history)
+		}
+		if err != nil {
+			return err
+		}
+		if len(history.Spec.Entries) >= MaxEntries {
+			return fmt.Errorf("%w: it holds %d entries", ErrFull, len(history.Spec.Entries))
+		}
+		history.Spec.Entries = append(history.Spec.Entries, entry)
+		return c.Update(ctx, history)
+	})
+}
+
+// Last returns the last entry of the history of the PackageRevision, or nil if it has none.
+func Last(ctx context.Context, c client.Reader, pr *cachev1alpha1.PackageRevision) (*cachev1alpha1.PackageRevisionHistoryEntry, error) {
+	history := &cachev1alpha1.PackageRevisionHistory{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, history)
+	if err != nil || len(history.Spec.Entries) == 0 {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return &history.Spec.Entries[len(history.Spec.Entries)-1], nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package injection injects the data of in-cluster objects into the resources of a package.
+//
+// A resource in a package becomes an injection point when it carries the ConfigInjectionAnnotation.
+// Injecting an in-cluster object into it replaces everything except its apiVersion, kind and
+// metadata with the corresponding fields of the object, for example the data of a ConfigMap.
+package injection
+
+import (
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+const (
+	// ConfigInjectionAnnotation marks a resource in a package as an injection point.
+	// Its value is either "required" or "optional".
+	ConfigInjectionAnnotation = "kpt.dev/config-injection"
+
+	// InjectedResourceAnnotation records the name of the in-cluster object injected into a resource.
+	InjectedResourceAnnotation = "kpt.dev/injected-resource-name"
+
+	// Required marks an injection point that must be injected for the package to be complete.
+	Required = "required"
+
+	// Optional marks an injection point that may be left as it is in the package.
+	Optional = "optional"
+)
+
+// preservedFields are the fields of an injection point that are not replaced by injection.
+var preservedFields = []string{"apiVersion", "kind", "metadata"}
+
+// Point is a resource in a package that is marked for injection.
+type Point struct {
+	// Node is the resource in the package.
+	Node *yaml.RNode
+
+	// File is the path of the file containing the resource.
+	File string
+
+	// GroupVersionKind of the resource.
+	GroupVersionKind schema.GroupVersionKind
+
+	// Name of the resource.
+	Name string
+
+	// Required is true if the injection point must be injected.
+	Required bool
+}
+
+// FindPoints returns the injection points among the resources of a package.
+func FindPoints(nodes []*yaml.RNode) ([]*Point, error) {
+	var points []*Point
+	for _, node := range nodes {
+		value, found := node.GetAnnotations()[ConfigInjectionAnnotation]
+		if !found {
+			continue
+		}
+		if value != Required && value != Optional {
+			return nil, fmt.Errorf("%s %q in %s: annotation %s must be %q or %q, not %q",
+				node.GetKind(), node.GetName(), kpt.PathOf(node), ConfigInjectionAnnotation, Required, Optional, value)
+		}
+		gv, err := schema.ParseGroupVersion(node.GetApiVersion())
+		if err != nil {
+			return nil, fmt.Errorf("%s %q in %s: %w", node.GetKind(), node.GetName(), kpt.PathOf(node), err)
+		}
+		points = append(points, &Point{
+			Node:             node,
+			File:             kpt.PathOf(node),
+			GroupVersionKind: gv.WithKind(node.GetKind()),
+			Name:             node.GetName(),
+			Required:         value == Required,
+		})
+	}
+	return points, nil
+}
+
+// Inject replaces the data of the injection point with that of the source object, and reports
+// whether the injection point changed as a result.
+func (p *Point) Inject(source *unstructured.Unstructured) (bool, error) {
+	before, err := p.Node.String()
+	if err != nil {
+		return false, err
+	}
+
+	fields, err := p.Node.Fields()
+	if err != nil {
+		return false, err
+	}
+	for _, field := range fields {
+		if slices.Contains(preservedFields, field) {
+			continue
+		}
+		if _, err := p.Node.Pipe(yaml.Clear(field)); err != nil {
+			return false, err
+		}
+	}
+
+	var keys []string
+	for key := range source.Object {
+		if !slices.Contains(preservedFields, key) && key != "status" {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		wrapped, err := yaml.FromMap(map[string]any{key: source.Object[key]})
+		if err != nil {
+			return false, fmt.Errorf("failed to convert field %q of %s %q: %w", key, source.GetKind(), source.GetName(), err)
+		}
+		if err := p.Node.PipeE(yaml.SetField(key, wrapped.Field(key).Value)); err != nil {
+			return false, err
+		}
+	}
+
+	annotations := p.Node.GetAnnotations()
+	annotations[InjectedResourceAnnotation] = source.GetName()
+	if err := p.Node.SetAnnotations(annotations); err != nil {
+		return false, err
+	}
+
+	after, err := p.Node.String()
+	if err != nil {
+		return false, err
+	}
+	return before != after, nil
+}
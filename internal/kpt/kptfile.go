@@ -0,0 +1,152 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kpt
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	// KptfileName is the name of the kpt package metadata file.
+	KptfileName = "Kptfile"
+
+	// KptfileAPIVersion is the apiVersion of the Kptfile resource.
+	KptfileAPIVersion = "kpt.dev/v1"
+
+	// KptfileKind is the kind of the Kptfile resource.
+	KptfileKind = "Kptfile"
+
+	// LocalConfigAnnotation marks resources that configure the package rather than being deployed.
+	LocalConfigAnnotation = "config.kubernetes.io/local-config"
+)
+
+// Kptfile is the subset of the kpt package metadata file that the operator uses.
+type Kptfile struct {
+	yaml.ResourceMeta `json:",inline" yaml:",inline"`
+
+	// Info contains metadata such as license, documentation, etc.
+	Info *PackageInfo `json:"info,omitempty" yaml:"info,omitempty"`
+
+	// Pipeline declares the functions that render the package.
+	Pipeline *Pipeline `json:"pipeline,omitempty" yaml:"pipeline,omitempty"`
+}
+
+// PackageInfo contains optional information about the package.
+type PackageInfo struct {
+	// Site is a link to page with information about the package.
+	Site string `json:"site,omitempty" yaml:"site,omitempty"`
+
+	// Description is a short description of the package.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// Keywords is a list of keywords describing the package.
+	Keywords []string `json:"keywords,omitempty" yaml:"keywords,omitempty"`
+}
+
+// Pipeline declares the functions that render a package. Mutators are run first, in order,
+// followed by the validators.
+type Pipeline struct {
+	// Mutators are functions that may change the package resources.
+	Mutators []Function `json:"mutators,omitempty" yaml:"mutators,omitempty"`
+
+	// Validators are functions that check the package resources without changing them.
+	Validators []Function `json:"validators,omitempty" yaml:"validators,omitempty"`
+}
+
+// Function declares a KRM function in a pipeline.
+type Function struct {
+	// Image identifies the function, for example gcr.io/kpt-fn/set-labels:v0.2.0.
+	Image string `json:"image,omitempty" yaml:"image,omitempty"`
+
+	// Name is an optional name for the function within the pipeline.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// ConfigPath is the path, relative to the Kptfile, of the file holding the function config.
+	ConfigPath string `json:"configPath,omitempty" yaml:"configPath,omitempty"`
+
+	// ConfigMap is an inline function config, passed to the function as the data of a ConfigMap.
+	ConfigMap map[string]string `json:"configMap,omitempty" yaml:"configMap,omitempty"`
+
+	// Selectors select the resources the function is run on. A resource is selected if it
+	// matches any of the selectors. All resources are selected if there are no selectors.
+	Selectors []Selector `json:"selectors,omitempty" yaml:"selectors,omitempty"`
+
+	// Exclusions exclude resources that match any of them from the function.
+	Exclusions []Selector `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// Selector matches package resources. A resource matches if it matches all of the fields set.
+type Selector struct {
+	APIVersion  string            `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Kind        string            `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Name        string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Namespace   string            `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// Matches returns true if the resource matches the selector.
+func (s *Selector) Matches(node *yaml.RNode) bool {
+	if s.APIVersion != "" && s.APIVersion != node.GetApiVersion() ||
+		s.Kind != "" && s.Kind != node.GetKind() ||
+		s.Name != "" && s.Name != node.GetName() ||
+		s.Namespace != "" && s.Namespace != node.GetNamespace() {
+		return false
+	}
+	labels, annotations := node.GetLabels(), node.GetAnnotations()
+	for key, value := range s.Labels {
+		if labels[key] != value {
+			return false
+		}
+	}
+	for key, value := range s.Annotations {
+		if annotations[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseKptfile decodes a Kptfile resource.
+func ParseKptfile(node *yaml.RNode) (*Kptfile, error) {
+	kptfile := &Kptfile{}
+	if err := node.Document().Decode(kptfile); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", PathOf(node), err)
+	}
+	return kptfile, nil
+}
+
+// NewKptfile returns the content of a new Kptfile for a package with the given name.
+func NewKptfile(name string, info *PackageInfo) (string, error) {
+	kptfile := Kptfile{
+		ResourceMeta: yaml.ResourceMeta{
+			TypeMeta: yaml.TypeMeta{APIVersion: KptfileAPIVersion, Kind: KptfileKind},
+			ObjectMeta: yaml.ObjectMeta{
+				NameMeta:    yaml.NameMeta{Name: name},
+				Annotations: map[string]string{LocalConfigAnnotation: "true"},
+			},
+		},
+		Info: info,
+	}
+	out, err := yaml.Marshal(kptfile)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kpt reads and writes the contents of kpt packages.
+//
+// Package contents are held as a map of file path, relative to the package root, to file content.
+// The KRM resources in a package are parsed into kyaml nodes, annotated with the path of the file
+// they came from, so that they can be transformed and written back to the same files.
+package kpt
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// packageDir is the directory the package is laid out in on the in-memory file system.
+const packageDir = "/package"
+
+// ReadResources parses the KRM resources, including the Kptfile, in the package contents.
+func ReadResources(contents map[string]string) ([]*yaml.RNode, error) {
+	rw, _, err := newReadWriter(contents)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := rw.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package resources: %w", err)
+	}
+	return nodes, nil
+}
+
+// WriteResources returns a copy of the package contents with the KRM resources replaced by nodes.
+// Files that do not hold KRM resources are left unchanged, and files whose resources have all
+// been removed are deleted. The nodes must carry the path annotations set by ReadResources, or
+// a path annotation naming the file they should be written to.
+func WriteResources(contents map[string]string, nodes []*yaml.RNode) (map[string]string, error) {
+	rw, fs, err := newReadWriter(contents)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rw.Read(); err != nil {
+		return nil, fmt.Errorf("failed to read package resources: %w", err)
+	}
+
+	// The writer strips annotations from the nodes it writes, so write copies.
+	copies := make([]*yaml.RNode, 0, len(nodes))
+	for _, node := range nodes {
+		copies = append(copies, node.Copy())
+	}
+	if err := rw.Write(copies); err != nil {
+		return nil, fmt.Errorf("failed to write package resources: %w", err)
+	}
+
+	written := map[string]string{}
+	err = fs.Walk(packageDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(packageDir, path)
+		if err != nil {
+			return err
+		}
+		written[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect package contents: %w", err)
+	}
+	return written, nil
+}
+
+// PathOf returns the path of the file a resource was read from.
+func PathOf(node *yaml.RNode) string {
+	path, _, _ := kioutil.GetFileAnnotations(node)
+	return path
+}
+
+// IDOf returns the identity of a resource within package contents: its group, kind, namespace
+// and name, and the directory of its file. Resources keep their identity when they are moved
+// between the files of a directory.
+func IDOf(node *yaml.RNode) string {
+	group := ""
+	if g, _, found := strings.Cut(node.GetApiVersion(), "/"); found {
+		group = g
+	}
+	return strings.Join([]string{group, node.GetKind(), node.GetNamespace(), node.GetName(), path.Dir(PathOf(node))}, "\x00")
+}
+
+// WithoutFileAnnotations returns a copy of a resource without the annotations that record the
+// file it was read from, for comparing resources by their content.
+func WithoutFileAnnotations(node *yaml.RNode) (*yaml.RNode, error) {
+	node = node.Copy()
+	annotations := node.GetAnnotations()
+	maps.DeleteFunc(annotations, func(key, _ string) bool {
+		return strings.HasPrefix(key, "internal.config.kubernetes.io/") ||
+			key == kioutil.LegacyPathAnnotation || key == kioutil.LegacyIndexAnnotation || key == kioutil.LegacyIdAnnotation
+	})
+	if err := node.SetAnnotations(annotations); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// FindKptfile returns the root Kptfile among the package resources, or nil if there is none.
+func FindKptfile(nodes []*yaml.RNode) *yaml.RNode {
+	for _, node := range nodes {
+		if node.GetKind() == KptfileKind && PathOf(node) == KptfileName {
+			return node
+		}
+	}
+	return nil
+}
+
+// newReadWriter lays the package contents out on an in-memory file system and returns a
+// kio read/writer for it.
+func newReadWriter(contents map[string]string) (*kio.LocalPackageReadWriter, filesys.FileSystem, error) {
+	fs := filesys.MakeFsInMemory()
+	if err := fs.MkdirAll(packageDir); err != nil {
+		return nil, nil, err
+	}
+	for path, content := range contents {
+		full := filepath.Join(packageDir, filepath.FromSlash(path))
+		if err := fs.MkdirAll(filepath.Dir(full)); err != nil {
+			return nil, nil, err
+		}
+		if err := fs.WriteFile(full, []byte(content)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	rw := &kio.LocalPackageReadWriter{
+		PackagePath:        packageDir,
+		PackageFileName:    KptfileName,
+		MatchFilesGlob:     append([]string{KptfileName}, kio.DefaultMatch...),
+		IncludeSubpackages: true,
+		PreserveSeqIndent:  true,
+		WrapBareSeqNode:    true,
+		FileSystem:         filesys.FileSystemOrOnDisk{FileSystem: fs},
+	}
+	return rw, fs, nil
+}
@@ -0,0 +1,451 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package merge merges the changes made to an upstream package into a local package derived
+// from it.
+//
+// Changes that cannot be merged are left in the merged package for a user to resolve. A resource
+// with conflicting changes keeps its local values, and lists the conflicts in its
+// ConflictsAnnotation; the conflicts are resolved by settling the values and removing the
+// annotation. A file holding no resources with conflicting changes holds both versions between
+// conflict markers, as git writes them, which are resolved by editing the file.
+package merge
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/diff"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+const (
+	// ConflictsAnnotation lists the unresolved merge conflicts of a resource, as a JSON array.
+	ConflictsAnnotation = "porch.kpt.dev/merge-conflicts"
+
+	// Conflict markers delimit the versions of a file with conflicting changes.
+	localMarker    = "<<<<<<< local"
+	baseMarker     = "||||||| base"
+	upstreamMarker = "======="
+	endMarker      = ">>>>>>> upstream"
+)
+
+// conflict is a merge conflict recorded in the ConflictsAnnotation of a resource.
+type conflict struct {
+	Path        string `json:"path,omitempty"`
+	Base        string `json:"base,omitempty"`
+	Local       string `json:"local,omitempty"`
+	Upstream    string `json:"upstream,omitempty"`
+	Description string `json:"description"`
+}
+
+// resource is a resource in a version of package contents, without its file annotations.
+type resource struct {
+	// node is the resource without its file annotations.
+	node *yaml.RNode
+
+	// original is the resource as it was read.
+	original *yaml.RNode
+}
+
+// ThreeWay merges the changes made from the base contents to the upstream contents into the
+// local contents, and returns the merged contents with the number of conflicts left in them.
+//
+// Resources are merged field by field. Fields changed only upstream take their upstream values,
+// and fields changed only locally keep their local values. Lists whose elements all have a name
+// are merged element by element, and other lists as a whole. Files holding no resources are
+// merged as a whole.
+func ThreeWay(base, local, upstream map[string]string) (map[string]string, int, error) {
+	baseResources, err := readResources(base)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read base resources: %w", err)
+	}
+	localResources, err := readResources(local)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read local resources: %w", err)
+	}
+	upstreamResources, err := readResources(upstream)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read upstream resources: %w", err)
+	}
+
+	ids := slices.Sorted(maps.Keys(localResources))
+	for id := range upstreamResources {
+		if _, found := localResources[id]; !found {
+			ids = append(ids, id)
+		}
+	}
+
+	conflicts := 0
+	var merged []*yaml.RNode
+	for _, id := range ids {
+		b, l, u := baseResources[id], localResources[id], upstreamResources[id]
+		var result *resource
+		var resourceConflicts []conflict
+		switch {
+		case l != nil && u != nil:
+			var baseNode *yaml.Node
+			if b != nil {
+				baseNode = b.node.YNode()
+			}
+			node, fieldConflicts := mergeValue("", baseNode, l.node.YNode(), u.node.YNode())
+			result = &resource{node: yaml.NewRNode(node), original: l.original}
+			resourceConflicts = fieldConflicts
+		case l != nil && b == nil:
+			// Added locally.
+			result = l
+		case l != nil && !diff.EqualNodes(b.node.YNode(), l.node.YNode()):
+			result = l
+			resourceConflicts = []conflict{{Description: "removed upstream but modified locally"}}
+		case u != nil && b == nil:
+			// Added upstream.
+			result = u
+		case u != nil && !diff.EqualNodes(b.node.YNode(), u.node.YNode()):
+			result = u
+			resourceConflicts = []conflict{{Description: "removed locally but modified upstream"}}
+		}
+		if result == nil {
+			continue
+		}
+
+		node, err := withFileAnnotations(result)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(resourceConflicts) > 0 {
+			conflicts += len(resourceConflicts)
+			encoded, err := json.Marshal(resourceConflicts)
+			if err != nil {
+				return nil, 0, err
+			}
+			if err := node.PipeE(yaml.SetAnnotation(ConflictsAnnotation, string(encoded))); err != nil {
+				return nil, 0, err
+			}
+		}
+		merged = append(merged, node)
+	}
+
+	// The files holding no resources are merged as a whole.
+	files := map[string]bool{}
+	for _, contents := range []map[string]string{base, local, upstream} {
+		for file := range contents {
+			files[file] = true
+		}
+	}
+	resourceFiles, err := filesWithResources(base, local, upstream)
+	if err != nil {
+		return nil, 0, err
+	}
+	otherFiles := map[string]string{}
+	for file := range files {
+		if resourceFiles[file] {
+			continue
+		}
+		content, fileConflict := mergeFile(base, local, upstream, file)
+		if fileConflict {
+			conflicts++
+		}
+		if content != nil {
+			otherFiles[file] = *content
+		}
+	}
+
+	contents, err := kpt.WriteResources(otherFiles, merged)
+	if err != nil {
+		return nil, 0, err
+	}
+	return contents, conflicts, nil
+}
+
+// mergeValue merges the changes made from the base value to the upstream value into the local
+// value of the field at the path, where missing values are nil. It returns the merged value,
+// which is nil if the field is removed, with the conflicts in the field.
+func mergeValue(fieldPath string, base, local, upstream *yaml.Node) (*yaml.Node, []conflict) {
+	switch {
+	case diff.EqualNodes(local, upstream), diff.EqualNodes(base, upstream):
+		return local, nil
+	case diff.EqualNodes(base, local):
+		return upstream, nil
+	}
+
+	local, upstream = resolve(local), resolve(upstream)
+	base = resolve(base)
+	description := "changed both locally and upstream"
+	switch {
+	case local == nil:
+		description = "removed locally but changed upstream"
+	case upstream == nil:
+		description = "removed upstream but changed locally"
+	default:
+		if base != nil && base.Kind != local.Kind {
+			base = nil
+		}
+		switch {
+		case local.Kind == yaml.MappingNode && upstream.Kind == yaml.MappingNode:
+			return mergeMapping(fieldPath, base, local, upstream)
+		case local.Kind == yaml.SequenceNode && upstream.Kind == yaml.SequenceNode &&
+			diff.ElementNames(local) != nil && diff.ElementNames(upstream) != nil &&
+			(base == nil || diff.ElementNames(base) != nil):
+			return mergeSequence(fieldPath, base, local, upstream)
+		}
+	}
+
+	c := conflict{Path: fieldPath, Description: description}
+	if base != nil {
+		c.Base = diff.Encode(base)
+	}
+	if local != nil {
+		c.Local = diff.Encode(local)
+	}
+	if upstream != nil {
+		c.Upstream = diff.Encode(upstream)
+	}
+	return local, []conflict{c}
+}
+
+// mergeMapping merges two mappings field by field.
+func mergeMapping(fieldPath string, base, local, upstream *yaml.Node) (*yaml.Node, []conflict) {
+	baseFields, localFields, upstreamFields := fieldsOf(base), fieldsOf(local), fieldsOf(upstream)
+	// Fields are kept in their local order, followed by the fields added upstream. Fields only
+	// in the base were removed both locally and upstream.
+	merged := *local
+	merged.Content = nil
+	var conflicts []conflict
+	seen := map[string]bool{}
+	for _, source := range []*yaml.Node{local, upstream} {
+		for i := 0; i+1 < len(source.Content); i += 2 {
+			key := source.Content[i]
+			if seen[key.Value] {
+				continue
+			}
+			seen[key.Value] = true
+			value, fieldConflicts := mergeValue(diff.FieldPath(fieldPath, key.Value),
+				baseFields[key.Value], localFields[key.Value], upstreamFields[key.Value])
+			conflicts = append(conflicts, fieldConflicts...)
+			if value != nil {
+				merged.Content = append(merged.Content, key, value)
+			}
+		}
+	}
+	return &merged, conflicts
+}
+
+// mergeSequence merges two sequences whose elements all have a name element by element.
+func mergeSequence(fieldPath string, base, local, upstream *yaml.Node) (*yaml.Node, []conflict) {
+	elementsOf := func(node *yaml.Node) ([]string, map[string]*yaml.Node) {
+		if node == nil {
+			return nil, nil
+		}
+		names := diff.ElementNames(node)
+		elements := map[string]*yaml.Node{}
+		for i, name := range names {
+			elements[name] = node.Content[i]
+		}
+		return names, elements
+	}
+	_, baseElements := elementsOf(base)
+	localNames, localElements := elementsOf(local)
+	upstreamNames, upstreamElements := elementsOf(upstream)
+
+	merged := *local
+	merged.Content = nil
+	var conflicts []conflict
+	seen := map[string]bool{}
+	for _, names := range [][]string{localNames, upstreamNames} {
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			value, elementConflicts := mergeValue(diff.ElementPath(fieldPath, name),
+				baseElements[name], localElements[name], upstreamElements[name])
+			conflicts = append(conflicts, elementConflicts...)
+			if value != nil {
+				merged.Content = append(merged.Content, value)
+			}
+		}
+	}
+	return &merged, conflicts
+}
+
+// mergeFile merges a file holding no resources. It returns the merged content, which is nil if
+// the file is removed, and true if the changes conflict.
+func mergeFile(base, local, upstream map[string]string, file string) (*string, bool) {
+	b, inBase := base[file]
+	l, inLocal := local[file]
+	u, inUpstream := upstream[file]
+	switch {
+	case inLocal == inUpstream && l == u, inBase == inUpstream && b == u:
+		if !inLocal {
+			return nil, false
+		}
+		return &l, false
+	case inBase == inLocal && b == l:
+		if !inUpstream {
+			return nil, false
+		}
+		return &u, false
+	}
+
+	var merged strings.Builder
+	for _, section := range []struct {
+		marker  string
+		content string
+	}{{localMarker, l}, {baseMarker, b}, {upstreamMarker, u}} {
+		merged.WriteString(section.marker + "\n")
+		merged.WriteString(section.content)
+		if section.content != "" && !strings.HasSuffix(section.content, "\n") {
+			merged.WriteString("\n")
+		}
+	}
+	merged.WriteString(endMarker + "\n")
+	content := merged.String()
+	return &content, true
+}
+
+// Conflicts returns the unresolved merge conflicts in package contents.
+func Conflicts(contents map[string]string) ([]cachev1alpha1.MergeConflict, error) {
+	nodes, err := kpt.ReadResources(contents)
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []cachev1alpha1.MergeConflict
+	resourceFiles := map[string]bool{}
+	for _, node := range nodes {
+		resourceFiles[kpt.PathOf(node)] = true
+		annotation, found := node.GetAnnotations()[ConflictsAnnotation]
+		if !found {
+			continue
+		}
+		var resourceConflicts []conflict
+		if err := json.Unmarshal([]byte(annotation), &resourceConflicts); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation on %s %q: %w", ConflictsAnnotation, node.GetKind(), node.GetName(), err)
+		}
+		for _, c := range resourceConflicts {
+			conflicts = append(conflicts, cachev1alpha1.MergeConflict{
+				File:        kpt.PathOf(node),
+				APIVersion:  node.GetApiVersion(),
+				Kind:        node.GetKind(),
+				Name:        node.GetName(),
+				Namespace:   node.GetNamespace(),
+				Path:        c.Path,
+				Base:        c.Base,
+				Local:       c.Local,
+				Upstream:    c.Upstream,
+				Description: c.Description,
+			})
+		}
+	}
+	for _, file := range slices.Sorted(maps.Keys(contents)) {
+		if !resourceFiles[file] && hasConflictMarkers(contents[file]) {
+			conflicts = append(conflicts, cachev1alpha1.MergeConflict{
+				File:        file,
+				Description: "changed both locally and upstream; resolve the conflict markers in the file",
+			})
+		}
+	}
+	return conflicts, nil
+}
+
+// hasConflictMarkers returns true if the content holds conflict markers.
+func hasConflictMarkers(content string) bool {
+	for line := range strings.Lines(content) {
+		if strings.TrimRight(line, "\n") == localMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// readResources returns the resources of package contents by their identity.
+func readResources(contents map[string]string) (map[string]*resource, error) {
+	nodes, err := kpt.ReadResources(contents)
+	if err != nil {
+		return nil, err
+	}
+	resources := map[string]*resource{}
+	for _, node := range nodes {
+		id := kpt.IDOf(node)
+		if _, found := resources[id]; found {
+			return nil, fmt.Errorf("%s %q is declared more than once in %s", node.GetKind(), node.GetName(), kpt.PathOf(node))
+		}
+		stripped, err := kpt.WithoutFileAnnotations(node)
+		if err != nil {
+			return nil, err
+		}
+		resources[id] = &resource{node: stripped, original: node}
+	}
+	return resources, nil
+}
+
+// withFileAnnotations returns a copy of the merged resource with the file annotations of the
+// resource it was merged into.
+func withFileAnnotations(r *resource) (*yaml.RNode, error) {
+	node := r.node.Copy()
+	annotations := node.GetAnnotations()
+	for key, value := range r.original.GetAnnotations() {
+		if strings.HasPrefix(key, "internal.config.kubernetes.io/") ||
+			key == kioutil.LegacyPathAnnotation || key == kioutil.LegacyIndexAnnotation {
+			annotations[key] = value
+		}
+	}
+	if err := node.SetAnnotations(annotations); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// filesWithResources returns the files that hold resources in any of the contents.
+func filesWithResources(contents ...map[string]string) (map[string]bool, error) {
+	files := map[string]bool{}
+	for _, c := range contents {
+		nodes, err := kpt.ReadResources(c)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			files[kpt.PathOf(node)] = true
+		}
+	}
+	return files, nil
+}
+
+// fieldsOf returns the values of the fields of a mapping by key, or nil for a nil mapping.
+func fieldsOf(node *yaml.Node) map[string]*yaml.Node {
+	if node == nil {
+		return nil
+	}
+	fields := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fields[node.Content[i].Value] = node.Content[i+1]
+	}
+	return fields
+}
+
+// resolve returns the node an alias or document refers to, or nil for nil.
+func resolve(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	return diff.Resolve(node)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package merge
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+var _ = Describe("ThreeWay", func() {
+	kptfile := "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: app\n"
+	base := map[string]string{
+		"Kptfile": kptfile,
+		"deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+`,
+		"service.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n",
+	}
+
+	It("should merge changes made to different fields and elements", func() {
+		local := map[string]string{
+			"Kptfile": kptfile,
+			"deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3 # scaled for the site
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+      - name: proxy
+        image: proxy:v1
+`,
+			"service.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n",
+			"site.yaml":    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: site\n",
+		}
+		upstream := map[string]string{
+			"Kptfile": kptfile,
+			"deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+`,
+		}
+
+		merged, conflicts, err := ThreeWay(base, local, upstream)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conflicts).To(BeZero())
+		Expect(merged).To(HaveKey("site.yaml"))
+		Expect(merged).NotTo(HaveKey("service.yaml"))
+		Expect(merged["deployment.yaml"]).To(Equal(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3 # scaled for the site
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+      - name: proxy
+        image: proxy:v1
+`))
+
+		found, err := Conflicts(merged)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeEmpty())
+	})
+
+	It("should report a resource removed upstream but modified locally", func() {
+		local := map[string]string{
+			"Kptfile":         kptfile,
+			"deployment.yaml": base["deployment.yaml"],
+			"service.yaml":    "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\nspec:\n  type: NodePort\n",
+		}
+		upstream := map[string]string{
+			"Kptfile":         kptfile,
+			"deployment.yaml": base["deployment.yaml"],
+		}
+
+		merged, conflicts, err := ThreeWay(base, local, upstream)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conflicts).To(Equal(1))
+		Expect(merged["service.yaml"]).To(ContainSubstring("type: NodePort"))
+
+		found, err := Conflicts(merged)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(Equal([]cachev1alpha1.MergeConflict{{
+			File:        "service.yaml",
+			APIVersion:  "v1",
+			Kind:        "Service",
+			Name:        "app",
+			Description: "removed upstream but modified locally",
+		}}))
+	})
+
+	It("should report fields removed on one side but changed on the other", func() {
+		configMap := func(data string) map[string]string {
+			return map[string]string{"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n" + data}
+		}
+		base := configMap("  x: \"1\"\n  y: \"1\"\n")
+
+		merged, conflicts, err := ThreeWay(base, configMap("  y: \"1\"\n"), configMap("  x: \"2\"\n  y: \"1\"\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conflicts).To(Equal(1))
+		found, err := Conflicts(merged)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(ConsistOf(And(
+			HaveField("Path", "data.x"),
+			HaveField("Base", "\"1\""),
+			HaveField("Local", ""),
+			HaveField("Upstream", "\"2\""),
+			HaveField("Description", "removed locally but changed upstream"))))
+
+		merged, conflicts, err = ThreeWay(base, configMap("  x: \"2\"\n  y: \"1\"\n"), configMap("  y: \"1\"\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conflicts).To(Equal(1))
+		Expect(merged["config.yaml"]).To(ContainSubstring("x: \"2\""))
+		found, err = Conflicts(merged)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(ConsistOf(And(
+			HaveField("Path", "data.x"),
+			HaveField("Local", "\"2\""),
+			HaveField("Upstream", ""),
+			HaveField("Description", "removed upstream but changed locally"))))
+	})
+})
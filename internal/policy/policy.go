@@ -0,0 +1,121 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy enforces FunctionPolicies on the functions run by the pipelines of packages.
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+// ErrFunctionNotAllowed is returned when a FunctionPolicy does not allow a function.
+var ErrFunctionNotAllowed = errors.New("function is not allowed")
+
+// AppliesTo returns true if the policy applies to the packages of the repository in the namespace.
+func AppliesTo(policy *cachev1alpha1.FunctionPolicy, namespace, repository string) bool {
+	return (len(policy.Spec.Namespaces) == 0 || slices.Contains(policy.Spec.Namespaces, namespace)) &&
+		(len(policy.Spec.Repositories) == 0 || slices.Contains(policy.Spec.Repositories, repository))
+}
+
+// Allows returns true if the policy allows the function image.
+func Allows(policy *cachev1alpha1.FunctionPolicy, image string) bool {
+	name := fn.ImageName(image)
+	_, digest, pinned := strings.Cut(image, "@")
+	for _, allowed := range policy.Spec.AllowedFunctions {
+		if prefix, found := strings.CutSuffix(allowed.Image, "*"); found {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+		} else if name != allowed.Image {
+			continue
+		}
+		if len(allowed.Digests) == 0 || pinned && slices.Contains(allowed.Digests, digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// Runner is a fn.Runner that only runs the functions allowed by every one of its policies, within
+// their limits.
+type Runner struct {
+	// Runner runs the allowed functions.
+	Runner fn.Runner
+
+	// Policies are the FunctionPolicies that apply to the package being rendered.
+	Policies []cachev1alpha1.FunctionPolicy
+}
+
+var _ fn.Runner = &Runner{}
+
+// Run runs the function if it is allowed, or returns an error wrapping ErrFunctionNotAllowed.
+// The function is run within the lowest of the limits of the policies.
+func (r *Runner) Run(ctx context.Context, function *kpt.Function, input []byte) ([]byte, error) {
+	var timeout time.Duration
+	var memory, maxSteps uint64
+	var timeoutLimitedBy, memoryLimitedBy string
+	for i := range r.Policies {
+		policy := &r.Policies[i]
+		if !Allows(policy, function.Image) {
+			return nil, fmt.Errorf("%w by FunctionPolicy %q: %s", ErrFunctionNotAllowed, policy.Name, function.Image)
+		}
+		limits := policy.Spec.Limits
+		if limits == nil {
+			continue
+		}
+		if limits.Timeout != nil && (timeout == 0 || limits.Timeout.Duration < timeout) {
+			timeout, timeoutLimitedBy = limits.Timeout.Duration, policy.Name
+		}
+		if limits.Memory != nil && limits.Memory.Sign() > 0 &&
+			(memory == 0 || uint64(limits.Memory.Value()) < memory) {
+			memory, memoryLimitedBy = uint64(limits.Memory.Value()), policy.Name
+		}
+		if limits.MaxSteps != nil && *limits.MaxSteps > 0 && (maxSteps == 0 || uint64(*limits.MaxSteps) < maxSteps) {
+			maxSteps = uint64(*limits.MaxSteps)
+		}
+	}
+	if memory != 0 {
+		ctx = fn.WithMemoryLimit(ctx, memory)
+	}
+	if maxSteps != 0 {
+		ctx = fn.WithMaxSteps(ctx, maxSteps)
+	}
+
+	runCtx := ctx
+	if timeout != 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	output, err := r.Runner.Run(runCtx, function, input)
+	switch {
+	case err == nil:
+	case timeout != 0 && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("function did not finish within the %s limit of FunctionPolicy %q", timeout, timeoutLimitedBy)
+	case errors.Is(err, fn.ErrMemoryLimitExceeded):
+		err = fmt.Errorf("%w, set by FunctionPolicy %q", err, memoryLimitedBy)
+	}
+	return output, err
+}
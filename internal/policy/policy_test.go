@@ -0,0 +1,95 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/fn"
+	"github.com/liamfallon/porch-operator/internal/kpt"
+)
+
+// limitsRunner records the limits of the context it runs functions with, and fails the functions
+// whose memory exceeds the limit.
+type limitsRunner struct {
+	memory   uint64
+	maxSteps uint64
+	deadline bool
+}
+
+func (r *limitsRunner) Run(ctx context.Context, function *kpt.Function, input []byte) ([]byte, error) {
+	r.memory, r.maxSteps = fn.MemoryLimit(ctx), fn.MaxSteps(ctx)
+	_, r.deadline = ctx.Deadline()
+	if function.Image == "example.com/fns/bigmem" {
+		return nil, fmt.Errorf("%w of %d bytes", fn.ErrMemoryLimitExceeded, r.memory)
+	}
+	return input, nil
+}
+
+var _ = Describe("Function Policy Runner", func() {
+	ctx := context.Background()
+
+	newPolicy := func(name string, limits *cachev1alpha1.FunctionLimits) cachev1alpha1.FunctionPolicy {
+		return cachev1alpha1.FunctionPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: cachev1alpha1.FunctionPolicySpec{
+				AllowedFunctions: []cachev1alpha1.AllowedFunction{{Image: "example.com/fns/*"}},
+				Limits:           limits,
+			},
+		}
+	}
+
+	It("should refuse the functions a policy does not allow", func() {
+		inner := &limitsRunner{}
+		runner := &Runner{Runner: inner, Policies: []cachev1alpha1.FunctionPolicy{newPolicy("fns", nil)}}
+
+		_, err := runner.Run(ctx, &kpt.Function{Image: "example.org/generate:v1"}, nil)
+		Expect(err).To(MatchError(ErrFunctionNotAllowed))
+		_, err = runner.Run(ctx, &kpt.Function{Image: "example.com/fns/set-labels:v1"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*inner).To(Equal(limitsRunner{}))
+	})
+
+	It("should run functions within the lowest limits of the policies", func() {
+		inner := &limitsRunner{}
+		runner := &Runner{Runner: inner, Policies: []cachev1alpha1.FunctionPolicy{
+			newPolicy("large", &cachev1alpha1.FunctionLimits{
+				Timeout:  &metav1.Duration{Duration: time.Minute},
+				Memory:   ptr.To(resource.MustParse("64Mi")),
+				MaxSteps: ptr.To[int64](1000),
+			}),
+			newPolicy("small", &cachev1alpha1.FunctionLimits{Memory: ptr.To(resource.MustParse("16Mi"))}),
+		}}
+
+		_, err := runner.Run(ctx, &kpt.Function{Image: "example.com/fns/set-labels:v1"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*inner).To(Equal(limitsRunner{memory: 16 << 20, maxSteps: 1000, deadline: true}))
+
+		_, err = runner.Run(ctx, &kpt.Function{Image: "example.com/fns/bigmem"}, nil)
+		Expect(err).To(MatchError(fn.ErrMemoryLimitExceeded))
+		Expect(err).To(MatchError(ContainSubstring(`set by FunctionPolicy "small"`)))
+	})
+})
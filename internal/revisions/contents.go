@@ -0,0 +1,162 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revisions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+// MetadataFile is the file, in a directory the contents of a PackageRevision are pulled into, that
+// records which PackageRevision they are and the generation of their last edit when they were
+// pulled. Pushing the directory back only succeeds if the contents of the PackageRevision have not
+// been edited since: the operator rendering them does not count as an edit.
+const MetadataFile = ".KptRevisionMetadata"
+
+// Pull writes the contents of a PackageRevision into the directory, which must be empty or not
+// exist, along with the MetadataFile.
+func Pull(ctx context.Context, reader client.Reader, namespace, name, dir string) error {
+	pr, err := Get(ctx, reader, namespace, name)
+	if err != nil {
+		return err
+	}
+	prr := &cachev1alpha1.PackageRevisionResources{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, prr); err != nil {
+		return fmt.Errorf("failed to get the contents of PackageRevision %q: %w", name, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("directory %s is not empty", dir)
+	}
+	for file, content := range prr.Spec.Resources {
+		if !filepath.IsLocal(filepath.FromSlash(file)) || file == MetadataFile {
+			return fmt.Errorf("PackageRevision %q has a file with an invalid path %q", name, file)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return writeMetadata(dir, prr, editGeneration(pr, prr))
+}
+
+// Push replaces the contents of the draft PackageRevision that the directory was pulled from
+// with the files of the directory, and records the new version of the contents in its
+// MetadataFile. It fails if the contents were changed since they were pulled.
+func Push(ctx context.Context, c client.Client, dir string) (*cachev1alpha1.PackageRevisionResources, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MetadataFile))
+	if err != nil {
+		return nil, fmt.Errorf("directory %s was not pulled from a PackageRevision: %w", dir, err)
+	}
+	pulled := &metav1.ObjectMeta{}
+	if err := yaml.Unmarshal(data, pulled); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", MetadataFile, err)
+	}
+
+	pr, err := Get(ctx, c, pulled.Namespace, pulled.Name)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Spec.Lifecycle != cachev1alpha1.PackageRevisionLifecycleDraft {
+		return nil, fmt.Errorf("PackageRevision %q is %s: only drafts can be pushed to", pr.Name, pr.Spec.Lifecycle)
+	}
+	contents, err := readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	prr := &cachev1alpha1.PackageRevisionResources{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: pulled.Namespace, Name: pulled.Name}, prr); err != nil {
+		return nil, fmt.Errorf("failed to get the contents of PackageRevision %q: %w", pr.Name, err)
+	}
+	changed := fmt.Errorf("the contents of PackageRevision %q have changed since they were pulled, pull them again", pr.Name)
+	if editGeneration(pr, prr) != pulled.Generation {
+		return nil, changed
+	}
+	// The update is refused by the API server if the contents change while they are pushed.
+	prr.Spec.Resources = contents
+	if err := c.Update(ctx, prr); err != nil {
+		if apierrors.IsConflict(err) {
+			return nil, changed
+		}
+		return nil, err
+	}
+	// The push is itself an edit.
+	return prr, writeMetadata(dir, prr, prr.Generation)
+}
+
+// editGeneration returns the generation of the contents of a PackageRevision written by their last
+// edit. The contents the operator wrote itself since are left out, unless it has not reconciled
+// the PackageRevision since the contents were last written, in which case that write is an edit.
+func editGeneration(pr *cachev1alpha1.PackageRevision, prr *cachev1alpha1.PackageRevisionResources) int64 {
+	if prr.Generation == pr.Status.ObservedResourcesGeneration {
+		return pr.Status.EditedResourcesGeneration
+	}
+	return prr.Generation
+}
+
+// readDir reads the files of the directory, keyed by their slash-separated paths relative to it.
+func readDir(dir string) (map[string]string, error) {
+	contents := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		file, err := filepath.Rel(dir, path)
+		if err != nil || file == MetadataFile {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		contents[filepath.ToSlash(file)] = string(data)
+		return nil
+	})
+	return contents, err
+}
+
+// writeMetadata writes the MetadataFile of the contents of a PackageRevision, whose last edit has
+// the generation, into the directory.
+func writeMetadata(dir string, prr *cachev1alpha1.PackageRevisionResources, generation int64) error {
+	data, err := yaml.Marshal(&metav1.ObjectMeta{Name: prr.Name, Namespace: prr.Namespace, Generation: generation})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, MetadataFile), data, 0o644)
+}
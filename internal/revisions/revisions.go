@@ -0,0 +1,226 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package revisions builds the PackageRevisions of the common package operations, and drives
+// their lifecycle, as porchctl does: drafts are created with the tasks that produce their
+// contents, and are proposed, approved, rejected and deleted by changing their lifecycle.
+package revisions
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/diff"
+)
+
+// Name returns the object name of the revision of a package in a workspace of a repository.
+func Name(repoName, packageName, workspaceName string) string {
+	return strings.ToLower(strings.Join([]string{repoName, strings.ReplaceAll(packageName, "/", "."), workspaceName}, "."))
+}
+
+// NewDraft returns a draft of the package in the workspace of the repository, whose contents are
+// produced by the tasks.
+func NewDraft(namespace, repoName, packageName, workspaceName string, tasks ...cachev1alpha1.Task) *cachev1alpha1.PackageRevision {
+	return &cachev1alpha1.PackageRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: Name(repoName, packageName, workspaceName), Namespace: namespace},
+		Spec: cachev1alpha1.PackageRevisionSpec{
+			PackageName:    packageName,
+			RepositoryName: repoName,
+			WorkspaceName:  workspaceName,
+			Lifecycle:      cachev1alpha1.PackageRevisionLifecycleDraft,
+			Tasks:          tasks,
+		},
+	}
+}
+
+// InitTask returns the task that initializes a new package.
+func InitTask(spec cachev1alpha1.PackageInitTaskSpec) cachev1alpha1.Task {
+	return cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeInit, Init: &spec}
+}
+
+// CloneTask returns the task that clones the upstream PackageRevision with the strategy.
+func CloneTask(upstream string, strategy cachev1alpha1.PackageMergeStrategy) cachev1alpha1.Task {
+	return cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeClone, Clone: &cachev1alpha1.PackageCloneTaskSpec{
+		Upstream: cachev1alpha1.UpstreamPackage{UpstreamRef: &cachev1alpha1.PackageRevisionRef{Name: upstream}},
+		Strategy: strategy,
+	}}
+}
+
+// EditTask returns the task that copies the contents of the source PackageRevision.
+func EditTask(source string) cachev1alpha1.Task {
+	return cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeEdit, Edit: &cachev1alpha1.PackageEditTaskSpec{
+		Source: &cachev1alpha1.PackageRevisionRef{Name: source},
+	}}
+}
+
+// UpgradeTask returns the task that upgrades the local PackageRevision from its upstream to a newer
+// revision of the upstream package: the revision of the package numbered revision, or its latest
+// revision if revision is 0.
+func UpgradeTask(ctx context.Context, reader client.Reader, local *cachev1alpha1.PackageRevision, revision int,
+	strategy cachev1alpha1.PackageMergeStrategy) (cachev1alpha1.Task, error) {
+	oldUpstream, err := diff.UpstreamOf(local)
+	if err != nil {
+		return cachev1alpha1.Task{}, err
+	}
+	old, err := Get(ctx, reader, local.Namespace, oldUpstream)
+	if err != nil {
+		return cachev1alpha1.Task{}, err
+	}
+	revisions, err := Published(ctx, reader, local.Namespace, old.Spec.RepositoryName, old.Spec.PackageName)
+	if err != nil {
+		return cachev1alpha1.Task{}, err
+	}
+	var newUpstream *cachev1alpha1.PackageRevision
+	for i := range revisions {
+		if revision == 0 || revisions[i].Spec.Revision == revision {
+			newUpstream = &revisions[i]
+		}
+	}
+	switch {
+	case newUpstream == nil && revision == 0:
+		return cachev1alpha1.Task{}, fmt.Errorf("package %s of repository %s has no published revision",
+			old.Spec.PackageName, old.Spec.RepositoryName)
+	case newUpstream == nil:
+		return cachev1alpha1.Task{}, fmt.Errorf("package %s of repository %s has no published revision %d",
+			old.Spec.PackageName, old.Spec.RepositoryName, revision)
+	case newUpstream.Spec.Revision <= old.Spec.Revision:
+		return cachev1alpha1.Task{}, fmt.Errorf("upstream %s of PackageRevision %q is revision %d, which is not older than revision %d",
+			old.Name, local.Name, old.Spec.Revision, newUpstream.Spec.Revision)
+	}
+	return cachev1alpha1.Task{Type: cachev1alpha1.TaskTypeUpgrade, Upgrade: &cachev1alpha1.PackageUpgradeTaskSpec{
+		OldUpstream:             cachev1alpha1.PackageRevisionRef{Name: old.Name},
+		NewUpstream:             cachev1alpha1.PackageRevisionRef{Name: newUpstream.Name},
+		LocalPackageRevisionRef: cachev1alpha1.PackageRevisionRef{Name: local.Name},
+		Strategy:                strategy,
+	}}, nil
+}
+
+// Get gets a PackageRevision.
+func Get(ctx context.Context, reader client.Reader, namespace, name string) (*cachev1alpha1.PackageRevision, error) {
+	pr := &cachev1alpha1.PackageRevision{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, pr); err != nil {
+		return nil, fmt.Errorf("failed to get PackageRevision %q: %w", name, err)
+	}
+	return pr, nil
+}
+
+// Published returns the published revisions of the package of the repository, oldest first.
+func Published(ctx context.Context, reader client.Reader, namespace, repoName, packageName string) ([]cachev1alpha1.PackageRevision, error) {
+	prs := &cachev1alpha1.PackageRevisionList{}
+	if err := reader.List(ctx, prs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	var published []cachev1alpha1.PackageRevision
+	for _, pr := range prs.Items {
+		if pr.Spec.RepositoryName == repoName && pr.Spec.PackageName == packageName &&
+			pr.Spec.Lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished {
+			published = append(published, pr)
+		}
+	}
+	slices.SortFunc(published, func(a, b cachev1alpha1.PackageRevision) int { return a.Spec.Revision - b.Spec.Revision })
+	return published, nil
+}
+
+// Propose proposes a draft for approval.
+func Propose(ctx context.Context, c client.Client, namespace, name string) (*cachev1alpha1.PackageRevision, error) {
+	return transition(ctx, c, namespace, name, func(pr *cachev1alpha1.PackageRevision) error {
+		return move(pr, cachev1alpha1.PackageRevisionLifecycleDraft, cachev1alpha1.PackageRevisionLifecycleProposed)
+	})
+}
+
+// Approve publishes a proposed PackageRevision, as the next revision of its package.
+func Approve(ctx context.Context, c client.Client, namespace, name string) (*cachev1alpha1.PackageRevision, error) {
+	return transition(ctx, c, namespace, name, func(pr *cachev1alpha1.PackageRevision) error {
+		if err := move(pr, cachev1alpha1.PackageRevisionLifecycleProposed, cachev1alpha1.PackageRevisionLifecyclePublished); err != nil {
+			return err
+		}
+		published, err := Published(ctx, c, namespace, pr.Spec.RepositoryName, pr.Spec.PackageName)
+		if err != nil {
+			return err
+		}
+		pr.Spec.Revision = 1
+		if len(published) > 0 {
+			pr.Spec.Revision = published[len(published)-1].Spec.Revision + 1
+		}
+		return nil
+	})
+}
+
+// Reject sends a proposed PackageRevision back to Draft, or a PackageRevision proposed for
+// deletion back to Published.
+func Reject(ctx context.Context, c client.Client, namespace, name string) (*cachev1alpha1.PackageRevision, error) {
+	return transition(ctx, c, namespace, name, func(pr *cachev1alpha1.PackageRevision) error {
+		if pr.Spec.Lifecycle == cachev1alpha1.PackageRevisionLifecycleDeletionProposed {
+			return move(pr, cachev1alpha1.PackageRevisionLifecycleDeletionProposed, cachev1alpha1.PackageRevisionLifecyclePublished)
+		}
+		return move(pr, cachev1alpha1.PackageRevisionLifecycleProposed, cachev1alpha1.PackageRevisionLifecycleDraft)
+	})
+}
+
+// ProposeDelete proposes the deletion of a published PackageRevision.
+func ProposeDelete(ctx context.Context, c client.Client, namespace, name string) (*cachev1alpha1.PackageRevision, error) {
+	return transition(ctx, c, namespace, name, func(pr *cachev1alpha1.PackageRevision) error {
+		return move(pr, cachev1alpha1.PackageRevisionLifecyclePublished, cachev1alpha1.PackageRevisionLifecycleDeletionProposed)
+	})
+}
+
+// Delete deletes a PackageRevision. Published PackageRevisions are only deleted once their
+// deletion has been proposed.
+func Delete(ctx context.Context, c client.Client, namespace, name string) error {
+	pr, err := Get(ctx, c, namespace, name)
+	if err != nil {
+		return err
+	}
+	if pr.Spec.Lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished {
+		return fmt.Errorf("PackageRevision %q is Published: propose its deletion first", name)
+	}
+	return client.IgnoreNotFound(c.Delete(ctx, pr, client.Preconditions{UID: &pr.UID, ResourceVersion: &pr.ResourceVersion}))
+}
+
+// move changes the lifecycle of the PackageRevision from the lifecycle it must be in to another.
+func move(pr *cachev1alpha1.PackageRevision, from, to cachev1alpha1.PackageRevisionLifecycle) error {
+	if pr.Spec.Lifecycle != from {
+		return fmt.Errorf("PackageRevision %q is %s, not %s", pr.Name, pr.Spec.Lifecycle, from)
+	}
+	pr.Spec.Lifecycle = to
+	return nil
+}
+
+// transition changes a PackageRevision and updates it, and starts over from the latest
+// PackageRevision if it is changed concurrently.
+func transition(ctx context.Context, c client.Client, namespace, name string,
+	change func(*cachev1alpha1.PackageRevision) error) (*cachev1alpha1.PackageRevision, error) {
+	var pr *cachev1alpha1.PackageRevision
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		if pr, err = Get(ctx, c, namespace, name); err != nil {
+			return err
+		}
+		if err := change(pr); err != nil {
+			return err
+		}
+		return c.Update(ctx, pr)
+	})
+	return pr, err
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revisions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+var _ = Describe("Revisions", func() {
+	const namespace = "default"
+	ctx := context.Background()
+
+	var c client.Client
+
+	published := func(repoName, packageName, workspaceName string, revision int,
+		tasks ...cachev1alpha1.Task) *cachev1alpha1.PackageRevision {
+		pr := NewDraft(namespace, repoName, packageName, workspaceName, tasks...)
+		pr.Spec.Lifecycle, pr.Spec.Revision = cachev1alpha1.PackageRevisionLifecyclePublished, revision
+		return pr
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(cachev1alpha1.AddToScheme(scheme)).To(Succeed())
+		// The API server increments the generation of objects whose spec changes.
+		builder := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&cachev1alpha1.PackageRevision{})
+		c = builder.WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				obj.SetGeneration(1)
+				return c.Create(ctx, obj, opts...)
+			},
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if prr, ok := obj.(*cachev1alpha1.PackageRevisionResources); ok {
+					current := &cachev1alpha1.PackageRevisionResources{}
+					if err := c.Get(ctx, client.ObjectKeyFromObject(prr), current); err != nil {
+						return err
+					}
+					prr.Generation = current.Generation
+					if !equality.Semantic.DeepEqual(prr.Spec, current.Spec) {
+						prr.Generation++
+					}
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		}).WithObjects(
+			published("blueprints", "app", "v1", 1),
+			published("blueprints", "app", "v2", 2),
+			published("blueprints", "app", "v3", 3),
+			published("edge", "sites/app", "v1", 1, CloneTask("blueprints.app.v1", "")),
+		).Build()
+	})
+
+	It("should name revisions after their repository, package and workspace", func() {
+		Expect(Name("Edge", "sites/app", "v1")).To(Equal("edge.sites.app.v1"))
+	})
+
+	It("should take drafts through their lifecycle, numbering the revisions they are published as", func() {
+		draft := NewDraft(namespace, "blueprints", "app", "v4", EditTask("blueprints.app.v3"))
+		Expect(c.Create(ctx, draft)).To(Succeed())
+
+		_, err := Approve(ctx, c, namespace, draft.Name)
+		Expect(err).To(MatchError(`PackageRevision "blueprints.app.v4" is Draft, not Proposed`))
+
+		pr, err := Propose(ctx, c, namespace, draft.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecycleProposed))
+		pr, err = Reject(ctx, c, namespace, draft.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecycleDraft))
+
+		_, err = Propose(ctx, c, namespace, draft.Name)
+		Expect(err).NotTo(HaveOccurred())
+		pr, err = Approve(ctx, c, namespace, draft.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecyclePublished))
+		Expect(pr.Spec.Revision).To(Equal(4))
+
+		By("deleting the published revision")
+		Expect(Delete(ctx, c, namespace, draft.Name)).To(MatchError(ContainSubstring("propose its deletion first")))
+		_, err = ProposeDelete(ctx, c, namespace, draft.Name)
+		Expect(err).NotTo(HaveOccurred())
+		pr, err = Reject(ctx, c, namespace, draft.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr.Spec.Lifecycle).To(Equal(cachev1alpha1.PackageRevisionLifecyclePublished))
+		_, err = ProposeDelete(ctx, c, namespace, draft.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Delete(ctx, c, namespace, draft.Name)).To(Succeed())
+		_, err = Get(ctx, c, namespace, draft.Name)
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+	})
+
+	It("should upgrade a package from its upstream to a newer revision of the upstream package", func() {
+		local, err := Get(ctx, c, namespace, "edge.sites.app.v1")
+		Expect(err).NotTo(HaveOccurred())
+
+		task, err := UpgradeTask(ctx, c, local, 0, cachev1alpha1.ResourceMerge)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(task.Type).To(Equal(cachev1alpha1.TaskTypeUpgrade))
+		Expect(*task.Upgrade).To(Equal(cachev1alpha1.PackageUpgradeTaskSpec{
+			OldUpstream:             cachev1alpha1.PackageRevisionRef{Name: "blueprints.app.v1"},
+			NewUpstream:             cachev1alpha1.PackageRevisionRef{Name: "blueprints.app.v3"},
+			LocalPackageRevisionRef: cachev1alpha1.PackageRevisionRef{Name: "edge.sites.app.v1"},
+			Strategy:                cachev1alpha1.ResourceMerge,
+		}))
+
+		task, err = UpgradeTask(ctx, c, local, 2, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(task.Upgrade.NewUpstream.Name).To(Equal("blueprints.app.v2"))
+
+		_, err = UpgradeTask(ctx, c, local, 1, "")
+		Expect(err).To(MatchError(ContainSubstring("which is not older than revision 1")))
+		_, err = UpgradeTask(ctx, c, local, 5, "")
+		Expect(err).To(MatchError("package app of repository blueprints has no published revision 5"))
+	})
+
+	It("should pull the contents of a draft and push them back unless they were edited since", func() {
+		draft := NewDraft(namespace, "blueprints", "app", "v4")
+		Expect(c.Create(ctx, draft)).To(Succeed())
+		prr := &cachev1alpha1.PackageRevisionResources{
+			ObjectMeta: metav1.ObjectMeta{Name: draft.Name, Namespace: namespace},
+			Spec: cachev1alpha1.PackageRevisionResourcesSpec{Resources: map[string]string{
+				"Kptfile":         "apiVersion: kpt.dev/v1\nkind: Kptfile\nmetadata:\n  name: app\n",
+				"config/app.yaml": "kind: ConfigMap\n",
+				"config/old.yaml": "kind: Secret\n",
+			}},
+		}
+		Expect(c.Create(ctx, prr)).To(Succeed())
+
+		// reconcile records an edit of the contents of the draft, and renders them, as the operator does.
+		reconcile := func() {
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(draft), draft)).To(Succeed())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(prr), prr)).To(Succeed())
+			if prr.Generation != draft.Status.ObservedResourcesGeneration {
+				draft.Status.EditedResourcesGeneration = prr.Generation
+			}
+			prr.Spec.Resources["rendered.yaml"] = fmt.Sprintf("# rendered from generation %d\n", prr.Generation)
+			Expect(c.Update(ctx, prr)).To(Succeed())
+			draft.Status.ObservedResourcesGeneration = prr.Generation
+			Expect(c.Status().Update(ctx, draft)).To(Succeed())
+		}
+
+		dir := filepath.Join(GinkgoT().TempDir(), "app")
+		Expect(Pull(ctx, c, namespace, draft.Name, dir)).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(dir, "config", "app.yaml"))).To(BeEquivalentTo("kind: ConfigMap\n"))
+		Expect(Pull(ctx, c, namespace, draft.Name, dir)).To(MatchError(ContainSubstring("is not empty")))
+
+		By("pushing local changes")
+		Expect(os.WriteFile(filepath.Join(dir, "config", "app.yaml"), []byte("kind: ConfigMap # edited\n"), 0o644)).To(Succeed())
+		Expect(os.Remove(filepath.Join(dir, "config", "old.yaml"))).To(Succeed())
+		_, err := Push(ctx, c, dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(prr), prr)).To(Succeed())
+		Expect(prr.Spec.Resources).To(HaveLen(2))
+		Expect(prr.Spec.Resources).To(HaveKeyWithValue("config/app.yaml", "kind: ConfigMap # edited\n"))
+
+		By("pushing twice after the operator rendered the pushed contents")
+		reconcile()
+		_, err = Push(ctx, c, dir)
+		Expect(err).NotTo(HaveOccurred())
+		reconcile()
+		reconcile()
+		_, err = Push(ctx, c, dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("pushing again after the contents were edited")
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(prr), prr)).To(Succeed())
+		prr.Spec.Resources["README.md"] = "# App\n"
+		Expect(c.Update(ctx, prr)).To(Succeed())
+		_, err = Push(ctx, c, dir)
+		Expect(err).To(MatchError(ContainSubstring("have changed since they were pulled")))
+		reconcile()
+		_, err = Push(ctx, c, dir)
+		Expect(err).To(MatchError(ContainSubstring("have changed since they were pulled")))
+
+		By("pushing after pulling rendered contents")
+		Expect(os.RemoveAll(dir)).To(Succeed())
+		Expect(Pull(ctx, c, namespace, draft.Name, dir)).To(Succeed())
+		reconcile()
+		_, err = Push(ctx, c, dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("pushing to a proposed revision")
+		Expect(os.RemoveAll(dir)).To(Succeed())
+		Expect(Pull(ctx, c, namespace, draft.Name, dir)).To(Succeed())
+		_, err = Propose(ctx, c, namespace, draft.Name)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = Push(ctx, c, dir)
+		Expect(err).To(MatchError(ContainSubstring("only drafts can be pushed to")))
+	})
+})
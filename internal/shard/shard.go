@@ -0,0 +1,454 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package shard splits reconciliation between the replicas of the operator by repository.
+//
+// Every Repository belongs to one of a fixed number of shards, by the hash of its namespace and
+// name, and so do all of its PackageRevisions. Each shard is owned by at most one replica at a
+// time, through a Lease named after the shard, so the git operations on a repository are only
+// ever made by one replica.
+//
+// Every replica also holds a membership Lease of its own, which tells the replicas how many of
+// them are running. A replica owns at most its share of the shards, the number of shards divided
+// by the number of replicas rounded up: it releases the shards above its share when replicas
+// join, and acquires the shards that are free, or whose owner has stopped renewing them, while it
+// is below its share. Shards are thus rebalanced when replicas join or leave.
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// LeaseLabel is set on the Leases of the shards and replicas; its value is the kind of Lease.
+	LeaseLabel = "porch.kpt.dev/shard-lease"
+
+	leaseShard  = "shard"
+	leaseMember = "member"
+
+	// DefaultLeaseDuration is how long a shard stays owned by a replica that stops renewing it.
+	DefaultLeaseDuration = 15 * time.Second
+)
+
+var (
+	owned = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "porch_shard_owned",
+		Help: "Whether this replica owns the shard, 1 if it does and 0 if it does not.",
+	}, []string{"shard"})
+	members = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "porch_shard_members",
+		Help: "Number of replicas of the operator sharing the shards.",
+	})
+	transitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "porch_shard_transitions_total",
+		Help: "Number of times this replica acquired, released or lost a shard.",
+	}, []string{"transition"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(owned, members, transitions)
+}
+
+// Shards are the shards of the reconciliation of Repositories, as seen by one replica. A nil
+// *Shards owns every shard, so that an operator that is not sharded reconciles everything.
+type Shards struct {
+	// Client reads and writes the Leases. It should not be backed by the cache of the manager,
+	// which would watch the Leases of the whole cluster.
+	Client client.Client
+
+	// Namespace is the namespace of the Leases.
+	Namespace string
+
+	// Identity identifies this replica in the Leases. It must be unique among the replicas.
+	Identity string
+
+	// Count is the number of shards. Every replica must be configured with the same count.
+	Count int
+
+	// LeaseDuration is how long a shard stays owned by a replica that stops renewing it. The
+	// Leases are renewed every third of it. If zero, DefaultLeaseDuration is used.
+	LeaseDuration time.Duration
+
+	mu sync.Mutex
+	// expiries are when the ownership of the owned shards ends unless it is renewed.
+	expiries map[int]time.Time
+	// locks are held for reading while a shard is reconciled, and for writing while it is
+	// released, so that it is only released once its reconciles are done.
+	locks       []sync.RWMutex
+	subscribers []chan event.TypedGenericEvent[int]
+}
+
+// For returns the shard of the Repository with the name in the namespace.
+func (s *Shards) For(namespace, name string) int {
+	if s == nil || s.Count <= 1 {
+		return 0
+	}
+	return int(fnv32(namespace+"/"+name) % uint32(s.Count))
+}
+
+// Owns returns true if this replica owns the shard.
+func (s *Shards) Owns(shard int) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Before(s.expiries[shard])
+}
+
+// Owned returns the shards this replica owns, in order.
+func (s *Shards) Owned() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var shards []int
+	for shard, expiry := range s.expiries {
+		if now.Before(expiry) {
+			shards = append(shards, shard)
+		}
+	}
+	slices.Sort(shards)
+	return shards
+}
+
+// Lock returns true, and a function that unlocks the shard, if this replica owns the shard. The
+// shard is not released while it is locked, so it should be locked while it is reconciled.
+func (s *Shards) Lock(shard int) (func(), bool) {
+	if s == nil {
+		return func() {}, true
+	}
+	s.init()
+	s.locks[shard].RLock()
+	if !s.Owns(shard) {
+		s.locks[shard].RUnlock()
+		return nil, false
+	}
+	return s.locks[shard].RUnlock, true
+}
+
+// Subscribe returns a channel on which the shards are sent when this replica acquires them, so
+// that their objects are reconciled by this replica. It must be called before the Shards are
+// started.
+func (s *Shards) Subscribe() <-chan event.TypedGenericEvent[int] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan event.TypedGenericEvent[int], s.Count)
+	s.subscribers = append(s.subscribers, ch)
+	return ch
+}
+
+// NeedLeaderElection returns false: every replica owns shards of its own.
+func (s *Shards) NeedLeaderElection() bool {
+	return false
+}
+
+// Start acquires and renews the shards of this replica until the context is done, and then
+// releases them.
+func (s *Shards) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithValues("identity", s.Identity)
+	if s.Count <= 0 {
+		return fmt.Errorf("invalid shard count %d", s.Count)
+	}
+	s.init()
+
+	ticker := time.NewTicker(s.leaseDuration() / 3)
+	defer ticker.Stop()
+	for {
+		if err := s.rebalance(ctx); err != nil {
+			log.Error(err, "Failed to renew shards")
+		}
+		select {
+		case <-ctx.Done():
+			// The manager is stopping, so the shards are handed over to the other replicas
+			// rather than left until their Leases expire.
+			releaseCtx, cancel := context.WithTimeout(context.Background(), s.leaseDuration()/3)
+			defer cancel()
+			if err := s.release(releaseCtx); err != nil {
+				log.Error(err, "Failed to release shards")
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Shards) init() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locks == nil {
+		s.locks = make([]sync.RWMutex, s.Count)
+		s.expiries = map[int]time.Time{}
+	}
+}
+
+func (s *Shards) leaseDuration() time.Duration {
+	if s.LeaseDuration > 0 {
+		return s.LeaseDuration
+	}
+	return DefaultLeaseDuration
+}
+
+// rebalance renews the membership of this replica and the shards it owns, then releases the
+// shards above its share, or acquires free shards up to its share.
+func (s *Shards) rebalance(ctx context.Context) error {
+	log := logf.FromContext(ctx)
+	now := time.Now()
+	if err := s.renew(ctx, s.memberLease(), leaseMember, now); err != nil {
+		return fmt.Errorf("failed to renew membership: %w", err)
+	}
+
+	leases := &coordinationv1.LeaseList{}
+	if err := s.Client.List(ctx, leases, client.InNamespace(s.Namespace), client.HasLabels{LeaseLabel}); err != nil {
+		return fmt.Errorf("failed to list Leases: %w", err)
+	}
+	live := 0
+	byName := map[string]*coordinationv1.Lease{}
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		byName[lease.Name] = lease
+		if lease.Labels[LeaseLabel] == leaseMember && !expired(lease, now) {
+			live++
+		}
+	}
+	live = max(live, 1)
+	members.Set(float64(live))
+	share := (s.Count + live - 1) / live
+
+	var errs []error
+	var held []int
+	for shard := range s.Count {
+		lease := byName[s.shardLease(shard)]
+		if lease == nil || ptr.Deref(lease.Spec.HolderIdentity, "") != s.Identity {
+			s.lose(ctx, shard)
+			continue
+		}
+		if err := s.renew(ctx, s.shardLease(shard), leaseShard, now); err != nil {
+			errs = append(errs, fmt.Errorf("failed to renew shard %d: %w", shard, err))
+			s.lose(ctx, shard)
+			continue
+		}
+		s.hold(shard, now)
+		held = append(held, shard)
+	}
+
+	for len(held) > share {
+		shard := held[len(held)-1]
+		held = held[:len(held)-1]
+		if err := s.releaseShard(ctx, shard); err != nil {
+			errs = append(errs, fmt.Errorf("failed to release shard %d: %w", shard, err))
+			continue
+		}
+		log.Info("Released shard", "shard", shard, "share", share)
+	}
+
+	// Replicas start looking for free shards at different shards, so that they do not all
+	// compete for the same ones.
+	start := int(fnv32(s.Identity) % uint32(s.Count))
+	for i := 0; i < s.Count && len(held) < share; i++ {
+		shard := (start + i) % s.Count
+		lease := byName[s.shardLease(shard)]
+		if lease != nil && ptr.Deref(lease.Spec.HolderIdentity, "") != "" && !expired(lease, now) {
+			continue
+		}
+		acquired, err := s.acquire(ctx, shard, lease, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to acquire shard %d: %w", shard, err))
+			continue
+		}
+		if acquired {
+			held = append(held, shard)
+			log.Info("Acquired shard", "shard", shard, "share", share)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// renew creates or renews a Lease held by this replica.
+func (s *Shards) renew(ctx context.Context, name, kind string, now time.Time) error {
+	lease := &coordinationv1.Lease{}
+	err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: name}, lease)
+	switch {
+	case apierrors.IsNotFound(err):
+		lease = s.newLease(name, kind, now)
+		err = s.Client.Create(ctx, lease)
+	case err != nil:
+	case ptr.Deref(lease.Spec.HolderIdentity, "") != s.Identity:
+		return fmt.Errorf("lease %s is held by %q", name, ptr.Deref(lease.Spec.HolderIdentity, ""))
+	default:
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+		lease.Spec.LeaseDurationSeconds = ptr.To(int32(s.leaseDuration().Seconds()))
+		err = s.Client.Update(ctx, lease)
+	}
+	return err
+}
+
+// acquire takes over the Lease of a free shard, unless another replica takes it first.
+func (s *Shards) acquire(ctx context.Context, shard int, lease *coordinationv1.Lease, now time.Time) (bool, error) {
+	var err error
+	if lease == nil {
+		lease = s.newLease(s.shardLease(shard), leaseShard, now)
+		err = s.Client.Create(ctx, lease)
+	} else {
+		// The resource version of the Lease as it was listed guards against replicas acquiring
+		// the shard together.
+		lease = lease.DeepCopy()
+		lease.Spec.HolderIdentity = ptr.To(s.Identity)
+		lease.Spec.AcquireTime = &metav1.MicroTime{Time: now}
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+		lease.Spec.LeaseDurationSeconds = ptr.To(int32(s.leaseDuration().Seconds()))
+		lease.Spec.LeaseTransitions = ptr.To(ptr.Deref(lease.Spec.LeaseTransitions, 0) + 1)
+		err = s.Client.Update(ctx, lease)
+	}
+	if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.hold(shard, now)
+	transitions.WithLabelValues("acquired").Inc()
+
+	s.mu.Lock()
+	subscribers := slices.Clone(s.subscribers)
+	s.mu.Unlock()
+	for _, ch := range subscribers {
+		go func() {
+			select {
+			case ch <- event.TypedGenericEvent[int]{Object: shard}:
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return true, nil
+}
+
+// hold records that this replica owns the shard, whose Lease it has just written. The ownership
+// ends before the Lease expires for the other replicas, so that the shard is never owned twice.
+func (s *Shards) hold(shard int, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expiries[shard] = now.Add(s.leaseDuration() * 2 / 3)
+	owned.WithLabelValues(strconv.Itoa(shard)).Set(1)
+}
+
+// lose records that this replica no longer owns the shard, if it did.
+func (s *Shards) lose(ctx context.Context, shard int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.expiries[shard]; !found {
+		return
+	}
+	delete(s.expiries, shard)
+	owned.WithLabelValues(strconv.Itoa(shard)).Set(0)
+	transitions.WithLabelValues("lost").Inc()
+	logf.FromContext(ctx).Info("Lost shard", "shard", shard)
+}
+
+// releaseShard waits for the reconciles of the shard to finish, and then hands it over to the
+// other replicas by clearing the holder of its Lease.
+func (s *Shards) releaseShard(ctx context.Context, shard int) error {
+	s.locks[shard].Lock()
+	s.mu.Lock()
+	delete(s.expiries, shard)
+	s.mu.Unlock()
+	s.locks[shard].Unlock()
+	owned.WithLabelValues(strconv.Itoa(shard)).Set(0)
+	transitions.WithLabelValues("released").Inc()
+
+	lease := &coordinationv1.Lease{}
+	if err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: s.shardLease(shard)}, lease); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if ptr.Deref(lease.Spec.HolderIdentity, "") != s.Identity {
+		return nil
+	}
+	lease.Spec.HolderIdentity = nil
+	return s.Client.Update(ctx, lease)
+}
+
+// release releases every shard this replica owns, and ends its membership.
+func (s *Shards) release(ctx context.Context) error {
+	var errs []error
+	for _, shard := range s.Owned() {
+		if err := s.releaseShard(ctx, shard); err != nil {
+			errs = append(errs, fmt.Errorf("failed to release shard %d: %w", shard, err))
+		}
+	}
+	member := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: s.Namespace, Name: s.memberLease()}}
+	if err := client.IgnoreNotFound(s.Client.Delete(ctx, member)); err != nil {
+		errs = append(errs, fmt.Errorf("failed to end membership: %w", err))
+	}
+	members.Set(0)
+	return errors.Join(errs...)
+}
+
+func (s *Shards) newLease(name, kind string, now time.Time) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: s.Namespace,
+			Name:      name,
+			Labels:    map[string]string{LeaseLabel: kind},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To(s.Identity),
+			LeaseDurationSeconds: ptr.To(int32(s.leaseDuration().Seconds())),
+			AcquireTime:          &metav1.MicroTime{Time: now},
+			RenewTime:            &metav1.MicroTime{Time: now},
+		},
+	}
+}
+
+func (s *Shards) shardLease(shard int) string {
+	return fmt.Sprintf("porch-shard-%d", shard)
+}
+
+// memberLease returns the name of the membership Lease of this replica. Identities need not be
+// valid object names, so the name is derived from the hash of the identity.
+func (s *Shards) memberLease() string {
+	return fmt.Sprintf("porch-shard-member-%08x", fnv32(s.Identity))
+}
+
+// expired returns true if the Lease is held by no one, or has not been renewed in time.
+func expired(lease *coordinationv1.Lease, now time.Time) bool {
+	if ptr.Deref(lease.Spec.HolderIdentity, "") == "" || lease.Spec.RenewTime == nil {
+		return true
+	}
+	duration := time.Duration(ptr.Deref(lease.Spec.LeaseDurationSeconds, 0)) * time.Second
+	return !now.Before(lease.Spec.RenewTime.Add(duration))
+}
+
+func fnv32(s string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum32()
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shard
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Shards", func() {
+	ctx := context.Background()
+	const count = 8
+
+	var c client.Client
+	newShards := func(identity string) *Shards {
+		return &Shards{Client: c, Namespace: "porch-system", Identity: identity, Count: count}
+	}
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().Build()
+	})
+
+	It("should split the shards between the replicas and rebalance them", func() {
+		first, second := newShards("first"), newShards("second")
+		first.init()
+		second.init()
+		acquired := second.Subscribe()
+
+		Expect(first.rebalance(ctx)).To(Succeed())
+		Expect(first.Owned()).To(HaveLen(count))
+
+		By("releasing the shards above its share once another replica joins")
+		Expect(second.rebalance(ctx)).To(Succeed())
+		Expect(second.Owned()).To(BeEmpty())
+		Expect(first.rebalance(ctx)).To(Succeed())
+		Expect(first.Owned()).To(HaveLen(count / 2))
+		Expect(second.rebalance(ctx)).To(Succeed())
+		Expect(second.Owned()).To(HaveLen(count / 2))
+		for shard := range count {
+			Expect(first.Owns(shard)).NotTo(Equal(second.Owns(shard)), "shard %d", shard)
+		}
+		Eventually(acquired).Should(HaveLen(count / 2))
+
+		By("taking over the shards of a replica that leaves")
+		Expect(first.release(ctx)).To(Succeed())
+		Expect(first.Owned()).To(BeEmpty())
+		Expect(second.rebalance(ctx)).To(Succeed())
+		Expect(second.Owned()).To(HaveLen(count))
+	})
+
+	It("should only release a shard once its reconciles are done", func() {
+		first := newShards("first")
+		first.init()
+		Expect(first.rebalance(ctx)).To(Succeed())
+		shard := first.For("default", "blueprints")
+		Expect(shard).To(Equal(newShards("second").For("default", "blueprints")))
+
+		unlock, ok := first.Lock(shard)
+		Expect(ok).To(BeTrue())
+		released := make(chan error)
+		go func() { released <- first.releaseShard(ctx, shard) }()
+		Consistently(released, 100*time.Millisecond).ShouldNot(Receive())
+		unlock()
+		Eventually(released).Should(Receive(BeNil()))
+
+		_, ok = first.Lock(shard)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should own every shard when it is nil", func() {
+		var shards *Shards
+		Expect(shards.For("default", "blueprints")).To(Equal(0))
+		unlock, ok := shards.Lock(0)
+		Expect(ok).To(BeTrue())
+		unlock()
+	})
+})
@@ -0,0 +1,139 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing traces the work of the operator with OpenTelemetry: the reconciles, the tasks
+// applied to drafts, the operations on repositories and the functions run by renderings.
+//
+// Spans are started with the global tracer provider, which does nothing until Setup replaces
+// it. Tracing thus costs next to nothing when it is disabled: spans are not recording, and the
+// attributes of the objects they are about are only computed for recording spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+// ServiceName is the name of the service the spans are recorded for.
+const ServiceName = "porch-operator"
+
+// The attributes of the spans.
+const (
+	NamespaceKey         = attribute.Key("k8s.namespace.name")
+	PackageRevisionKey   = attribute.Key("porch.package_revision.name")
+	PackageKey           = attribute.Key("porch.package.name")
+	RepositoryKey        = attribute.Key("porch.repository.name")
+	LifecycleKey         = attribute.Key("porch.package_revision.lifecycle")
+	PackageVariantSetKey = attribute.Key("porch.package_variant_set.name")
+	TaskTypeKey          = attribute.Key("porch.task.type")
+	TaskIndexKey         = attribute.Key("porch.task.index")
+	FunctionImageKey     = attribute.Key("porch.function.image")
+	FunctionStageKey     = attribute.Key("porch.function.stage")
+	GitURLKey            = attribute.Key("porch.git.url")
+)
+
+// scope is the instrumentation scope of the spans.
+const scope = "github.com/liamfallon/porch-operator"
+
+// Options configure the export of spans.
+type Options struct {
+	// Endpoint is the address of the OTLP gRPC collector the spans are exported to.
+	Endpoint string
+
+	// Insecure disables TLS on the connection to the collector.
+	Insecure bool
+
+	// SampleRatio is the fraction of the traces that are sampled. Traces whose parent was
+	// sampled are always sampled.
+	SampleRatio float64
+}
+
+// Setup exports the spans to an OTLP collector, and returns a function that flushes the spans
+// that have not been exported yet and stops the export.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	exporterOptions := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(options.Endpoint)}
+	if options.Insecure {
+		exporterOptions = append(exporterOptions, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	provider := NewProvider(sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider for the operator with the options, for example to export
+// the spans to an in-memory exporter in tests.
+func NewProvider(options ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	options = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	}, options...)
+	return sdktrace.NewTracerProvider(options...)
+}
+
+// Start starts a span with the name, as a child of the span of the context. The tracer is looked
+// up on every call, rather than once, so that the spans follow the tracer provider when it is
+// replaced, as tests do.
+func Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(scope).Start(ctx, name)
+}
+
+// End records the error the work of the span failed with, if any, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil && span.IsRecording() {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// SetPackageRevision sets the attributes of a PackageRevision on the span.
+func SetPackageRevision(span trace.Span, pr *cachev1alpha1.PackageRevision) {
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(
+		NamespaceKey.String(pr.Namespace),
+		PackageRevisionKey.String(pr.Name),
+		PackageKey.String(pr.Spec.PackageName),
+		RepositoryKey.String(pr.Spec.RepositoryName),
+		LifecycleKey.String(string(pr.Spec.Lifecycle)),
+	)
+}
+
+// SetRepository sets the attributes of a Repository on the span.
+func SetRepository(span trace.Span, repo *cachev1alpha1.Repository) {
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(NamespaceKey.String(repo.Namespace), RepositoryKey.String(repo.Name))
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 holds the admission webhooks of the v1alpha1 porch API. They record the users
+// who modify PackageRevisions and their PackageRevisionResources, and who delete PackageRevisions,
+// for the history of the PackageRevisions, and keep published PackageRevisions and their
+// contents immutable.
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/history"
+)
+
+// log is for logging in this package.
+var packagerevisionlog = logf.Log.WithName("packagerevision-resource")
+
+// The annotations the Repository controller records the git ref and the commit of a discovered
+// PackageRevision in. The contents of the PackageRevision are read from the commit.
+const (
+	gitRefAnnotation = "porch.kpt.dev/git-ref"
+	commitAnnotation = "porch.kpt.dev/commit"
+)
+
+// SetupPackageRevisionWebhookWithManager registers the webhooks for PackageRevisions in the manager.
+// The conversion webhook between the versions of PackageRevisions is registered along with them,
+// when the versions are in the scheme of the manager. The operator is the user the operator
+// authenticates as.
+func SetupPackageRevisionWebhookWithManager(mgr ctrl.Manager, operator string) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&cachev1alpha1.PackageRevision{}).
+		WithDefaulter(&PackageRevisionCustomDefaulter{}).
+		WithValidator(&PackageRevisionCustomValidator{Client: mgr.GetClient(), Operator: operator}).
+		Complete()
+}
+
+// OperatorUser returns the user the client authenticates as, which the webhooks exempt from the
+// rules that keep published PackageRevisions and their contents immutable.
+func OperatorUser(ctx context.Context, c client.Client) (string, error) {
+	review := &authenticationv1.SelfSubjectReview{}
+	if err := c.Create(ctx, review); err != nil {
+		return "", fmt.Errorf("failed to review the user of the operator: %w", err)
+	}
+	return review.Status.UserInfo.Username, nil
+}
+
+// requestedBy returns true if the admission request is made by the user.
+func requestedBy(ctx context.Context, user string) (bool, error) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	return user != "" && req.UserInfo.Username == user, nil
+}
+
+// +kubebuilder:webhook:path=/mutate-porch-kpt-dev-v1alpha1-packagerevision,mutating=true,failurePolicy=fail,sideEffects=None,groups=porch.kpt.dev,resources=packagerevisions,verbs=create;update,versions=v1alpha1,name=mpackagerevision-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// PackageRevisionCustomDefaulter records the user who creates or updates a PackageRevision in its
+// history.ActorAnnotation.
+type PackageRevisionCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &PackageRevisionCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind PackageRevision.
+func (d *PackageRevisionCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	pr, ok := obj.(*cachev1alpha1.PackageRevision)
+	if !ok {
+		return fmt.Errorf("expected a PackageRevision object but got %T", obj)
+	}
+	return setActor(ctx, pr)
+}
+
+// setActor records the user who makes the admission request in the history.ActorAnnotation of
+// the object.
+func setActor(ctx context.Context, obj metav1.Object) error {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[history.ActorAnnotation] = req.UserInfo.Username
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-porch-kpt-dev-v1alpha1-packagerevision,mutating=false,failurePolicy=fail,sideEffects=NoneOnDryRun,groups=porch.kpt.dev,resources=packagerevisions,verbs=update;delete,versions=v1alpha1,name=vpackagerevision-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// PackageRevisionCustomValidator refuses the updates of published PackageRevisions that change
+// more than their metadata and whether their deletion is proposed, or that change their git ref
+// and commit annotations but by the operator, and the deletions of published PackageRevisions
+// whose deletion has not been proposed. It records the deletions of PackageRevisions in their
+// histories, with the users who delete them. A deletion that cannot be recorded is refused, unless
+// the history is full.
+type PackageRevisionCustomValidator struct {
+	Client client.Client
+	// Operator is the user the operator authenticates as.
+	Operator string
+}
+
+var _ webhook.CustomValidator = &PackageRevisionCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type PackageRevision.
+func (v *PackageRevisionCustomValidator) ValidateCreate(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type PackageRevision.
+func (v *PackageRevisionCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*cachev1alpha1.PackageRevision)
+	if !ok {
+		return nil, fmt.Errorf("expected a PackageRevision object but got %T", oldObj)
+	}
+	pr, ok := newObj.(*cachev1alpha1.PackageRevision)
+	if !ok {
+		return nil, fmt.Errorf("expected a PackageRevision object but got %T", newObj)
+	}
+	if !isPublished(old.Spec.Lifecycle) {
+		return nil, nil
+	}
+	if !isPublished(pr.Spec.Lifecycle) {
+		return nil, fmt.Errorf("PackageRevision %q is %s: it can only move between Published and DeletionProposed",
+			pr.Name, old.Spec.Lifecycle)
+	}
+	oldSpec, spec := old.Spec.DeepCopy(), pr.Spec.DeepCopy()
+	oldSpec.Lifecycle, spec.Lifecycle = "", ""
+	if !equality.Semantic.DeepEqual(oldSpec, spec) {
+		return nil, fmt.Errorf("PackageRevision %q is %s: its spec is immutable but for its lifecycle",
+			pr.Name, old.Spec.Lifecycle)
+	}
+	for _, annotation := range []string{gitRefAnnotation, commitAnnotation} {
+		if pr.Annotations[annotation] == old.Annotations[annotation] {
+			continue
+		}
+		byOperator, err := requestedBy(ctx, v.Operator)
+		if err != nil {
+			return nil, err
+		}
+		if !byOperator {
+			return nil, fmt.Errorf("PackageRevision %q is %s: its %s annotation is immutable",
+				pr.Name, old.Spec.Lifecycle, annotation)
+		}
+	}
+	return nil, nil
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type PackageRevision.
+func (v *PackageRevisionCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	pr, ok := obj.(*cachev1alpha1.PackageRevision)
+	if !ok {
+		return nil, fmt.Errorf("expected a PackageRevision object but got %T", obj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Spec.Lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished {
+		deleting, err := v.repositoryDeleting(ctx, pr)
+		if err != nil {
+			return nil, err
+		}
+		if !deleting {
+			return nil, fmt.Errorf("PackageRevision %q is Published: propose its deletion first", pr.Name)
+		}
+	}
+	if req.DryRun != nil && *req.DryRun {
+		return nil, nil
+	}
+	packagerevisionlog.Info("Recording deletion", "name", pr.Name, "namespace", pr.Namespace, "user", req.UserInfo.Username)
+	entry := history.NewEntry(pr, cachev1alpha1.PackageRevisionActionDeleted, req.UserInfo.Username, pr.Spec.Lifecycle, metav1.Now())
+	entry.ToLifecycle = ""
+	err = history.Append(ctx, v.Client, pr, entry)
+	if errors.Is(err, history.ErrFull) {
+		return admission.Warnings{fmt.Sprintf("the deletion of PackageRevision %q is not recorded: %v", pr.Name, err)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record the deletion in the history of the PackageRevision: %w", err)
+	}
+	return nil, nil
+}
+
+// repositoryDeleting returns true if the Repository of the PackageRevision is gone or being
+// deleted, when its PackageRevisions are deleted along with it whatever their lifecycles.
+func (v *PackageRevisionCustomValidator) repositoryDeleting(ctx context.Context, pr *cachev1alpha1.PackageRevision) (bool, error) {
+	repo := &cachev1alpha1.Repository{}
+	err := v.Client.Get(ctx, client.ObjectKey{Namespace: pr.Namespace, Name: pr.Spec.RepositoryName}, repo)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !repo.DeletionTimestamp.IsZero(), nil
+}
+
+// isPublished returns true if the lifecycle is that of a published PackageRevision, whose deletion
+// may have been proposed.
+func isPublished(lifecycle cachev1alpha1.PackageRevisionLifecycle) bool {
+	return lifecycle == cachev1alpha1.PackageRevisionLifecyclePublished ||
+		lifecycle == cachev1alpha1.PackageRevisionLifecycleDeletionProposed
+}
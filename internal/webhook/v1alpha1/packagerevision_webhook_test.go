@@ -0,0 +1,224 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+	"github.com/liamfallon/porch-operator/internal/history"
+)
+
+var _ = Describe("PackageRevision Webhook", func() {
+	const operator = "system:serviceaccount:porch-system:porch-controller-manager"
+
+	var c client.Client
+
+	// requestBy returns a context holding an admission request by the user.
+	requestBy := func(user string, dryRun bool) context.Context {
+		return admission.NewContextWithRequest(context.Background(), admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: user},
+				DryRun:   ptr.To(dryRun),
+			},
+		})
+	}
+
+	newRevision := func() *cachev1alpha1.PackageRevision {
+		return &cachev1alpha1.PackageRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "blueprints.app.v1", Namespace: "default",
+				Annotations: map[string]string{"porch.kpt.dev/commit": "abc123"}},
+			Spec: cachev1alpha1.PackageRevisionSpec{PackageName: "app", RepositoryName: "blueprints",
+				WorkspaceName: "v1", Lifecycle: cachev1alpha1.PackageRevisionLifecyclePublished},
+		}
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(cachev1alpha1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).Build()
+	})
+
+	It("should record the users who modify PackageRevisions and their resources", func() {
+		pr := newRevision()
+		Expect((&PackageRevisionCustomDefaulter{}).Default(requestBy("alice", false), pr)).To(Succeed())
+		Expect(pr.Annotations).To(HaveKeyWithValue(history.ActorAnnotation, "alice"))
+		Expect(pr.Annotations).To(HaveKey("porch.kpt.dev/commit"))
+
+		By("overwriting the user set by the client")
+		pr.Annotations[history.ActorAnnotation] = "mallory"
+		Expect((&PackageRevisionCustomDefaulter{}).Default(requestBy("bob", false), pr)).To(Succeed())
+		Expect(pr.Annotations).To(HaveKeyWithValue(history.ActorAnnotation, "bob"))
+
+		prr := &cachev1alpha1.PackageRevisionResources{}
+		Expect((&PackageRevisionResourcesCustomDefaulter{}).Default(requestBy("carol", false), prr)).To(Succeed())
+		Expect(prr.Annotations).To(HaveKeyWithValue(history.ActorAnnotation, "carol"))
+	})
+
+	It("should record the deletion of PackageRevisions in their history", func() {
+		validator := &PackageRevisionCustomValidator{Client: c}
+		pr := newRevision()
+		pr.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecycleDeletionProposed
+
+		_, err := validator.ValidateDelete(requestBy("alice", true), pr)
+		Expect(err).NotTo(HaveOccurred())
+		last, err := history.Last(context.Background(), c, pr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(last).To(BeNil())
+
+		_, err = validator.ValidateDelete(requestBy("alice", false), pr)
+		Expect(err).NotTo(HaveOccurred())
+		h := &cachev1alpha1.PackageRevisionHistory{}
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(pr), h)).To(Succeed())
+		Expect(h.Labels).To(HaveKeyWithValue(history.PackageLabel, "app"))
+		Expect(h.Spec.Entries).To(ConsistOf(And(
+			HaveField("Action", cachev1alpha1.PackageRevisionActionDeleted),
+			HaveField("Actor", "alice"),
+			HaveField("FromLifecycle", cachev1alpha1.PackageRevisionLifecycleDeletionProposed),
+			HaveField("ToLifecycle", cachev1alpha1.PackageRevisionLifecycle("")),
+			HaveField("Commit", "abc123"))))
+	})
+
+	It("should refuse the deletion of published PackageRevisions whose deletion is not proposed", func() {
+		validator := &PackageRevisionCustomValidator{Client: c}
+		ctx := context.Background()
+		repo := &cachev1alpha1.Repository{
+			ObjectMeta: metav1.ObjectMeta{Name: "blueprints", Namespace: "default", Finalizers: []string{"test"}},
+		}
+		Expect(c.Create(ctx, repo)).To(Succeed())
+		pr := newRevision()
+
+		for _, dryRun := range []bool{true, false} {
+			_, err := validator.ValidateDelete(requestBy("alice", dryRun), pr)
+			Expect(err).To(MatchError(ContainSubstring("propose its deletion first")))
+		}
+		for _, lifecycle := range []cachev1alpha1.PackageRevisionLifecycle{
+			cachev1alpha1.PackageRevisionLifecycleDeletionProposed,
+			cachev1alpha1.PackageRevisionLifecycleProposed,
+			cachev1alpha1.PackageRevisionLifecycleDraft,
+		} {
+			pr.Spec.Lifecycle = lifecycle
+			_, err := validator.ValidateDelete(requestBy("alice", true), pr)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		By("deleting the repository")
+		pr.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecyclePublished
+		Expect(c.Delete(ctx, repo)).To(Succeed())
+		_, err := validator.ValidateDelete(requestBy("system:serviceaccount:kube-system:generic-garbage-collector", true), pr)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should keep published PackageRevisions immutable but for their lifecycle and metadata", func() {
+		validator := &PackageRevisionCustomValidator{Client: c}
+		ctx := requestBy("alice", false)
+		old := newRevision()
+		old.Spec.Revision = 1
+
+		pr := old.DeepCopy()
+		pr.Annotations[history.ActorAnnotation] = "alice"
+		pr.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecycleDeletionProposed
+		_, err := validator.ValidateUpdate(ctx, old, pr)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = validator.ValidateUpdate(ctx, pr, old)
+		Expect(err).NotTo(HaveOccurred())
+
+		pr = old.DeepCopy()
+		pr.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecycleDraft
+		_, err = validator.ValidateUpdate(ctx, old, pr)
+		Expect(err).To(MatchError(ContainSubstring("it can only move between Published and DeletionProposed")))
+
+		pr = old.DeepCopy()
+		pr.Spec.ReadinessGates = []cachev1alpha1.ReadinessGate{{ConditionType: "Tested"}}
+		_, err = validator.ValidateUpdate(ctx, old, pr)
+		Expect(err).To(MatchError(ContainSubstring("its spec is immutable")))
+
+		By("changing the spec of a draft")
+		old.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecycleDraft
+		pr.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecycleDraft
+		_, err = validator.ValidateUpdate(ctx, old, pr)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should keep the contents of published PackageRevisions immutable but to the operator", func() {
+		validator := &PackageRevisionResourcesCustomValidator{Client: c, Operator: operator}
+		ctx := requestBy("alice", false)
+		pr := newRevision()
+		Expect(c.Create(ctx, pr)).To(Succeed())
+		old := &cachev1alpha1.PackageRevisionResources{
+			ObjectMeta: metav1.ObjectMeta{Name: pr.Name, Namespace: pr.Namespace,
+				Annotations: map[string]string{"porch.kpt.dev/commit": "abc123"}},
+			Spec: cachev1alpha1.PackageRevisionResourcesSpec{Resources: map[string]string{"Kptfile": "kind: Kptfile\n"}},
+		}
+
+		prr := old.DeepCopy()
+		prr.Annotations[history.ActorAnnotation] = "alice"
+		_, err := validator.ValidateUpdate(ctx, old, prr)
+		Expect(err).NotTo(HaveOccurred())
+		prr.Spec.Resources["config.yaml"] = "kind: ConfigMap\n"
+		_, err = validator.ValidateUpdate(ctx, old, prr)
+		Expect(err).To(MatchError(ContainSubstring("its contents are immutable")))
+		_, err = validator.ValidateCreate(ctx, prr)
+		Expect(err).To(MatchError(ContainSubstring("its contents are immutable")))
+		_, err = validator.ValidateDelete(ctx, old)
+		Expect(err).To(MatchError(ContainSubstring("its contents cannot be deleted")))
+
+		By("reading the contents again from the commit the PackageRevision was moved to")
+		prr.Annotations["porch.kpt.dev/commit"] = "def456"
+		_, err = validator.ValidateUpdate(ctx, old, prr)
+		Expect(err).To(MatchError(ContainSubstring("its contents are immutable")))
+		operatorCtx := requestBy(operator, false)
+		_, err = validator.ValidateUpdate(operatorCtx, old, prr)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = validator.ValidateDelete(operatorCtx, old)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = validator.ValidateCreate(operatorCtx, prr)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("editing the contents of a draft")
+		pr.Spec.Lifecycle = cachev1alpha1.PackageRevisionLifecycleDraft
+		Expect(c.Update(ctx, pr)).To(Succeed())
+		_, err = validator.ValidateUpdate(ctx, old, prr)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = validator.ValidateDelete(ctx, old)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should keep the git ref and commit of published PackageRevisions immutable but to the operator", func() {
+		validator := &PackageRevisionCustomValidator{Client: c, Operator: operator}
+		old := newRevision()
+		for _, annotation := range []string{"porch.kpt.dev/commit", "porch.kpt.dev/git-ref"} {
+			pr := old.DeepCopy()
+			pr.Annotations[annotation] = "def456"
+			_, err := validator.ValidateUpdate(requestBy("alice", false), old, pr)
+			Expect(err).To(MatchError(ContainSubstring("its " + annotation + " annotation is immutable")))
+			_, err = validator.ValidateUpdate(requestBy(operator, false), old, pr)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+})
@@ -0,0 +1,129 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	cachev1alpha1 "github.com/liamfallon/porch-operator/api/v1alpha1"
+)
+
+// SetupPackageRevisionResourcesWebhookWithManager registers the webhooks for PackageRevisionResources in the manager.
+// The operator is the user the operator authenticates as.
+func SetupPackageRevisionResourcesWebhookWithManager(mgr ctrl.Manager, operator string) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&cachev1alpha1.PackageRevisionResources{}).
+		WithDefaulter(&PackageRevisionResourcesCustomDefaulter{}).
+		WithValidator(&PackageRevisionResourcesCustomValidator{Client: mgr.GetClient(), Operator: operator}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-porch-kpt-dev-v1alpha1-packagerevisionresources,mutating=true,failurePolicy=fail,sideEffects=None,groups=porch.kpt.dev,resources=packagerevisionresources,verbs=create;update,versions=v1alpha1,name=mpackagerevisionresources-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// PackageRevisionResourcesCustomDefaulter records the user who creates or updates
+// PackageRevisionResources in their history.ActorAnnotation, so that edits of the resources of
+// drafts are recorded with their users.
+type PackageRevisionResourcesCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &PackageRevisionResourcesCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind PackageRevisionResources.
+func (d *PackageRevisionResourcesCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	prr, ok := obj.(*cachev1alpha1.PackageRevisionResources)
+	if !ok {
+		return fmt.Errorf("expected a PackageRevisionResources object but got %T", obj)
+	}
+	return setActor(ctx, prr)
+}
+
+// +kubebuilder:webhook:path=/validate-porch-kpt-dev-v1alpha1-packagerevisionresources,mutating=false,failurePolicy=fail,sideEffects=None,groups=porch.kpt.dev,resources=packagerevisionresources,verbs=create;update;delete,versions=v1alpha1,name=vpackagerevisionresources-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// PackageRevisionResourcesCustomValidator refuses the creation, changes and deletion of the
+// contents of published PackageRevisions, but by the operator: the contents of a discovered
+// revision -1 follow its branch, and the operator reads the contents of the revisions it discovers
+// again when they are missing. The contents of a PackageRevision that is gone or being deleted may
+// be deleted by anyone, so that they are garbage collected along with it.
+type PackageRevisionResourcesCustomValidator struct {
+	Client client.Client
+	// Operator is the user the operator authenticates as.
+	Operator string
+}
+
+var _ webhook.CustomValidator = &PackageRevisionResourcesCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type PackageRevisionResources.
+func (v *PackageRevisionResourcesCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	prr, ok := obj.(*cachev1alpha1.PackageRevisionResources)
+	if !ok {
+		return nil, fmt.Errorf("expected a PackageRevisionResources object but got %T", obj)
+	}
+	return nil, v.checkPublished(ctx, prr, "its contents are immutable")
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type PackageRevisionResources.
+func (v *PackageRevisionResourcesCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*cachev1alpha1.PackageRevisionResources)
+	if !ok {
+		return nil, fmt.Errorf("expected a PackageRevisionResources object but got %T", oldObj)
+	}
+	prr, ok := newObj.(*cachev1alpha1.PackageRevisionResources)
+	if !ok {
+		return nil, fmt.Errorf("expected a PackageRevisionResources object but got %T", newObj)
+	}
+	if equality.Semantic.DeepEqual(old.Spec, prr.Spec) {
+		return nil, nil
+	}
+	return nil, v.checkPublished(ctx, prr, "its contents are immutable")
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type PackageRevisionResources.
+func (v *PackageRevisionResourcesCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	prr, ok := obj.(*cachev1alpha1.PackageRevisionResources)
+	if !ok {
+		return nil, fmt.Errorf("expected a PackageRevisionResources object but got %T", obj)
+	}
+	return nil, v.checkPublished(ctx, prr, "its contents cannot be deleted")
+}
+
+// checkPublished returns an error, ending with the reason, if the PackageRevision of the contents
+// is published, gone or being deleted, and the request is not made by the operator.
+func (v *PackageRevisionResourcesCustomValidator) checkPublished(ctx context.Context,
+	prr *cachev1alpha1.PackageRevisionResources, reason string) error {
+	byOperator, err := requestedBy(ctx, v.Operator)
+	if err != nil || byOperator {
+		return err
+	}
+	pr := &cachev1alpha1.PackageRevision{}
+	if err := v.Client.Get(ctx, client.ObjectKeyFromObject(prr), pr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get the PackageRevision of the contents: %w", err)
+	}
+	if !isPublished(pr.Spec.Lifecycle) || !pr.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	return fmt.Errorf("PackageRevision %q is %s: %s", pr.Name, pr.Spec.Lifecycle, reason)
+}